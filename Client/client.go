@@ -3,11 +3,13 @@ package client
 import (
 	"bytes"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"scootin/models"
+	"strconv"
 	"time"
 )
 
@@ -18,22 +20,16 @@ import (
 type (
 	// Client connects to the service using its url
 	Client struct {
-		baseUrl string
+		baseUrl      string
+		adminToken   string
+		scooterToken string
 	}
 
-	CheckoutCreate struct {
-		MerchantCode string
-		Amount       int64
-		Currency     string
-	}
+	// CheckoutCreate asks to pay a finished trip
+	CheckoutCreate = models.CheckoutCreate
 
-	Checkout struct {
-		ID           string
-		MerchantCode string
-		Amount       int64
-		Currency     string
-		CreatedAt    time.Time
-	}
+	// Checkout is the payment of a trip's fare
+	Checkout = models.Checkout
 )
 
 // NewClient take the service base url, returns a new service's client
@@ -41,6 +37,16 @@ func NewClient(url string) *Client {
 	return &Client{baseUrl: url}
 }
 
+// WithAdminToken returns a copy of the client calling the admin endpoints with the operators' token
+func (c *Client) WithAdminToken(token string) *Client {
+	return &Client{baseUrl: c.baseUrl, adminToken: token, scooterToken: c.scooterToken}
+}
+
+// WithScooterToken returns a copy of the client reporting the scooters' telemetry with the fleet's token
+func (c *Client) WithScooterToken(token string) *Client {
+	return &Client{baseUrl: c.baseUrl, adminToken: c.adminToken, scooterToken: token}
+}
+
 // CreateUser creates a user, returns the user uuid.
 func (c *Client) CreateUser(user *models.User) (*models.UUIDResponse, error) {
 	var (
@@ -57,7 +63,7 @@ func (c *Client) CreateUser(user *models.User) (*models.UUIDResponse, error) {
 	if resp, err = http.Post(url, "application/json", bytes.NewBuffer(j)); err != nil {
 		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("Received http status: %v", resp.StatusCode))
+		return nil, decodeError(resp)
 	}
 	defer resp.Body.Close()
 
@@ -72,7 +78,7 @@ func (c *Client) CreateUser(user *models.User) (*models.UUIDResponse, error) {
 }
 
 // CreateScooter creates a scooter, returns the scooter uuid.
-func (c *Client) CreateScooter() (*models.UUIDResponse, error) {
+func (c *Client) CreateScooter(scooter models.ScooterCreate) (*models.UUIDResponse, error) {
 	var (
 		j, body []byte
 		resp    *http.Response
@@ -81,13 +87,13 @@ func (c *Client) CreateScooter() (*models.UUIDResponse, error) {
 	)
 
 	url := fmt.Sprintf("%s%s", c.baseUrl, "/v0.1/scooter")
-	if j, err = json.Marshal(""); err != nil {
+	if j, err = json.Marshal(scooter); err != nil {
 		return nil, err
 	}
 	if resp, err = http.Post(url, "application/json", bytes.NewBuffer(j)); err != nil {
 		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("Received http status: %v", resp.StatusCode))
+		return nil, decodeError(resp)
 	}
 	defer resp.Body.Close()
 
@@ -101,46 +107,63 @@ func (c *Client) CreateScooter() (*models.UUIDResponse, error) {
 	return uuid, nil
 }
 
-// BookScooter books a scooter for a user.
-func (c *Client) BookScooter(scooterID, userID string) error {
+// BookScooter books a scooter for a user at the position, returns the started trip.
+// The rider must be close to the scooter, the operators may book without a position.
+func (c *Client) BookScooter(scooterID, userID string, position *models.Location) (*models.Trip, error) {
 	var (
 		err  error
+		body []byte
 		resp *http.Response
+		trip *models.Trip
 	)
-	url := fmt.Sprintf("%s%s%s", c.baseUrl, "/v0.1/scooter/book/", scooterID)
+	url := fmt.Sprintf("%s%s%s%s", c.baseUrl, "/v0.1/scooter/book/", scooterID, positionQuery(position))
 	// set the HTTP method, url, and request body
 	req, err := http.NewRequest(http.MethodPut, url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// set the request header
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 	req.Header.Set("user-id", userID)
+	if len(c.adminToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	}
 
 	// initialize http client
 	h := &http.Client{}
 
 	// execute the request
 	if resp, err = h.Do(req); err != nil {
-		return err
+		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
-		return errors.New(fmt.Sprintf("Received http status: %v", resp.StatusCode))
+		return nil, decodeError(resp)
 	}
-	return nil
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &trip); err != nil {
+		return nil, err
+	}
+	return trip, nil
 }
 
-// ReleaseScooter releases a scooter by a user.
-func (c *Client) ReleaseScooter(userID string) error {
+// ReleaseScooter releases a scooter by a user, returns the ended trip with its fare.
+func (c *Client) ReleaseScooter(userID string) (*models.Trip, error) {
 	var (
 		err  error
+		body []byte
 		resp *http.Response
+		trip *models.Trip
 	)
 	url := fmt.Sprintf("%s%s", c.baseUrl, "/v0.1/scooter/release/")
 	// set the HTTP method, url, and request body
 	req, err := http.NewRequest(http.MethodPut, url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// set the request header
@@ -152,11 +175,20 @@ func (c *Client) ReleaseScooter(userID string) error {
 
 	// execute the request
 	if resp, err = h.Do(req); err != nil {
-		return err
+		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
-		return errors.New(fmt.Sprintf("Received http status: %v", resp.StatusCode))
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &trip); err != nil {
+		return nil, err
 	}
-	return nil
+	return trip, nil
 }
 
 // ListAvailableScooter returns the available scooters to ride
@@ -172,7 +204,7 @@ func (c *Client) ListAvailableScooter() ([]models.ScooterInfo, error) {
 	if resp, err = http.Get(url); err != nil {
 		return nil, err
 	} else if resp.StatusCode != http.StatusOK {
-		return nil, errors.New(fmt.Sprintf("Received http status: %v", resp.StatusCode))
+		return nil, decodeError(resp)
 	}
 	defer resp.Body.Close()
 
@@ -185,3 +217,701 @@ func (c *Client) ListAvailableScooter() ([]models.ScooterInfo, error) {
 	}
 	return sco, nil
 }
+
+// ListAvailableScooterNear returns up to limit available scooters within the radius in meters
+// around the center, the nearest first
+func (c *Client) ListAvailableScooterNear(center models.Location, radius float64, limit int) ([]models.NearbyScooter, error) {
+	var (
+		body []byte
+		resp *http.Response
+		err  error
+		sco  []models.NearbyScooter
+	)
+
+	query := url.Values{}
+	query.Set("lat", strconv.FormatFloat(center.Latitude, 'f', -1, 64))
+	query.Set("lon", strconv.FormatFloat(center.Longitude, 'f', -1, 64))
+	query.Set("radius", strconv.FormatFloat(radius, 'f', -1, 64))
+	query.Set("limit", strconv.Itoa(limit))
+	u := fmt.Sprintf("%s/v0.1/scooters?%s", c.baseUrl, query.Encode())
+	if resp, err = http.Get(u); err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &sco); err != nil {
+		return nil, err
+	}
+	return sco, nil
+}
+
+// GetTrip returns a trip by its id
+func (c *Client) GetTrip(tripID string) (*models.Trip, error) {
+	var (
+		body []byte
+		resp *http.Response
+		err  error
+		trip *models.Trip
+	)
+
+	url := fmt.Sprintf("%s%s%s", c.baseUrl, "/v0.1/trips/", tripID)
+	if resp, err = http.Get(url); err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &trip); err != nil {
+		return nil, err
+	}
+	return trip, nil
+}
+
+// ListUserTrips returns the user's trips, the oldest first
+func (c *Client) ListUserTrips(userID string) ([]models.Trip, error) {
+	var (
+		body  []byte
+		resp  *http.Response
+		err   error
+		trips []models.Trip
+	)
+
+	url := fmt.Sprintf("%s/v0.1/users/%s/trips", c.baseUrl, userID)
+	if resp, err = http.Get(url); err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &trips); err != nil {
+		return nil, err
+	}
+	return trips, nil
+}
+
+// ScooterTrack returns the scooter's location history within [from, to)
+func (c *Client) ScooterTrack(scooterID string, from, to time.Time) ([]models.LocationSample, error) {
+	var (
+		body  []byte
+		resp  *http.Response
+		err   error
+		track []models.LocationSample
+	)
+
+	query := url.Values{}
+	query.Set("from", from.Format(time.RFC3339))
+	query.Set("to", to.Format(time.RFC3339))
+	u := fmt.Sprintf("%s/v0.1/scooters/%s/track?%s", c.baseUrl, scooterID, query.Encode())
+	if resp, err = http.Get(u); err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &track); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// SetScooterStatus moves a scooter to another operational status, it needs the operators' token.
+func (c *Client) SetScooterStatus(scooterID string, status models.ScooterStatus) (*models.ScooterInfo, error) {
+	var (
+		j, body []byte
+		resp    *http.Response
+		err     error
+		sc      *models.ScooterInfo
+	)
+	url := fmt.Sprintf("%s/v0.1/admin/scooters/%s/status", c.baseUrl, scooterID)
+	if j, err = json.Marshal(models.StatusChange{Status: status}); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(j))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+c.adminToken)
+
+	h := &http.Client{}
+	if resp, err = h.Do(req); err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if body, err = ioutil.ReadAll(resp.Body); err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// ReserveScooter holds a scooter for a user, returns the active reservation.
+func (c *Client) ReserveScooter(scooterID, userID string) (*models.Reservation, error) {
+	var reservation *models.Reservation
+	url := fmt.Sprintf("%s/v0.1/scooter/reserve/%s", c.baseUrl, scooterID)
+	if err := c.send(http.MethodPut, url, userID, nil, &reservation); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// GetReservation returns the reservation by its id.
+func (c *Client) GetReservation(reservationID string) (*models.Reservation, error) {
+	var reservation *models.Reservation
+	url := fmt.Sprintf("%s/v0.1/reservations/%s", c.baseUrl, reservationID)
+	if err := c.send(http.MethodGet, url, "", nil, &reservation); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// ConvertReservation starts the trip on the reserved scooter of the user at the position, returns the started trip.
+// The rider must be close to the scooter, the operators may convert without a position.
+func (c *Client) ConvertReservation(reservationID, userID string, position *models.Location) (*models.Trip, error) {
+	var trip *models.Trip
+	url := fmt.Sprintf("%s/v0.1/reservations/%s/convert%s", c.baseUrl, reservationID, positionQuery(position))
+	if err := c.send(http.MethodPut, url, userID, nil, &trip); err != nil {
+		return nil, err
+	}
+	return trip, nil
+}
+
+// positionQuery returns the query of the rider's position, empty without one
+func positionQuery(position *models.Location) string {
+	if position == nil {
+		return ""
+	}
+	return fmt.Sprintf("?lat=%s&lon=%s", strconv.FormatFloat(position.Latitude, 'f', -1, 64),
+		strconv.FormatFloat(position.Longitude, 'f', -1, 64))
+}
+
+// CancelReservation cancels the user's reservation, returns the cancelled reservation.
+func (c *Client) CancelReservation(reservationID, userID string) (*models.Reservation, error) {
+	var reservation *models.Reservation
+	url := fmt.Sprintf("%s/v0.1/reservations/%s/cancel", c.baseUrl, reservationID)
+	if err := c.send(http.MethodPut, url, userID, nil, &reservation); err != nil {
+		return nil, err
+	}
+	return reservation, nil
+}
+
+// FareEstimate quotes a ride of the duration and distance in meters in the city with the vehicle type,
+// the default vehicle type when it's empty. The ride is surged like one booked now at the location, when it's given.
+func (c *Client) FareEstimate(city, vehicleType string, duration time.Duration, distance float64, at *models.Location) (*models.Fare, error) {
+	var fare *models.Fare
+	query := url.Values{}
+	query.Set("city", city)
+	query.Set("vehicle_type", vehicleType)
+	query.Set("minutes", strconv.FormatFloat(duration.Minutes(), 'f', -1, 64))
+	query.Set("distance", strconv.FormatFloat(distance, 'f', -1, 64))
+	if at != nil {
+		query.Set("lat", strconv.FormatFloat(at.Latitude, 'f', -1, 64))
+		query.Set("lon", strconv.FormatFloat(at.Longitude, 'f', -1, 64))
+	}
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/fare-estimate?%s", c.baseUrl, query.Encode()), "", nil, &fare); err != nil {
+		return nil, err
+	}
+	return fare, nil
+}
+
+// CreatePricePlan stores the next version of a city's and vehicle type's prices, it needs the operators' token.
+func (c *Client) CreatePricePlan(plan *models.PricePlan) (*models.PricePlan, error) {
+	var stored *models.PricePlan
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/price-plans", c.baseUrl), "", plan, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListPricePlans lists all the price plans' versions, it needs the operators' token.
+func (c *Client) ListPricePlans() ([]models.PricePlan, error) {
+	var plans []models.PricePlan
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/price-plans", c.baseUrl), "", nil, &plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// CreateCheckout pays the user's finished trip, returns the captured checkout.
+func (c *Client) CreateCheckout(userID string, checkout *CheckoutCreate) (*Checkout, error) {
+	var captured *Checkout
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/checkouts", c.baseUrl), userID, checkout, &captured); err != nil {
+		return nil, err
+	}
+	return captured, nil
+}
+
+// GetCheckout returns the user's checkout by its id.
+func (c *Client) GetCheckout(checkoutID, userID string) (*Checkout, error) {
+	var checkout *Checkout
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/checkouts/%s", c.baseUrl, checkoutID), userID, nil, &checkout); err != nil {
+		return nil, err
+	}
+	return checkout, nil
+}
+
+// GetWallet returns the user's wallet balances.
+func (c *Client) GetWallet(userID string) (*models.Wallet, error) {
+	var wallet *models.Wallet
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/users/%s/wallet", c.baseUrl, userID), userID, nil, &wallet); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// ListWalletTransactions lists the user's ledger transactions, the oldest first.
+func (c *Client) ListWalletTransactions(userID string) ([]models.LedgerTransaction, error) {
+	var transactions []models.LedgerTransaction
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/users/%s/wallet/transactions", c.baseUrl, userID), userID, nil, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// TopUpWallet pays the money into the user's wallet, returns the posted transaction.
+func (c *Client) TopUpWallet(userID string, money models.Money) (*models.LedgerTransaction, error) {
+	var tx *models.LedgerTransaction
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/users/%s/wallet/top-ups", c.baseUrl, userID), userID, money, &tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// CreditPromo credits the user's wallet with a promotion, it needs the operators' token.
+func (c *Client) CreditPromo(userID string, money models.Money) (*models.LedgerTransaction, error) {
+	var tx *models.LedgerTransaction
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/users/%s/promo-credits", c.baseUrl, userID), "", money, &tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// CreateDispute opens a dispute of the user's ended trip.
+func (c *Client) CreateDispute(userID string, create *models.DisputeCreate) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/disputes", c.baseUrl), userID, create, &dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// GetDispute returns the user's dispute by its id with its audit trail.
+func (c *Client) GetDispute(disputeID, userID string) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/disputes/%s", c.baseUrl, disputeID), userID, nil, &dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// ListDisputes lists the disputes in the status, all of them when it's empty, it needs the operators' token.
+func (c *Client) ListDisputes(status models.DisputeStatus) ([]models.Dispute, error) {
+	query := url.Values{}
+	if len(status) > 0 {
+		query.Set("status", string(status))
+	}
+	var disputes []models.Dispute
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/disputes?%s", c.baseUrl, query.Encode()), "", nil, &disputes); err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
+
+// ReviewDispute takes the open dispute under review, returns it with its trip and the trip's location history.
+// It needs the operators' token.
+func (c *Client) ReviewDispute(disputeID string) (*models.DisputeReview, error) {
+	return c.disputeReview(http.MethodPut, disputeID)
+}
+
+// GetDisputeReview returns the dispute with its trip and the trip's location history, it needs the operators' token.
+func (c *Client) GetDisputeReview(disputeID string) (*models.DisputeReview, error) {
+	return c.disputeReview(http.MethodGet, disputeID)
+}
+
+func (c *Client) disputeReview(method, disputeID string) (*models.DisputeReview, error) {
+	var review *models.DisputeReview
+	if err := c.send(method, fmt.Sprintf("%s/v0.1/admin/disputes/%s/review", c.baseUrl, disputeID), "", nil, &review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// DecideDispute refunds or rejects the dispute under review, it needs the operators' token.
+func (c *Client) DecideDispute(disputeID string, decision *models.DisputeDecision) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	if err := c.send(http.MethodPut, fmt.Sprintf("%s/v0.1/admin/disputes/%s/decision", c.baseUrl, disputeID), "", decision, &dispute); err != nil {
+		return nil, err
+	}
+	return dispute, nil
+}
+
+// CreatePromoCode stores a new promo code, it needs the operators' token.
+func (c *Client) CreatePromoCode(promo *models.PromoCode) (*models.PromoCode, error) {
+	var stored *models.PromoCode
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/promo-codes", c.baseUrl), "", promo, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListPromoCodes lists the promo codes, it needs the operators' token.
+func (c *Client) ListPromoCodes() ([]models.PromoCode, error) {
+	var promos []models.PromoCode
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/promo-codes", c.baseUrl), "", nil, &promos); err != nil {
+		return nil, err
+	}
+	return promos, nil
+}
+
+// RedeemPromoCode attaches the promo code to the user.
+func (c *Client) RedeemPromoCode(userID, code string) (*models.PromoRedemption, error) {
+	var redemption *models.PromoRedemption
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/users/%s/promo-codes", c.baseUrl, userID), userID,
+		models.PromoRedeem{Code: code}, &redemption); err != nil {
+		return nil, err
+	}
+	return redemption, nil
+}
+
+// ListPromoRedemptions lists the promo codes the user redeemed.
+func (c *Client) ListPromoRedemptions(userID string) ([]models.PromoRedemption, error) {
+	var redemptions []models.PromoRedemption
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/users/%s/promo-codes", c.baseUrl, userID), userID, nil, &redemptions); err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// CreatePassPlan stores a new pass plan, it needs the operators' token. Returns the stored plan.
+func (c *Client) CreatePassPlan(plan *models.PassPlan) (*models.PassPlan, error) {
+	var stored *models.PassPlan
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/pass-plans", c.baseUrl), "", plan, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListPassPlans lists the pass plans on sale.
+func (c *Client) ListPassPlans() ([]models.PassPlan, error) {
+	var plans []models.PassPlan
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/pass-plans", c.baseUrl), "", nil, &plans); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
+
+// BuyPass buys the user a pass of the plan, auto-renewed when autoRenew is set. Returns the pass.
+func (c *Client) BuyPass(userID, planID string, autoRenew bool) (*models.Pass, error) {
+	var pass *models.Pass
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/users/%s/passes", c.baseUrl, userID), userID,
+		models.PassPurchase{PlanID: planID, AutoRenew: autoRenew}, &pass); err != nil {
+		return nil, err
+	}
+	return pass, nil
+}
+
+// ListUserPasses lists the user's valid passes with what's left of their allowances today.
+func (c *Client) ListUserPasses(userID string) ([]models.PassAllowance, error) {
+	var allowances []models.PassAllowance
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/users/%s/passes", c.baseUrl, userID), userID, nil, &allowances); err != nil {
+		return nil, err
+	}
+	return allowances, nil
+}
+
+// SetTaxRule sets the VAT rate and the legal entity of the rule's city, the rule without a city applies to the others.
+func (c *Client) SetTaxRule(rule *models.TaxRule) (*models.TaxRule, error) {
+	var stored *models.TaxRule
+	if err := c.send(http.MethodPut, fmt.Sprintf("%s/v0.1/admin/tax-rules", c.baseUrl), "", rule, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListTaxRules lists the tax rules ordered by city.
+func (c *Client) ListTaxRules() ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/tax-rules", c.baseUrl), "", nil, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// IssueInvoices issues the invoices of the ended month, "2006-01". Returns the newly issued ones and the charges
+// left unbilled.
+func (c *Client) IssueInvoices(month string) (*models.InvoiceRun, error) {
+	var run *models.InvoiceRun
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/invoices?month=%s", c.baseUrl, url.QueryEscape(month)), "",
+		nil, &run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// ListUserInvoices lists the user's invoices, the oldest first.
+func (c *Client) ListUserInvoices(userID string) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/users/%s/invoices", c.baseUrl, userID), userID, nil, &invoices); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// GetInvoice returns the user's invoice by its id.
+func (c *Client) GetInvoice(invoiceID, userID string) (*models.Invoice, error) {
+	var invoice *models.Invoice
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/invoices/%s", c.baseUrl, invoiceID), userID, nil, &invoice); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// GetInvoiceHTML returns the user's invoice by its id as a printable HTML page.
+func (c *Client) GetInvoiceHTML(invoiceID, userID string) ([]byte, error) {
+	return c.fetch(fmt.Sprintf("%s/v0.1/invoices/%s?format=html", c.baseUrl, invoiceID), userID)
+}
+
+// ExportLedgerCSV exports the ledger entries posted within from and to as CSV.
+func (c *Client) ExportLedgerCSV(from, to time.Time) ([]byte, error) {
+	return c.fetch(fmt.Sprintf("%s/v0.1/admin/ledger.csv?from=%s&to=%s", c.baseUrl,
+		url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(to.Format(time.RFC3339))), "")
+}
+
+// CreateZone stores the zone with its GeoJSON geometry. Returns the stored zone.
+func (c *Client) CreateZone(zone *models.Zone) (*models.Zone, error) {
+	var stored *models.Zone
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/zones", c.baseUrl), "", zone, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// UpdateZone replaces the zone's name, tags, geometry and speed limit. Returns the updated zone.
+func (c *Client) UpdateZone(zone *models.Zone) (*models.Zone, error) {
+	var updated *models.Zone
+	if err := c.send(http.MethodPut, fmt.Sprintf("%s/v0.1/admin/zones/%s", c.baseUrl, zone.ID), "", zone, &updated); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteZone deletes the zone.
+func (c *Client) DeleteZone(zoneID string) error {
+	return c.send(http.MethodDelete, fmt.Sprintf("%s/v0.1/admin/zones/%s", c.baseUrl, zoneID), "", nil, nil)
+}
+
+// GetZone returns the zone by its id.
+func (c *Client) GetZone(zoneID string) (*models.Zone, error) {
+	var zone *models.Zone
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/zones/%s", c.baseUrl, zoneID), "", nil, &zone); err != nil {
+		return nil, err
+	}
+	return zone, nil
+}
+
+// ListZones lists the zones in their creation order.
+func (c *Client) ListZones() ([]models.Zone, error) {
+	var zones []models.Zone
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/zones", c.baseUrl), "", nil, &zones); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+// ListZoneAlerts lists the alerts of the scooters entering no-riding zones raised within from and to.
+func (c *Client) ListZoneAlerts(from, to time.Time) ([]models.ZoneAlert, error) {
+	var alerts []models.ZoneAlert
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/zone-alerts?from=%s&to=%s", c.baseUrl,
+		url.QueryEscape(from.Format(time.RFC3339)), url.QueryEscape(to.Format(time.RFC3339))), "", nil, &alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// ReportTelemetry sends the scooter's samples, the oldest first. Returns how many were recorded and how many
+// were already.
+func (c *Client) ReportTelemetry(scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error) {
+	var receipt *models.TelemetryReceipt
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/scooters/%s/telemetry", c.baseUrl, scooterID), "",
+		models.TelemetryBatch{Samples: samples}, &receipt); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// PositionBufferStats returns the counters of the service's UDP telemetry write-behind buffer
+func (c *Client) PositionBufferStats() (*models.PositionBufferStats, error) {
+	var stats *models.PositionBufferStats
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/telemetry/buffer", c.baseUrl), "", nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// IssueCommand sends the operator's command to the scooter. Returns the queued command.
+func (c *Client) IssueCommand(scooterID string, create models.CommandCreate) (*models.Command, error) {
+	var command *models.Command
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/scooters/%s/commands", c.baseUrl, scooterID), "", create, &command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// GetCommand returns the command with its delivery state
+func (c *Client) GetCommand(commandID string) (*models.Command, error) {
+	var command *models.Command
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/commands/%s", c.baseUrl, commandID), "", nil, &command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// ListCommands lists the scooter's commands in their creation order
+func (c *Client) ListCommands(scooterID string) ([]models.Command, error) {
+	var commands []models.Command
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/admin/scooters/%s/commands", c.baseUrl, scooterID), "", nil, &commands); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// PollCommands returns the scooter's commands waiting for an acknowledgment, the oldest first
+func (c *Client) PollCommands(scooterID string) ([]models.Command, error) {
+	var commands []models.Command
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/scooters/%s/commands", c.baseUrl, scooterID), "", nil, &commands); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
+
+// AckCommand acknowledges the scooter's delivered command, acked or failed. Returns the ended command.
+func (c *Client) AckCommand(scooterID, commandID string, ack models.CommandAck) (*models.Command, error) {
+	var command *models.Command
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/scooters/%s/commands/%s/ack", c.baseUrl, scooterID, commandID), "", ack, &command); err != nil {
+		return nil, err
+	}
+	return command, nil
+}
+
+// CreateStation opens the virtual parking station. Returns the stored station.
+func (c *Client) CreateStation(station *models.Station) (*models.Station, error) {
+	var stored *models.Station
+	if err := c.send(http.MethodPost, fmt.Sprintf("%s/v0.1/admin/stations", c.baseUrl), "", station, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// ListStations lists the city's stations, every station when the city is empty, with their free slots.
+// Only the stations over capacity are listed when rebalance is set.
+func (c *Client) ListStations(city string, rebalance bool) ([]models.StationOccupancy, error) {
+	var stations []models.StationOccupancy
+	query := url.Values{}
+	if len(city) > 0 {
+		query.Set("city", city)
+	}
+	if rebalance {
+		query.Set("rebalance", "true")
+	}
+	if err := c.send(http.MethodGet, fmt.Sprintf("%s/v0.1/stations?%s", c.baseUrl, query.Encode()), "", nil, &stations); err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// fetch gets the url on behalf of the user when userID isn't empty, returns the raw response body.
+func (c *Client) fetch(url, userID string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(userID) > 0 {
+		req.Header.Set("user-id", userID)
+	}
+	if len(c.adminToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	} else if len(c.scooterToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.scooterToken)
+	}
+
+	h := &http.Client{}
+	resp, err := h.Do(req)
+	if err != nil {
+		return nil, err
+	} else if resp.StatusCode != http.StatusOK {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// send does the request on behalf of the user when userID isn't empty, the in value is sent as the JSON body
+// when it's not nil and the JSON response is decoded into out.
+func (c *Client) send(method, url, userID string, in, out interface{}) error {
+	var (
+		body io.Reader
+		resp *http.Response
+		err  error
+	)
+	if in != nil {
+		j, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewBuffer(j)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if len(userID) > 0 {
+		req.Header.Set("user-id", userID)
+	}
+	if len(c.adminToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	} else if len(c.scooterToken) > 0 {
+		req.Header.Set("Authorization", "Bearer "+c.scooterToken)
+	}
+
+	h := &http.Client{}
+	if resp, err = h.Do(req); err != nil {
+		return err
+	} else if resp.StatusCode != http.StatusOK {
+		return decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
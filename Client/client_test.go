@@ -2,10 +2,17 @@ package client
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
 	"scootin/db"
+	"scootin/geo"
 	"scootin/logger"
 	"scootin/models"
+	"scootin/payment"
+	"scootin/pricing"
+	"scootin/service"
 	"testing"
 	"time"
 
@@ -19,9 +26,21 @@ func TestClient(t *testing.T) {
 		uid *models.UUIDResponse
 	)
 
+	// initialize the logging and the service backed by the in-memory repository
+	log := logger.NewLogger()
+	logger.InitLogger(log)
+	defer logger.Sync()
+
+	db.SetRepository(db.NewInMemory())
+	service.SetAdminToken(adminToken)
+	service.SetTelemetryToken(scooterToken)
+	payments := payment.NewFake(payment.FakeApprove)
+	service.SetPaymentProvider(payments, 100*time.Millisecond)
+	srv := httptest.NewServer(service.NewRouter())
+	defer srv.Close()
+
 	// create the client
-	baseUrl := "http://localhost:8080"
-	c := NewClient(baseUrl)
+	c := NewClient(srv.URL)
 
 	////////////////////  create Users  //////////////////////
 	u1 := &models.User{Name: "David", Email: "david@scootin.com"}
@@ -56,62 +75,794 @@ func TestClient(t *testing.T) {
 	for _, scooterID := range scooterIDs {
 		assert.True(t, expectedScootersMap[scooterID])
 	}
+	////////////////////  ListAvailableScooterNear  //////////////////////
+	// only the 2 scooters within 1km are found, the nearest first
+	near, err := c.ListAvailableScooterNear(center, 1000, 10)
+	assert.NoError(t, err)
+	assert.Len(t, near, 2)
+	assert.Equal(t, scooterIDs[0], near[0].ID)
+	assert.Equal(t, scooterIDs[1], near[1].ID)
+	assert.InDelta(t, 100, near[0].Distance, 1)
+
+	near, err = c.ListAvailableScooterNear(center, 5000, 1)
+	assert.NoError(t, err)
+	assert.Len(t, near, 1)
+	assert.Equal(t, scooterIDs[0], near[0].ID)
+
 	////////////////////////////   BookScooter  ///////////////////////////
 	// book the scooter sc1 by the user u1
-	err = c.BookScooter(scooterIDs[0], u1.ID)
+	trip1, err := c.BookScooter(scooterIDs[0], u1.ID, &scooterLocations[0])
 	assert.NoError(t, err)
+	assert.Equal(t, scooterIDs[0], trip1.ScooterID)
+	assert.Equal(t, u1.ID, trip1.UserID)
+	assert.Nil(t, trip1.EndedAt)
 
 	// we should receive an error if we tried to book the same scooter by another user
-	err = c.BookScooter(scooterIDs[0], u2.ID)
-	assert.Error(t, err)
-
-	// book the scooter sc2 by the user u2
-	err = c.BookScooter(scooterIDs[1], u2.ID)
+	_, err = c.BookScooter(scooterIDs[0], u2.ID, &scooterLocations[0])
+	assert.ErrorIs(t, err, models.ErrScooterOccupied)
+
+	// a user rides one scooter at a time
+	_, err = c.BookScooter(scooterIDs[1], u1.ID, &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrActiveTripExists)
+
+	// unknown scooters and users can't book
+	_, err = c.BookScooter(scooterIDs[1], "unknown", &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+	_, err = c.BookScooter("unknown", u2.ID, &center)
+	assert.ErrorIs(t, err, models.ErrScooterNotFound)
+	_, err = c.BookScooter(scooterIDs[1], "", &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrMissingUserID)
+
+	// the rider must stand by the scooter, the operators book from anywhere
+	_, err = c.BookScooter(scooterIDs[1], u2.ID, &scooterLocations[2])
+	assert.ErrorIs(t, err, models.ErrScooterTooFar)
+	_, err = c.BookScooter(scooterIDs[1], u2.ID, nil)
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+
+	// book the scooter sc2 for the user u2 by an operator
+	_, err = c.WithAdminToken(adminToken).BookScooter(scooterIDs[1], u2.ID, nil)
 	assert.NoError(t, err)
 
 	// checks all available scooters, we booked 2, so we have 1 left available
 	scs, err = c.ListAvailableScooter()
 	assert.NoError(t, err)
 	assert.Len(t, scs, 1)
-	assert.Equal(t, scs[0].UserID, models.NotOccupied)
+	assert.Equal(t, models.StatusAvailable, scs[0].Status)
 
 	////////////////////////////   ReleaseScooter  ///////////////////////////
-	err = c.ReleaseScooter(u1.ID)
+	trip, err := c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, trip1.ID, trip.ID)
+	// a short ride costs the default plan's unlock fee and first minute
+	assert.Equal(t, "EUR", trip.Fare.Currency)
+	assert.Equal(t, int64(125), trip.Fare.Total)
+
+	// the released scooter's trip is ended
+	trip, err = c.GetTrip(trip1.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, trip.EndedAt)
+	assert.NotNil(t, trip.EndLocation)
+	assert.NotNil(t, trip.Fare)
+	trips, err := c.ListUserTrips(u1.ID)
 	assert.NoError(t, err)
+	assert.Len(t, trips, 1)
+	assert.Equal(t, trip1.ID, trips[0].ID)
+	_, err = c.GetTrip("unknown")
+	assert.ErrorIs(t, err, models.ErrTripNotFound)
+	_, err = c.ListUserTrips("unknown")
+	assert.ErrorIs(t, err, models.ErrUserNotFound)
+
+	// nothing is left to release
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.ErrorIs(t, err, models.ErrNoActiveTrip)
+
 	// checks all available scooters, we have 1 booked, so we have 2 left available
 	scs, err = c.ListAvailableScooter()
 	assert.NoError(t, err)
 	assert.Len(t, scs, 2)
-	assert.Equal(t, scs[0].UserID, models.NotOccupied)
-	assert.Equal(t, scs[1].UserID, models.NotOccupied)
+	assert.Equal(t, models.StatusAvailable, scs[0].Status)
+	assert.Equal(t, models.StatusAvailable, scs[1].Status)
 
-	err = c.ReleaseScooter(u2.ID)
+	_, err = c.ReleaseScooter(u2.ID)
 	assert.NoError(t, err)
 	// checks all available scooters, we have 0 booked, so we have 3 left available
 	scs, err = c.ListAvailableScooter()
 	assert.NoError(t, err)
 	assert.Len(t, scs, 3)
-	assert.Equal(t, scs[0].UserID, models.NotOccupied)
-	assert.Equal(t, scs[1].UserID, models.NotOccupied)
-	assert.Equal(t, scs[2].UserID, models.NotOccupied)
+	assert.Equal(t, models.StatusAvailable, scs[0].Status)
+	assert.Equal(t, models.StatusAvailable, scs[1].Status)
+	assert.Equal(t, models.StatusAvailable, scs[2].Status)
 
-	////////////////////////////   Scooters' Simulation  ///////////////////////////
-	// initialize postgres connection and logging for the simulation
-	setupEnv(t)
-	err = db.InitiatePostgre()
+	////////////////////////////   SetScooterStatus  ///////////////////////////
+	// only the operators can change a scooter's status
+	_, err = c.SetScooterStatus(scooterIDs[2], models.StatusMaintenance)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+
+	admin := c.WithAdminToken(adminToken)
+	sc, err := admin.SetScooterStatus(scooterIDs[2], models.StatusMaintenance)
 	assert.NoError(t, err)
+	assert.Equal(t, models.StatusMaintenance, sc.Status)
 
-	log := logger.NewLogger()
-	logger.InitLogger(log)
-	defer logger.Sync()
+	// a scooter in maintenance is neither listed nor bookable
+	scs, err = c.ListAvailableScooter()
+	assert.NoError(t, err)
+	assert.Len(t, scs, 2)
+	_, err = c.BookScooter(scooterIDs[2], u3.ID, &scooterLocations[2])
+	assert.ErrorIs(t, err, models.ErrScooterUnavailable)
+
+	// the riders' states are only entered by booking
+	_, err = admin.SetScooterStatus(scooterIDs[2], models.StatusInTrip)
+	assert.ErrorIs(t, err, models.ErrInvalidTransition)
+
+	_, err = admin.SetScooterStatus(scooterIDs[2], models.StatusAvailable)
+	assert.NoError(t, err)
+	scs, err = c.ListAvailableScooter()
+	assert.NoError(t, err)
+	assert.Len(t, scs, 3)
+
+	////////////////////////////   Pricing  ///////////////////////////
+	// a ride is quoted by the plan in effect
+	fare, err := c.FareEstimate("berlin", "", 10*time.Minute, 2000, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(350), fare.Total)
+
+	// only the operators set the prices
+	plan := &models.PricePlan{City: "berlin", Currency: "EUR", UnlockFee: 0, PerMinute: 20, PerKilometer: 50}
+	_, err = c.CreatePricePlan(plan)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	plan, err = admin.CreatePricePlan(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, plan.Version)
+	assert.Equal(t, models.DefaultVehicleType, plan.VehicleType)
+	_, err = admin.CreatePricePlan(&models.PricePlan{Currency: "EURO"})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	plans, err := admin.ListPricePlans()
+	assert.NoError(t, err)
+	assert.Len(t, plans, 2)
+
+	fare, err = c.FareEstimate("berlin", "", 10*time.Minute, 2000, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, plan.ID, fare.PlanID)
+	assert.Equal(t, int64(300), fare.Total)
+	_, err = c.FareEstimate("berlin", "bike", time.Minute, 0, nil)
+	assert.ErrorIs(t, err, models.ErrPricePlanNotFound)
+
+	////////////////////////////   Payment holds  ///////////////////////////
+	// trip1's fare was charged from the hold placed when it started
+	_, err = c.CreateCheckout(u1.ID, &CheckoutCreate{TripID: trip1.ID})
+	assert.ErrorIs(t, err, models.ErrTripAlreadyPaid)
+	assert.Equal(t, 0, payments.OpenHolds())
+
+	// a declined hold refuses the trip, a trip which doesn't start releases its hold
+	payments.SetMode(payment.FakeDecline)
+	_, err = c.BookScooter(scooterIDs[1], u3.ID, &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrPaymentDeclined)
+	payments.SetMode(payment.FakeApprove)
+	_, err = c.BookScooter("unknown", u3.ID, &center)
+	assert.ErrorIs(t, err, models.ErrScooterNotFound)
+	assert.Equal(t, 0, payments.OpenHolds())
+
+	////////////////////////////   Checkouts  ///////////////////////////
+	// only the trip's user pays it once it has ended
+	trip, err = c.BookScooter(scooterIDs[1], u3.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 1, payments.OpenHolds())
+	_, err = c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrTripNotFinished)
+
+	// the fare the hold couldn't pay is left to a checkout
+	payments.SetMode(payment.FakeDecline)
+	trip, err = c.ReleaseScooter(u3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, payments.OpenHolds())
+	_, err = c.CreateCheckout(u2.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID, Amount: 1})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+
+	// the declined and timed out payments can be retried
+	_, err = c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrPaymentDeclined)
+	payments.SetMode(payment.FakeTimeout)
+	_, err = c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrPaymentTimeout)
+
+	payments.SetMode(payment.FakeApprove)
+	checkout, err := c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID, Amount: trip.Fare.Total, Currency: "EUR"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.CheckoutCaptured, checkout.Status)
+	assert.Equal(t, "SCOOTIN", checkout.MerchantCode)
+	assert.NotEmpty(t, checkout.ProviderReference)
+	assert.True(t, payments.Captured(checkout.ID))
+	_, err = c.CreateCheckout(u3.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrTripAlreadyPaid)
+
+	checkout, err = c.GetCheckout(checkout.ID, u3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CheckoutCaptured, checkout.Status)
+	assert.NotNil(t, checkout.CompletedAt)
+	_, err = c.GetCheckout(checkout.ID, u2.ID)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = c.GetCheckout("unknown", u1.ID)
+	assert.ErrorIs(t, err, models.ErrCheckoutNotFound)
+
+	////////////////////////////   Wallets  ///////////////////////////
+	// the fares are charged to the riders' wallets and the payments credit them
+	wallet, err := c.GetWallet(u3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 0, Currency: "EUR"}}, wallet.Balances)
+
+	// a wallet in debt refuses the trips until it's topped up
+	trip, err = c.BookScooter(scooterIDs[1], u3.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	payments.SetMode(payment.FakeDecline)
+	trip, err = c.ReleaseScooter(u3.ID)
+	assert.NoError(t, err)
+	payments.SetMode(payment.FakeApprove)
+	_, err = c.BookScooter(scooterIDs[1], u3.ID, &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrInsufficientBalance)
+	_, err = c.TopUpWallet(u3.ID, models.Money{Amount: trip.Fare.Total, Currency: "EUR"})
+	assert.NoError(t, err)
+	_, err = c.BookScooter(scooterIDs[1], u3.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	_, err = c.ReleaseScooter(u3.ID)
+	assert.NoError(t, err)
+
+	// only the operators credit promotions
+	_, err = c.CreditPromo(u3.ID, models.Money{Amount: 500, Currency: "EUR"})
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.CreditPromo(u3.ID, models.Money{Amount: 500, Currency: "EUR"})
+	assert.NoError(t, err)
+	wallet, err = c.GetWallet(u3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 500, Currency: "EUR"}}, wallet.Balances)
 
-	// create runtime scooters
+	transactions, err := c.ListWalletTransactions(u3.ID)
+	assert.NoError(t, err)
+	kinds := make([]models.LedgerKind, 0)
+	for _, tx := range transactions {
+		kinds = append(kinds, tx.Kind)
+	}
+	assert.Equal(t, []models.LedgerKind{models.LedgerFare, models.LedgerPayment, models.LedgerFare, models.LedgerTopUp,
+		models.LedgerFare, models.LedgerPayment, models.LedgerPromo}, kinds)
+	_, err = c.TopUpWallet(u3.ID, models.Money{Amount: -1, Currency: "EUR"})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+
+	////////////////////////////   Disputes  ///////////////////////////
+	// a rider disputes an ended trip once
+	_, err = c.CreateDispute(u2.ID, &models.DisputeCreate{TripID: checkout.TripID, Reason: "the scooter broke"})
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	dispute, err := c.CreateDispute(u3.ID, &models.DisputeCreate{TripID: checkout.TripID, Reason: "the scooter broke"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeOpen, dispute.Status)
+	_, err = c.CreateDispute(u3.ID, &models.DisputeCreate{TripID: checkout.TripID, Reason: "again"})
+	assert.ErrorIs(t, err, models.ErrDisputeExists)
+
+	// support reviews it with the trip before deciding
+	_, err = admin.DecideDispute(dispute.ID, &models.DisputeDecision{Status: models.DisputeRefunded})
+	assert.ErrorIs(t, err, models.ErrInvalidDisputeStatus)
+	_, err = c.ReviewDispute(dispute.ID)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	review, err := admin.ReviewDispute(dispute.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeUnderReview, review.Dispute.Status)
+	assert.Equal(t, checkout.TripID, review.Trip.ID)
+	assert.NotNil(t, review.Track)
+	_, err = admin.ReviewDispute(dispute.ID)
+	assert.ErrorIs(t, err, models.ErrInvalidDisputeStatus)
+
+	// a paid fare is refunded, wholly or partly, through the payment provider
+	_, err = admin.DecideDispute(dispute.ID, &models.DisputeDecision{Status: models.DisputeRefunded, Amount: checkout.Amount + 1})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	dispute, err = admin.DecideDispute(dispute.ID, &models.DisputeDecision{Status: models.DisputeRefunded, Amount: checkout.Amount / 2,
+		Note: "broken brake"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeRefunded, dispute.Status)
+	assert.Equal(t, checkout.Amount/2, dispute.RefundAmount)
+	assert.NotEmpty(t, dispute.RefundReference)
+	assert.Equal(t, checkout.Amount/2, payments.Refunded(checkout.ProviderReference))
+	dispute, err = c.GetDispute(dispute.ID, u3.ID)
+	assert.NoError(t, err)
+	assert.Len(t, dispute.Events, 3)
+	transactions, err = c.ListWalletTransactions(u3.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.LedgerRefund, transactions[len(transactions)-1].Kind)
+
+	// or the dispute is rejected
+	dispute, err = c.CreateDispute(u1.ID, &models.DisputeCreate{TripID: trip1.ID, Reason: "too expensive"})
+	assert.NoError(t, err)
+	_, err = admin.ReviewDispute(dispute.ID)
+	assert.NoError(t, err)
+	dispute, err = admin.DecideDispute(dispute.ID, &models.DisputeDecision{Status: models.DisputeRejected, Note: "the fare is right"})
+	assert.NoError(t, err)
+	assert.Equal(t, models.DisputeRejected, dispute.Status)
+	assert.Zero(t, dispute.RefundAmount)
+	disputes, err := admin.ListDisputes(models.DisputeRefunded)
+	assert.NoError(t, err)
+	assert.Len(t, disputes, 1)
+
+	////////////////////////////   Promo codes  ///////////////////////////
+	// the operators run the campaigns
+	firstFree := &models.PromoCode{Code: "firstfree", Kind: models.PromoPercentOff, Percent: 100, Target: models.PromoTargetTotal,
+		FirstRideOnly: true, PerUserLimit: 1}
+	_, err = c.CreatePromoCode(firstFree)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	firstFree, err = admin.CreatePromoCode(firstFree)
+	assert.NoError(t, err)
+	assert.Equal(t, "FIRSTFREE", firstFree.Code)
+	_, err = admin.CreatePromoCode(firstFree)
+	assert.ErrorIs(t, err, models.ErrPromoExists)
+	_, err = admin.CreatePromoCode(&models.PromoCode{Code: "GIFT10", Kind: models.PromoVoucher, Amount: 1000, Currency: "EUR", MaxRedemptions: 1})
+	assert.NoError(t, err)
+	_, err = admin.CreatePromoCode(&models.PromoCode{Code: "BROKEN", Kind: models.PromoPercentOff, Percent: 150})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+
+	// a new rider redeems them, a gift voucher is credited to the wallet
+	u4 := &models.User{Name: "Nora", Email: "nora@scootin.com"}
+	uid, err = c.CreateUser(u4)
+	assert.NoError(t, err)
+	u4.ID = uid.ID
+	_, err = c.RedeemPromoCode(u4.ID, "unknown")
+	assert.ErrorIs(t, err, models.ErrPromoNotFound)
+	_, err = c.RedeemPromoCode(u4.ID, "firstfree")
+	assert.NoError(t, err)
+	_, err = c.RedeemPromoCode(u4.ID, "FIRSTFREE")
+	assert.ErrorIs(t, err, models.ErrPromoAlreadyRedeemed)
+	_, err = c.RedeemPromoCode(u4.ID, "gift10")
+	assert.NoError(t, err)
+	_, err = c.RedeemPromoCode(u3.ID, "gift10")
+	assert.ErrorIs(t, err, models.ErrPromoUnavailable)
+	wallet, err = c.GetWallet(u4.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 1000, Currency: "EUR"}}, wallet.Balances)
+
+	// the first ride is free and the discount shows on its fare, the next one isn't
+	_, err = c.BookScooter(scooterIDs[1], u4.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	trip, err = c.ReleaseScooter(u4.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "FIRSTFREE", trip.Fare.PromoCode)
+	assert.Equal(t, trip.Fare.UnlockFee+trip.Fare.TimeCharge+trip.Fare.DistanceCharge, trip.Fare.Discount)
+	assert.Zero(t, trip.Fare.Total)
+	assert.Equal(t, 0, payments.OpenHolds())
+	_, err = c.BookScooter(scooterIDs[1], u4.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	trip, err = c.ReleaseScooter(u4.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, trip.Fare.PromoCode)
+	assert.Positive(t, trip.Fare.Total)
+
+	// the gift voucher pays the next one rather than the card
+	assert.Less(t, trip.Fare.Total, int64(1000))
+	assert.Zero(t, payments.Charged(u4.ID))
+	assert.Equal(t, 0, payments.OpenHolds())
+	_, err = c.CreateCheckout(u4.ID, &CheckoutCreate{TripID: trip.ID})
+	assert.ErrorIs(t, err, models.ErrTripAlreadyPaid)
+	wallet, err = c.GetWallet(u4.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 1000 - trip.Fare.Total, Currency: "EUR"}}, wallet.Balances)
+	redemptions, err := c.ListPromoRedemptions(u4.ID)
+	assert.NoError(t, err)
+	assert.Len(t, redemptions, 2)
+	assert.Equal(t, 1, redemptions[0].Uses)
+
+	////////////////////////////   Passes  ///////////////////////////
+	// the operators sell a day pass with 30 free minutes a day
+	dayPass := &models.PassPlan{Name: "Day pass", Period: models.PassDay, Price: 300, Currency: "EUR", FreeMinutes: 30}
+	_, err = c.CreatePassPlan(dayPass)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.CreatePassPlan(&models.PassPlan{Name: "Nothing", Period: models.PassDay, Currency: "EUR"})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	dayPass, err = admin.CreatePassPlan(dayPass)
+	assert.NoError(t, err)
+	passPlans, err := c.ListPassPlans()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.PassPlan{*dayPass}, passPlans)
+
+	// a rider buys it through the payment provider, its minutes are free
+	_, err = c.BuyPass(u4.ID, "unknown", false)
+	assert.ErrorIs(t, err, models.ErrPassPlanNotFound)
+	pass, err := c.BuyPass(u4.ID, dayPass.ID, false)
+	assert.NoError(t, err)
+	assert.True(t, payments.Captured(pass.ID))
+	assert.Equal(t, 24*time.Hour, pass.EndsAt.Sub(pass.StartsAt))
+	_, err = c.BookScooter(scooterIDs[1], u4.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	trip, err = c.ReleaseScooter(u4.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, pass.ID, trip.Fare.PassID)
+	assert.Equal(t, trip.Fare.Minutes, trip.Fare.FreeMinutes)
+	assert.Equal(t, trip.Fare.TimeCharge, trip.Fare.PassCredit)
+	assert.Equal(t, trip.Fare.UnlockFee+trip.Fare.DistanceCharge, trip.Fare.Total)
+
+	// the rider sees what's left of today's allowances
+	allowances, err := c.ListUserPasses(u4.ID)
+	assert.NoError(t, err)
+	assert.Len(t, allowances, 1)
+	assert.Equal(t, pass.ID, allowances[0].Pass.ID)
+	assert.Equal(t, 30-trip.Fare.Minutes, allowances[0].MinutesLeft)
+
+	////////////////////////////   Reservations  ///////////////////////////
+	// a reserved scooter is held for its user until it's converted to a trip
+	reservation, err := c.ReserveScooter(scooterIDs[0], u1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationActive, reservation.Status)
+	assert.True(t, reservation.ExpiresAt.After(reservation.CreatedAt))
+	_, err = c.BookScooter(scooterIDs[0], u2.ID, &scooterLocations[0])
+	assert.ErrorIs(t, err, models.ErrScooterOccupied)
+	_, err = c.ReserveScooter(scooterIDs[1], u1.ID)
+	assert.ErrorIs(t, err, models.ErrActiveReservationExists)
+	_, err = c.CancelReservation(reservation.ID, u2.ID)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+
+	// the rider must still stand by the scooter to ride it off
+	_, err = c.ConvertReservation(reservation.ID, u1.ID, nil)
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = c.ConvertReservation(reservation.ID, u1.ID, &scooterLocations[2])
+	assert.ErrorIs(t, err, models.ErrScooterTooFar)
+	trip, err = c.ConvertReservation(reservation.ID, u1.ID, &scooterLocations[0])
+	assert.NoError(t, err)
+	assert.Equal(t, scooterIDs[0], trip.ScooterID)
+	reservation, err = c.GetReservation(reservation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationConverted, reservation.Status)
+	assert.Equal(t, trip.ID, reservation.TripID)
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+
+	// a cancelled reservation returns its scooter
+	reservation, err = c.ReserveScooter(scooterIDs[1], u2.ID)
+	assert.NoError(t, err)
+	reservation, err = c.CancelReservation(reservation.ID, u2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationCancelled, reservation.Status)
+	_, err = c.ConvertReservation(reservation.ID, u2.ID, &scooterLocations[1])
+	assert.ErrorIs(t, err, models.ErrReservationNotActive)
+
+	// so does an expired one
+	reservation, err = c.ReserveScooter(scooterIDs[1], u2.ID)
+	assert.NoError(t, err)
+	_, err = db.ExpireReservations(context.Background(), reservation.ExpiresAt)
+	assert.NoError(t, err)
+	reservation, err = c.GetReservation(reservation.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationExpired, reservation.Status)
+	_, err = c.GetReservation("unknown")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+	scs, err = c.ListAvailableScooter()
+	assert.NoError(t, err)
+	assert.Len(t, scs, 3)
+
+	////////////////////////////   Surge pricing  ///////////////////////////
+	// a zone surges once its reservations outnumber half its available scooters
+	service.SetSurgePolicy(pricing.SurgePolicy{Threshold: 0.5, Sensitivity: 1, MaxMultiplier: 3})
+	fare, err = c.FareEstimate("berlin", "", 10*time.Minute, 0, &scooterLocations[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, fare.Surge)
+	reservation, err = c.ReserveScooter(scooterIDs[1], u2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, reservation.Surge)
+	fare, err = c.FareEstimate("berlin", "", 10*time.Minute, 0, &scooterLocations[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, fare.Surge)
+	assert.Equal(t, int64(300), fare.TimeCharge)
+
+	// the surge is locked in when the scooter is booked, whatever the zone's demand by the release
+	trip, err = c.BookScooter(scooterIDs[0], u1.ID, &scooterLocations[0])
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, trip.Surge)
+	_, err = c.CancelReservation(reservation.ID, u2.ID)
+	assert.NoError(t, err)
+	trip, err = c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, trip.Fare.Surge)
+	assert.Equal(t, int64(30), trip.Fare.TimeCharge)
+	service.SetSurgePolicy(pricing.SurgePolicy{})
+
+	////////////////////////////   Invoices  ///////////////////////////
+	// the operators set the VAT of the cities, the rule without a city applies to the others
+	berlinVAT := &models.TaxRule{City: "berlin", LegalEntity: "DE01", EntityName: "Scootin GmbH", VATID: "DE123456789", VATRate: 1900}
+	_, err = c.SetTaxRule(berlinVAT)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.SetTaxRule(&models.TaxRule{City: "berlin", LegalEntity: "DE01", VATRate: 1900})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = admin.SetTaxRule(berlinVAT)
+	assert.NoError(t, err)
+	rules, err := admin.ListTaxRules()
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TaxRule{*berlinVAT}, rules)
+
+	// a month is invoiced once it's over, the past month had no rides
+	_, err = admin.IssueInvoices(time.Now().UTC().Format("2006-01"))
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	run, err := admin.IssueInvoices(time.Now().UTC().AddDate(0, 0, -time.Now().UTC().Day()).Format("2006-01"))
+	assert.NoError(t, err)
+	assert.Empty(t, run.Issued)
+	assert.Empty(t, run.Unbilled)
+	invoices, err := c.ListUserInvoices(u4.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, invoices)
+	_, err = c.GetInvoice("unknown", u4.ID)
+	assert.ErrorIs(t, err, models.ErrInvoiceNotFound)
+
+	// the accountants export the ledger of the month so far
+	_, err = c.ExportLedgerCSV(time.Now().Add(-time.Hour), time.Now())
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	export, err := admin.ExportLedgerCSV(time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Contains(t, string(export), "transaction_id,created_at,kind,user_id,reference,currency,account,amount")
+	assert.Contains(t, string(export), pass.ID)
+
+	////////////////////////////   Zones  ///////////////////////////
+	// the trips end within the city center and out of the station's square, where the scooters may not ride
+	city := &models.Zone{Name: "Center", Tags: []models.ZoneTag{models.ZoneOperatingArea}, Geometry: squareGeometry(center, 0.01)}
+	_, err = c.CreateZone(city)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.CreateZone(&models.Zone{Name: "Nowhere", Tags: []models.ZoneTag{models.ZoneNoParking}})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	city, err = admin.CreateZone(city)
+	assert.NoError(t, err)
+	station := models.Location{Latitude: center.Latitude, Longitude: 13.412}
+	stationZone, err := admin.CreateZone(&models.Zone{Name: "Station", Tags: []models.ZoneTag{models.ZoneNoParking, models.ZoneNoRiding},
+		Geometry: squareGeometry(station, 0.001)})
+	assert.NoError(t, err)
+	zones, err := admin.ListZones()
+	assert.NoError(t, err)
+	assert.Len(t, zones, 2)
+
+	// riding into the station raises an alert and the trip can't end there
+	_, err = c.BookScooter(scooterIDs[1], u1.ID, &scooterLocations[1])
+	assert.NoError(t, err)
+	moveScooter(t, scooterIDs[1], station)
+	alerts, err := admin.ListZoneAlerts(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, stationZone.ID, alerts[0].ZoneID)
+		assert.Equal(t, u1.ID, alerts[0].UserID)
+	}
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.ErrorIs(t, err, models.ErrNoParkingZone)
+	moveScooter(t, scooterIDs[1], scooterLocations[1])
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+
+	// nor out of the city until it's grown to reach the scooter
+	_, err = c.BookScooter(scooterIDs[2], u1.ID, &scooterLocations[2])
+	assert.NoError(t, err)
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.ErrorIs(t, err, models.ErrOutsideOperatingArea)
+	city.Geometry = squareGeometry(center, 0.05)
+	_, err = admin.UpdateZone(city)
+	assert.NoError(t, err)
+	_, err = c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+
+	for _, z := range []*models.Zone{city, stationZone} {
+		assert.NoError(t, admin.DeleteZone(z.ID))
+	}
+	_, err = admin.GetZone(city.ID)
+	assert.ErrorIs(t, err, models.ErrZoneNotFound)
+
+	////////////////////////////   Stations  ///////////////////////////
+	// a station docks two scooters around the second one
+	dock := &models.Station{Name: "Alexanderplatz", City: "berlin", Location: scooterLocations[1], Radius: 30, Capacity: 2, DiscountPercent: 20}
+	_, err = c.CreateStation(dock)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.CreateStation(&models.Station{Location: scooterLocations[1], Radius: 30})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	dock, err = admin.CreateStation(dock)
+	assert.NoError(t, err)
+	stations, err := c.ListStations("berlin", false)
+	assert.NoError(t, err)
+	if assert.Len(t, stations, 1) {
+		assert.Equal(t, 1, stations[0].Occupancy)
+		assert.Equal(t, 1, stations[0].FreeSlots)
+	}
+
+	// the trip ended in its free slot gets the discount, the next one doesn't and the station is over capacity
+	_, err = c.BookScooter(scooterIDs[0], u1.ID, &scooterLocations[0])
+	assert.NoError(t, err)
+	moveScooter(t, scooterIDs[0], scooterLocations[1])
+	trip, err = c.ReleaseScooter(u1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, dock.ID, trip.Fare.StationID)
+	assert.Equal(t, (trip.Fare.UnlockFee+trip.Fare.TimeCharge+trip.Fare.DistanceCharge)*20/100, trip.Fare.StationCredit)
+	_, err = c.BookScooter(scooterIDs[2], u2.ID, &scooterLocations[2])
+	assert.NoError(t, err)
+	moveScooter(t, scooterIDs[2], scooterLocations[1])
+	trip, err = c.ReleaseScooter(u2.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, trip.Fare.StationID)
+	stations, err = c.ListStations("", true)
+	assert.NoError(t, err)
+	if assert.Len(t, stations, 1) {
+		assert.Equal(t, 3, stations[0].Occupancy)
+		assert.Zero(t, stations[0].FreeSlots)
+	}
+	stations, err = c.ListStations("hamburg", false)
+	assert.NoError(t, err)
+	assert.Empty(t, stations)
+	moveScooter(t, scooterIDs[0], scooterLocations[0])
+	moveScooter(t, scooterIDs[2], scooterLocations[2])
+
+	////////////////////////////   Telemetry  ///////////////////////////
+	// only the fleet reports the scooters' telemetry, newer than the scooters' last locations
+	now := time.Now().UTC()
+	moved := geo.Move(scooterLocations[0], 50, 0)
+	samples := []models.TelemetrySample{
+		{Time: now.Add(time.Millisecond), Location: geo.Move(scooterLocations[0], 25, 0), Speed: 12, Battery: 80},
+		{Time: now.Add(2 * time.Millisecond), Location: moved, Speed: 18, Battery: 79},
+	}
+	_, err = c.ReportTelemetry(scooterIDs[0], samples)
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	fleet := c.WithScooterToken(scooterToken)
+	_, err = fleet.ReportTelemetry(scooterIDs[0], []models.TelemetrySample{samples[1], samples[0]})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = fleet.ReportTelemetry(scooterIDs[0], []models.TelemetrySample{{Time: now, Location: moved, Battery: 101}})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = fleet.ReportTelemetry(uuid.New().String(), samples)
+	assert.ErrorIs(t, err, models.ErrScooterNotFound)
+
+	receipt, err := fleet.ReportTelemetry(scooterIDs[0], samples)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Accepted: 2}, receipt)
+	sc, err = db.GetScooter(context.Background(), scooterIDs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, moved, sc.Location)
+
+	// a batch sent again only records its new samples
+	back := models.TelemetrySample{Time: now.Add(3 * time.Millisecond), Location: scooterLocations[0], Battery: 79}
+	receipt, err = fleet.ReportTelemetry(scooterIDs[0], append(samples, back))
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Accepted: 1, Duplicates: 2}, receipt)
+	track, err := c.ScooterTrack(scooterIDs[0], now.Add(-time.Minute), now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.True(t, len(track) >= 3) {
+		track = track[len(track)-3:]
+		assert.Equal(t, samples[0].Time, track[0].Time)
+		assert.Equal(t, 18.0, track[1].Speed)
+		assert.Equal(t, 79, track[1].Battery)
+		assert.Equal(t, scooterLocations[0], track[2].Location)
+	}
+
+	////////////////////////////   UDP Telemetry  ///////////////////////////
+	// the frames signed with the fleet's key are recorded, the others dropped
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	udpCtx, stopUDP := context.WithCancel(context.Background())
+	defer stopUDP()
+	service.SetTelemetryKey(telemetryKey)
+	udpDone := make(chan struct{})
+	go func() {
+		service.ServeTelemetry(udpCtx, conn)
+		close(udpDone)
+	}()
+
+	forged, err := DialTelemetry(conn.LocalAddr().String(), []byte("forged-key"))
+	assert.NoError(t, err)
+	defer forged.Close()
+	sender, err := DialTelemetry(conn.LocalAddr().String(), []byte(telemetryKey))
+	assert.NoError(t, err)
+	defer sender.Close()
+
+	reportedAt := func(l models.Location) func() bool {
+		return func() bool {
+			sc, err := db.GetScooter(context.Background(), scooterIDs[0])
+			return err == nil && geo.Distance(sc.Location, l) < 0.1
+		}
+	}
+	assert.NoError(t, forged.Send(scooterIDs[0], models.TelemetrySample{Time: now.Add(4 * time.Millisecond), Location: center}))
+	assert.NoError(t, sender.Send(scooterIDs[0], models.TelemetrySample{Time: now.Add(5 * time.Millisecond), Location: moved, Speed: 14, Battery: 78}))
+	assert.Eventually(t, reportedAt(moved), time.Second, 10*time.Millisecond)
+	track, err = c.ScooterTrack(scooterIDs[0], now.Add(-time.Minute), now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.True(t, len(track) >= 2) {
+		assert.Equal(t, scooterLocations[0], track[len(track)-2].Location)
+		assert.Equal(t, 78, track[len(track)-1].Battery)
+	}
+	assert.Error(t, sender.Send("sc1", models.TelemetrySample{Time: now, Location: moved}))
+
+	// the buffered frames are written behind, each scooter's latest one
+	stopUDP()
+	<-udpDone
+	service.SetPositionBuffer(db.NewPositionBuffer(100, 10))
+	conn, err = net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	bufferedCtx, stopBuffered := context.WithCancel(context.Background())
+	defer stopBuffered()
+	go service.ServeTelemetry(bufferedCtx, conn)
+	go service.FlushPositions(bufferedCtx, 10*time.Millisecond)
+	sender, err = DialTelemetry(conn.LocalAddr().String(), []byte(telemetryKey))
+	assert.NoError(t, err)
+	defer sender.Close()
+	assert.NoError(t, sender.Send(scooterIDs[0], models.TelemetrySample{Time: now.Add(6 * time.Millisecond), Location: center, Battery: 78}))
+	assert.NoError(t, sender.Send(scooterIDs[0], models.TelemetrySample{Time: now.Add(7 * time.Millisecond), Location: scooterLocations[0], Battery: 78}))
+	assert.Eventually(t, reportedAt(scooterLocations[0]), time.Second, 10*time.Millisecond)
+	// the HTTP batches are still recorded right away, every sample of them
+	receipt, err = fleet.ReportTelemetry(scooterIDs[0], []models.TelemetrySample{
+		{Time: now.Add(8 * time.Millisecond), Location: moved, Battery: 77},
+		{Time: now.Add(9 * time.Millisecond), Location: scooterLocations[0], Battery: 76},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Accepted: 2}, receipt)
+	track, err = c.ScooterTrack(scooterIDs[0], now.Add(-time.Minute), now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.True(t, len(track) >= 4) {
+		assert.Equal(t, []int{78, 78, 77, 76}, []int{track[len(track)-4].Battery, track[len(track)-3].Battery,
+			track[len(track)-2].Battery, track[len(track)-1].Battery})
+	}
+	_, err = c.PositionBufferStats()
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	stats, err := admin.PositionBufferStats()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Received)
+	assert.Equal(t, int64(2), stats.Written)
+	assert.Zero(t, stats.Pending)
+	assert.NotNil(t, stats.LastFlushAt)
+	stopBuffered()
+	service.SetPositionBuffer(nil)
+
+	////////////////////////////   Commands  ///////////////////////////
+	// the operators send the commands, the scooters poll and acknowledge them
+	_, err = c.IssueCommand(scooterIDs[0], models.CommandCreate{Kind: models.CommandBeep})
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	_, err = admin.IssueCommand(scooterIDs[0], models.CommandCreate{Kind: "explode"})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = admin.IssueCommand(scooterIDs[0], models.CommandCreate{Kind: models.CommandSpeedLimit})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = admin.IssueCommand(uuid.New().String(), models.CommandCreate{Kind: models.CommandBeep})
+	assert.ErrorIs(t, err, models.ErrScooterNotFound)
+	beep, err := admin.IssueCommand(scooterIDs[0], models.CommandCreate{Kind: models.CommandBeep, TTL: 60})
+	assert.NoError(t, err)
+	assert.Equal(t, models.CommandQueued, beep.State)
+	assert.WithinDuration(t, beep.CreatedAt.Add(time.Minute), beep.ExpiresAt, time.Millisecond)
+
+	_, err = fleet.AckCommand(scooterIDs[0], beep.ID, models.CommandAck{State: models.CommandAcked})
+	assert.ErrorIs(t, err, models.ErrCommandNotDelivered)
+	_, err = c.PollCommands(scooterIDs[0])
+	assert.ErrorIs(t, err, models.ErrForbidden)
+	commands, err := fleet.PollCommands(scooterIDs[0])
+	assert.NoError(t, err)
+	if assert.Len(t, commands, 1) {
+		assert.Equal(t, models.CommandDelivered, commands[0].State)
+	}
+	_, err = fleet.AckCommand(scooterIDs[0], beep.ID, models.CommandAck{State: models.CommandDelivered})
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	_, err = fleet.AckCommand(scooterIDs[0], beep.ID, models.CommandAck{State: models.CommandAcked})
+	assert.NoError(t, err)
+	_, err = fleet.AckCommand(scooterIDs[0], beep.ID, models.CommandAck{State: models.CommandAcked})
+	assert.ErrorIs(t, err, models.ErrCommandNotDelivered)
+	beep, err = admin.GetCommand(beep.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CommandAcked, beep.State)
+	commands, err = fleet.PollCommands(scooterIDs[0])
+	assert.NoError(t, err)
+	assert.Empty(t, commands)
+	commands, err = admin.ListCommands(scooterIDs[0])
+	assert.NoError(t, err)
+	assert.Len(t, commands, 1)
+	_, err = admin.GetCommand(uuid.New().String())
+	assert.ErrorIs(t, err, models.ErrCommandNotFound)
+
+	////////////////////////////   Scooters' Simulation  ///////////////////////////
+	// create runtime scooters, they report through the fleet's client
+	ctx := context.Background()
+	commandsCtx, stopCommands := context.WithCancel(ctx)
+	defer stopCommands()
 	scooters := make([]*Scooter, 0)
 	for _, x := range scs {
-		scooters = append(scooters, NewScooter(x.ID))
+		sc := NewScooter(fleet, x.ID, x.Location)
+		go sc.RunCommands(commandsCtx)
+		scooters = append(scooters, sc)
 	}
 
-	ctx := context.Background()
+	// the parked scooters run the operators' commands too
+	parkedBeep, err := admin.IssueCommand(scooters[2].Info.ID, models.CommandCreate{Kind: models.CommandBeep})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		command, err := admin.GetCommand(parkedBeep.ID)
+		return err == nil && command.State == models.CommandAcked
+	}, 3*time.Second, 100*time.Millisecond)
 
 	// book the first available scooter by the user u1
 	err = scooters[0].Start(ctx, u1.ID)
@@ -130,6 +881,22 @@ func TestClient(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, scs, 0)
 
+	// the runtime scooters run the operators' commands while they ride, the locked one stops
+	time.Sleep(2 * time.Second)
+	lock, err := admin.IssueCommand(scooters[0].Info.ID, models.CommandCreate{Kind: models.CommandLock})
+	assert.NoError(t, err)
+	limit, err := admin.IssueCommand(scooters[1].Info.ID, models.CommandCreate{Kind: models.CommandSpeedLimit, SpeedLimit: 10})
+	assert.NoError(t, err)
+	for _, command := range []*models.Command{lock, limit} {
+		id := command.ID
+		assert.Eventually(t, func() bool {
+			command, err := admin.GetCommand(id)
+			return err == nil && command.State == models.CommandAcked
+		}, 3*time.Second, 100*time.Millisecond)
+	}
+	locked, err := db.GetScooter(ctx, scooters[0].Info.ID)
+	assert.NoError(t, err)
+
 	// end the scooters' trips  after 10s
 	ticker := time.NewTicker(10 * time.Second)
 	endTrips(t, ctx, ticker, scooters)
@@ -138,21 +905,71 @@ func TestClient(t *testing.T) {
 	scs, err = c.ListAvailableScooter()
 	assert.NoError(t, err)
 	assert.Len(t, scs, 3)
+
+	// the locked scooter hasn't moved since, the limited one rode slower
+	assert.Equal(t, locked.Location, scooters[0].Info.Location)
+	limit, err = admin.GetCommand(limit.ID)
+	assert.NoError(t, err)
+	track, err = c.ScooterTrack(scooters[1].Info.ID, *limit.EndedAt, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	limited := 0
+	for _, sample := range track {
+		if sample.Time.After(*limit.EndedAt) {
+			assert.LessOrEqual(t, sample.Speed, 10+1e-9)
+			limited++
+		}
+	}
+	assert.NotZero(t, limited)
+
+	// every location update of the trips is kept in the scooters' history
+	for _, x := range scooters {
+		track, err := c.ScooterTrack(x.Info.ID, time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.NotEmpty(t, track)
+		assert.Equal(t, x.Info.Location, track[len(track)-1].Location)
+	}
+}
+
+// adminToken is the operators' token of the test service
+const adminToken = "test-admin-token"
+
+// scooterToken is the fleet's token of the test service
+const scooterToken = "test-scooter-token"
+
+// telemetryKey is the fleet's key of the test service's UDP telemetry
+const telemetryKey = "test-telemetry-key"
+
+// scooterLocations are the created scooters' locations, 100m, 200m and 2km north of the center
+var (
+	center           = models.Location{Latitude: 52.520008, Longitude: 13.404954}
+	scooterLocations = []models.Location{
+		{Latitude: 52.520907, Longitude: 13.404954},
+		{Latitude: 52.521807, Longitude: 13.404954},
+		{Latitude: 52.537995, Longitude: 13.404954},
+	}
+)
+
+// moveScooter reports the scooter's location now, as its telemetry would
+func moveScooter(t *testing.T, scooterID string, location models.Location) {
+	recorded, err := db.RecordPositions(context.Background(), []models.LocationSample{{ScooterID: scooterID,
+		Time: time.Now().UTC(), Location: location}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recorded)
 }
 
 func createScootersIDs(t *testing.T, c *Client) []string {
 	l := make([]string, 0)
-	uid, err := c.CreateScooter()
+	uid, err := c.CreateScooter(models.ScooterCreate{Location: scooterLocations[0], City: "berlin"})
 	assert.NoError(t, err)
 	assert.True(t, isValidUUID(uid.ID))
 	l = append(l, uid.ID)
 
-	uid, err = c.CreateScooter()
+	uid, err = c.CreateScooter(models.ScooterCreate{Location: scooterLocations[1], City: "berlin"})
 	assert.NoError(t, err)
 	assert.True(t, isValidUUID(uid.ID))
 	l = append(l, uid.ID)
 
-	uid, err = c.CreateScooter()
+	uid, err = c.CreateScooter(models.ScooterCreate{Location: scooterLocations[2], City: "berlin"})
 	assert.NoError(t, err)
 	assert.True(t, isValidUUID(uid.ID))
 	l = append(l, uid.ID)
@@ -160,6 +977,12 @@ func createScootersIDs(t *testing.T, c *Client) []string {
 	return l
 }
 
+// squareGeometry returns the GeoJSON polygon of the square of the half side in degrees around the location
+func squareGeometry(l models.Location, half float64) models.Geometry {
+	return models.Geometry{Type: "Polygon", Coordinates: json.RawMessage(fmt.Sprintf("[[[%[1]f,%[2]f],[%[3]f,%[2]f],[%[3]f,%[4]f],[%[1]f,%[4]f],[%[1]f,%[2]f]]]",
+		l.Longitude-half, l.Latitude-half, l.Longitude+half, l.Latitude+half))}
+}
+
 // isValidUUID validates uuid id
 func isValidUUID(u string) bool {
 	_, err := uuid.Parse(u)
@@ -176,20 +999,6 @@ func scooterInfoSliceToMap(ps []models.ScooterInfo) map[string]bool {
 	return m
 }
 
-// setupEnv sets the postgres envs
-func setupEnv(t *testing.T) {
-	err := os.Setenv("POSTGRES_USER", "postgres")
-	assert.NoError(t, err)
-	err = os.Setenv("POSTGRES_PASSWORD", "12345")
-	assert.NoError(t, err)
-	err = os.Setenv("POSTGRES_DATABASE", "dev_db")
-	assert.NoError(t, err)
-	err = os.Setenv("POSTGRES_HOST", "localhost")
-	assert.NoError(t, err)
-	err = os.Setenv("POSTGRES_PORT", "5432")
-	assert.NoError(t, err)
-}
-
 // endTrips ends all the scooter trip after the time specified for the simulation
 func endTrips(t *testing.T, ctx context.Context, ticker *time.Ticker, scooters []*Scooter) {
 	for {
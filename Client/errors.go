@@ -0,0 +1,32 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"scootin/models"
+	"strings"
+)
+
+// decodeError returns the error of a failed response, the problem responses of the service
+// are decoded back into their domain errors so they can be checked with errors.Is.
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	var problem models.Problem
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil || json.Unmarshal(body, &problem) != nil || len(problem.Code) == 0 {
+		return fmt.Errorf("Received http status: %v", resp.StatusCode)
+	}
+	domainErr := models.ErrorByCode(problem.Code)
+	if domainErr == nil {
+		return fmt.Errorf("Received http status: %v, %s: %s", resp.StatusCode, problem.Code, problem.Detail)
+	}
+	// the detail starts with the domain error's message
+	detail := strings.TrimPrefix(strings.TrimPrefix(problem.Detail, domainErr.Message), ": ")
+	if len(detail) == 0 {
+		return domainErr
+	}
+	return models.Errorf(domainErr, "%s", detail)
+}
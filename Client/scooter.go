@@ -3,37 +3,49 @@ package client
 
 import (
 	"context"
+	"errors"
 	"math/rand"
-	"scootin/db"
+	"scootin/geo"
 	"scootin/logger"
 	"scootin/models"
 	"sync"
 	"time"
 )
 
+// maxPendingSamples is the most samples a scooter keeps while it can't report them, the oldest are dropped first
+const maxPendingSamples = 500
+
 // Scooter embodies the scooter functionality i.e, scooter runtime instance.
 type Scooter struct {
-	Info models.ScooterInfo
-	Done chan bool
-	mu   *sync.Mutex
+	Info    models.ScooterInfo
+	Done    chan bool
+	mu      *sync.Mutex
+	client  *Client // reports to the service with the fleet's token
+	battery int
+	pending []models.TelemetrySample // the samples not reported yet, the oldest first
+	// set by the operators' commands
+	locked     bool
+	speedLimit float64 // km/h, none when 0
 }
 
 // LocationUpdate contains the time, and geographical coordinates.
 type LocationUpdate struct {
-	ScooterID   string
-	Time        time.Time
-	Coordinates int64 // represents the scooter location update
+	ScooterID string
+	Time      time.Time
+	Location  models.Location // represents the scooter location update
 }
 
-// NewScooter returns a new scooter runtime instance
-func NewScooter(ID string) *Scooter {
+// NewScooter returns a new scooter runtime instance at the location, reporting through the client
+func NewScooter(c *Client, ID string, location models.Location) *Scooter {
 	return &Scooter{Info: models.ScooterInfo{
-		ID:           ID,
-		UserID:       models.NotOccupied,
-		Coordination: 1,
+		ID:       ID,
+		Status:   models.StatusAvailable,
+		Location: location,
 	},
-		Done: make(chan bool),
-		mu:   &sync.Mutex{}}
+		Done:    make(chan bool),
+		mu:      &sync.Mutex{},
+		client:  c,
+		battery: 100}
 
 }
 
@@ -43,7 +55,8 @@ func (s *Scooter) Start(ctx context.Context, userID string) error {
 	defer s.mu.Unlock()
 
 	s.Info.UserID = userID
-	if err := db.BookScooter(ctx, s.Info.ID, s.Info.UserID); err != nil {
+	// the rider stands by the scooter
+	if _, err := s.client.BookScooter(s.Info.ID, s.Info.UserID, &s.Info.Location); err != nil {
 		return err
 	}
 	go s.Updates(ctx) // periodic updates
@@ -53,11 +66,14 @@ func (s *Scooter) Start(ctx context.Context, userID string) error {
 // End reports an event when a trip ends
 func (s *Scooter) End(ctx context.Context) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if err := db.ReleaseScooter(ctx, s.Info.UserID); err != nil {
+	// the trip ends where the scooter last reported
+	s.report()
+	_, err := s.client.ReleaseScooter(s.Info.UserID)
+	s.mu.Unlock()
+	if err != nil {
 		return err
 	}
+	// the lock must be released before signaling, the update() routine may be waiting on it
 	s.Done <- true // signal end of the trip to the update() routine
 	return nil
 }
@@ -71,24 +87,100 @@ func (s *Scooter) Updates(ctx context.Context) {
 			return
 		case <-ticker.C:
 			s.mu.Lock()
-			s.Info.Coordination += randomDistance()
+			// a locked scooter doesn't move
+			if s.locked {
+				s.mu.Unlock()
+				continue
+			}
+			distance := randomDistance()
+			if s.speedLimit > 0 && distance*3.6 > s.speedLimit {
+				distance = s.speedLimit / 3.6
+			}
+			s.Info.Location = geo.Move(s.Info.Location, distance, rand.Float64()*360)
+			if rand.Intn(10) == 0 && s.battery > 0 {
+				s.battery--
+			}
+			now := time.Now().UTC()
 			// redirect the update report to the log
-			logger.Infof("%+v", LocationUpdate{ScooterID: s.Info.ID, Coordinates: s.Info.Coordination, Time: time.Now()})
+			logger.Infof("%+v", LocationUpdate{ScooterID: s.Info.ID, Location: s.Info.Location, Time: now})
 
-			// Persist the scooter coordination in the database
-			if err := db.UpdateScooterCoordinates(ctx, s.Info.ID, s.Info.Coordination); err != nil {
-				logger.Errorf("couldn't persist the scooter %s updates: %s", s.Info.ID, err)
-			}
+			s.pending = append(s.pending, models.TelemetrySample{Time: now, Location: s.Info.Location,
+				Speed: distance * 3.6, Battery: s.battery})
+			s.report()
 			s.mu.Unlock()
 		}
 	}
 }
 
-// randomDistance returns random distance
-func randomDistance() int64 {
+// RunCommands polls and runs the operators' commands every second until ctx is done, the scooter takes
+// them for its whole life whether it's in a trip or parked
+func (s *Scooter) RunCommands(ctx context.Context) {
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.handleCommands()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// handleCommands runs the operators' commands, each one is acknowledged once done. An acknowledgment lost
+// on the way gets the command delivered again, running it twice does no harm.
+func (s *Scooter) handleCommands() {
+	commands, err := s.client.PollCommands(s.Info.ID)
+	if err != nil {
+		logger.Errorf("couldn't poll the scooter %s commands: %s", s.Info.ID, err)
+		return
+	}
+	for _, c := range commands {
+		ack := models.CommandAck{State: models.CommandAcked}
+		switch c.Kind {
+		case models.CommandLock:
+			s.locked = true
+		case models.CommandUnlock:
+			s.locked = false
+		case models.CommandBeep:
+			logger.Infof("the scooter %s beeps", s.Info.ID)
+		case models.CommandSpeedLimit:
+			s.speedLimit = c.SpeedLimit
+		case models.CommandReboot:
+			logger.Infof("the scooter %s reboots", s.Info.ID)
+		default:
+			ack = models.CommandAck{State: models.CommandFailed, Reason: "unsupported command"}
+		}
+		if _, err := s.client.AckCommand(s.Info.ID, c.ID, ack); err != nil {
+			logger.Errorf("couldn't acknowledge the scooter %s command %s: %s", s.Info.ID, c.ID, err)
+		}
+	}
+}
+
+// report sends the pending samples, they're kept for the next report unless the service refused them
+func (s *Scooter) report() {
+	if len(s.pending) == 0 {
+		return
+	}
+	if _, err := s.client.ReportTelemetry(s.Info.ID, s.pending); err != nil && !errors.Is(err, models.ErrInvalidRequest) {
+		logger.Errorf("couldn't report the scooter %s telemetry: %s", s.Info.ID, err)
+		if len(s.pending) > maxPendingSamples {
+			s.pending = s.pending[len(s.pending)-maxPendingSamples:]
+		}
+		return
+	} else if err != nil {
+		logger.Errorf("the scooter %s telemetry was refused: %s", s.Info.ID, err)
+	}
+	s.pending = s.pending[:0]
+}
+
+// randomDistance returns random distance in meters
+func randomDistance() float64 {
 	max := 20 // max scooter speed
 	min := 7  // min scooter speed
 
 	// choose random speed in-between
-	return int64(rand.Intn(max-min+1) + min)
+	return float64(rand.Intn(max-min+1) + min)
 }
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net"
+	"scootin/models"
+	"scootin/telemetry"
+	"sync"
+)
+
+// TelemetrySender sends the scooters' samples to the service's UDP telemetry as signed frames,
+// the fleets too large for ReportTelemetry use it.
+type TelemetrySender struct {
+	conn net.Conn
+	key  []byte
+	mu   sync.Mutex
+	seq  map[string]uint32 // the last sequence number sent by each scooter
+}
+
+// DialTelemetry returns a sender of the frames signed with the fleet's key to the service's UDP telemetry address
+func DialTelemetry(addr string, key []byte) (*TelemetrySender, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &TelemetrySender{conn: conn, key: key, seq: make(map[string]uint32)}, nil
+}
+
+// Send sends the scooter's sample in a frame. Nothing is acknowledged, a lost frame is superseded by the next ones.
+func (s *TelemetrySender) Send(scooterID string, sample models.TelemetrySample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	frame, err := telemetry.Encode(telemetry.Frame{ScooterID: scooterID, Seq: s.seq[scooterID] + 1, Sample: sample}, s.key)
+	if err != nil {
+		return err
+	}
+	if _, err = s.conn.Write(frame); err != nil {
+		return err
+	}
+	s.seq[scooterID]++
+	return nil
+}
+
+// Close closes the sender's connection
+func (s *TelemetrySender) Close() error {
+	return s.conn.Close()
+}
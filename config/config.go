@@ -1,6 +1,8 @@
 package config
 
 import (
+	"time"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
@@ -19,3 +21,186 @@ func IniatilizePostgreConfig() (*PostgreConfig, error) {
 	}
 	return &p, nil
 }
+
+// StorageConfig selects the repository implementation, either "postgres" or "memory".
+type StorageConfig struct {
+	Backend string `envconfig:"STORAGE_BACKEND" default:"postgres"`
+}
+
+func IniatilizeStorageConfig() (*StorageConfig, error) {
+	var s StorageConfig
+	if err := envconfig.Process("", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LocationHistoryConfig controls how long the scooters' location history is kept.
+type LocationHistoryConfig struct {
+	Retention           time.Duration `envconfig:"LOCATION_HISTORY_RETENTION" default:"720h"`
+	MaintenanceInterval time.Duration `envconfig:"LOCATION_HISTORY_MAINTENANCE_INTERVAL" default:"1h"`
+}
+
+func IniatilizeLocationHistoryConfig() (*LocationHistoryConfig, error) {
+	var l LocationHistoryConfig
+	if err := envconfig.Process("", &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// ServiceConfig has the REST-API settings.
+type ServiceConfig struct {
+	AdminToken     string `envconfig:"ADMIN_TOKEN"`     // the operators' bearer token, the admin endpoints are closed without it
+	TelemetryToken string `envconfig:"TELEMETRY_TOKEN"` // the scooters' bearer token, the telemetry is refused without it
+}
+
+func IniatilizeServiceConfig() (*ServiceConfig, error) {
+	var s ServiceConfig
+	if err := envconfig.Process("", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ReservationConfig controls how long a reserved scooter is held and how often the elapsed holds are expired.
+type ReservationConfig struct {
+	HoldMinutes   int           `envconfig:"RESERVATION_HOLD_MINUTES" default:"10"`
+	SweepInterval time.Duration `envconfig:"RESERVATION_SWEEP_INTERVAL" default:"30s"`
+}
+
+func IniatilizeReservationConfig() (*ReservationConfig, error) {
+	var r ReservationConfig
+	if err := envconfig.Process("", &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// BookingConfig sets how far in meters a rider may be from the scooter they book, 0 turns the check off
+type BookingConfig struct {
+	MaxDistance float64 `envconfig:"BOOKING_MAX_DISTANCE" default:"100"`
+}
+
+func IniatilizeBookingConfig() (*BookingConfig, error) {
+	var b BookingConfig
+	if err := envconfig.Process("", &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// PaymentConfig sets up the payments, the local fake provider is only used when its mode is set, or with the memory
+// backend where it approves, and it's told how to answer by its mode: "approve", "decline" or "timeout". Without a
+// provider the bookings and payments are refused. A hold of HoldAmount minor units is placed before every trip, and
+// the holds interrupted for longer than RecoverAfter are finished every RecoveryInterval.
+type PaymentConfig struct {
+	FakeMode         string        `envconfig:"PAYMENT_FAKE_MODE"`
+	Timeout          time.Duration `envconfig:"PAYMENT_TIMEOUT" default:"10s"`
+	MerchantCode     string        `envconfig:"PAYMENT_MERCHANT_CODE" default:"SCOOTIN"`
+	HoldAmount       int64         `envconfig:"PAYMENT_HOLD_AMOUNT" default:"1000"`
+	HoldCurrency     string        `envconfig:"PAYMENT_HOLD_CURRENCY" default:"EUR"`
+	RecoveryInterval time.Duration `envconfig:"PAYMENT_RECOVERY_INTERVAL" default:"1m"`
+	RecoverAfter     time.Duration `envconfig:"PAYMENT_RECOVER_AFTER" default:"1m"`
+}
+
+func IniatilizePaymentConfig() (*PaymentConfig, error) {
+	var p PaymentConfig
+	if err := envconfig.Process("", &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// WalletConfig sets up the riders' wallets, a user whose balance in any currency is below MinBalance minor units
+// can't start a trip.
+type WalletConfig struct {
+	MinBalance int64 `envconfig:"WALLET_MIN_BALANCE" default:"0"`
+}
+
+func IniatilizeWalletConfig() (*WalletConfig, error) {
+	var w WalletConfig
+	if err := envconfig.Process("", &w); err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// PassConfig sets up the ride passes, the auto-renewed passes are renewed every RenewalInterval
+type PassConfig struct {
+	RenewalInterval time.Duration `envconfig:"PASS_RENEWAL_INTERVAL" default:"1m"`
+}
+
+func IniatilizePassConfig() (*PassConfig, error) {
+	var p PassConfig
+	if err := envconfig.Process("", &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SurgeConfig sets up the surge pricing: a zone whose active reservations to available scooters ratio is above
+// Threshold has its prices multiplied by 1 + Sensitivity for every unit of ratio above it, up to MaxMultiplier.
+// Windows are the time-of-day rates in the TimeZone, like "07:00-10:00=1.25,23:00-05:00=0.8".
+type SurgeConfig struct {
+	Threshold     float64 `envconfig:"SURGE_THRESHOLD" default:"1"`
+	Sensitivity   float64 `envconfig:"SURGE_SENSITIVITY" default:"0.5"`
+	MaxMultiplier float64 `envconfig:"SURGE_MAX_MULTIPLIER" default:"2"`
+	Windows       string  `envconfig:"SURGE_WINDOWS" default:""`
+	TimeZone      string  `envconfig:"SURGE_TIMEZONE" default:"UTC"`
+}
+
+func IniatilizeSurgeConfig() (*SurgeConfig, error) {
+	var s SurgeConfig
+	if err := envconfig.Process("", &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// InvoiceConfig sets up the invoicing, the previous month's invoices are issued every Interval
+type InvoiceConfig struct {
+	Interval time.Duration `envconfig:"INVOICE_INTERVAL" default:"1h"`
+}
+
+func IniatilizeInvoiceConfig() (*InvoiceConfig, error) {
+	var i InvoiceConfig
+	if err := envconfig.Process("", &i); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// TelemetryConfig sets up the UDP telemetry, it's off while UDPAddr or UDPKey aren't set. Its samples are written
+// behind by batches when BufferCapacity is set, the last ones are flushed within ShutdownTimeout on shutdown.
+type TelemetryConfig struct {
+	UDPAddr         string        `envconfig:"TELEMETRY_UDP_ADDR"` // e.g. ":9090"
+	UDPKey          string        `envconfig:"TELEMETRY_UDP_KEY"`  // the fleet's key signing the frames
+	BufferCapacity  int           `envconfig:"TELEMETRY_BUFFER_CAPACITY" default:"10000"`
+	BatchSize       int           `envconfig:"TELEMETRY_BATCH_SIZE" default:"500"`
+	FlushInterval   time.Duration `envconfig:"TELEMETRY_FLUSH_INTERVAL" default:"1s"`
+	ShutdownTimeout time.Duration `envconfig:"TELEMETRY_SHUTDOWN_TIMEOUT" default:"10s"`
+}
+
+func IniatilizeTelemetryConfig() (*TelemetryConfig, error) {
+	var t TelemetryConfig
+	if err := envconfig.Process("", &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CommandConfig sets how long the scooters' remote commands wait for them by default and how often the
+// expired ones are failed
+type CommandConfig struct {
+	TTL           time.Duration `envconfig:"COMMAND_TTL" default:"5m"`
+	SweepInterval time.Duration `envconfig:"COMMAND_SWEEP_INTERVAL" default:"30s"`
+}
+
+func IniatilizeCommandConfig() (*CommandConfig, error) {
+	var c CommandConfig
+	if err := envconfig.Process("", &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
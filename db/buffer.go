@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"scootin/models"
+	"sync"
+	"time"
+)
+
+// closedFlushRetry is how often Close tries the final flush again while the repository fails
+const closedFlushRetry = 100 * time.Millisecond
+
+// ErrBufferClosed is returned for the samples added once the buffer is closed
+var ErrBufferClosed = errors.New("the position buffer is closed")
+
+// PositionBuffer writes the scooters' positions behind: the samples are recorded together by Flush, due once
+// a batch of scooters is pending, every one joins the history and each scooter is moved once to its latest.
+// Past its capacity the samples wait for a flush, so the senders are slowed down to the repository's pace.
+type PositionBuffer struct {
+	mu       sync.Mutex
+	pending  map[string][]models.LocationSample // by scooter, in their adding order
+	capacity int
+	batch    int
+	closed   bool
+	// drained is closed and replaced by every flush, the stalled samples wait on it
+	drained  chan struct{}
+	flushNow chan struct{}
+	flushMu  sync.Mutex // one flush at a time
+	stats    models.PositionBufferStats
+}
+
+// NewPositionBuffer returns a buffer of up to capacity scooters, flushed by batches of the size
+func NewPositionBuffer(capacity, batch int) *PositionBuffer {
+	if batch > capacity {
+		batch = capacity
+	}
+	return &PositionBuffer{
+		pending:  make(map[string][]models.LocationSample),
+		capacity: capacity,
+		batch:    batch,
+		drained:  make(chan struct{}),
+		flushNow: make(chan struct{}, 1),
+		stats:    models.PositionBufferStats{Capacity: capacity},
+	}
+}
+
+// Add keeps the sample until the next flush, it waits for one while the buffer is full
+func (b *PositionBuffer) Add(ctx context.Context, sample models.LocationSample) error {
+	b.mu.Lock()
+	var stalledAt time.Time
+	for !b.closed && len(b.pending) >= b.capacity {
+		if _, ok := b.pending[sample.ScooterID]; ok {
+			break
+		}
+		if stalledAt.IsZero() {
+			stalledAt = time.Now()
+			b.stats.Stalls++
+		}
+		drained := b.drained
+		b.mu.Unlock()
+		b.trigger()
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.stats.StalledFor += time.Since(stalledAt).Seconds()
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+		b.mu.Lock()
+	}
+	defer b.mu.Unlock()
+	if !stalledAt.IsZero() {
+		b.stats.StalledFor += time.Since(stalledAt).Seconds()
+	}
+	if b.closed {
+		return ErrBufferClosed
+	}
+
+	b.stats.Received++
+	if _, ok := b.pending[sample.ScooterID]; ok {
+		b.stats.Coalesced++
+	}
+	b.pending[sample.ScooterID] = append(b.pending[sample.ScooterID], sample)
+	if len(b.pending) >= b.batch {
+		b.trigger()
+	}
+	return nil
+}
+
+// trigger signals a flush is due
+func (b *PositionBuffer) trigger() {
+	select {
+	case b.flushNow <- struct{}{}:
+	default:
+	}
+}
+
+// Due signals a flush is due, a batch being pending or the buffer full
+func (b *PositionBuffer) Due() <-chan struct{} {
+	return b.flushNow
+}
+
+// Flush records the pending samples by batches of scooters, the ones of a failed batch stay pending
+func (b *PositionBuffer) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	scooters := make([][]models.LocationSample, 0, len(b.pending))
+	for _, samples := range b.pending {
+		scooters = append(scooters, samples)
+	}
+	b.pending = make(map[string][]models.LocationSample)
+	close(b.drained)
+	b.drained = make(chan struct{})
+	b.mu.Unlock()
+
+	var err error
+	for start := 0; start < len(scooters); start += b.batch {
+		end := start + b.batch
+		if end > len(scooters) {
+			end = len(scooters)
+		}
+		samples := make([]models.LocationSample, 0, end-start)
+		for _, s := range scooters[start:end] {
+			samples = append(samples, s...)
+		}
+		var written int
+		if written, err = RecordPositions(ctx, samples); err != nil {
+			b.restore(scooters[start:])
+			break
+		}
+		b.mu.Lock()
+		b.stats.Written += int64(written)
+		b.stats.Batches++
+		b.mu.Unlock()
+	}
+
+	b.mu.Lock()
+	now := time.Now().UTC()
+	b.stats.LastFlushAt = &now
+	b.mu.Unlock()
+	return err
+}
+
+// restore puts back the samples of a failed flush before the ones added meanwhile
+func (b *PositionBuffer) restore(scooters [][]models.LocationSample) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stats.FailedBatches++
+	for _, samples := range scooters {
+		id := samples[0].ScooterID
+		b.pending[id] = append(samples, b.pending[id]...)
+	}
+}
+
+// Close refuses the new samples and flushes the pending ones, trying again until they're all recorded
+// or the context is done
+func (b *PositionBuffer) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	close(b.drained) // the stalled samples are refused
+	b.drained = make(chan struct{})
+	b.mu.Unlock()
+
+	for {
+		err := b.Flush(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(closedFlushRetry):
+		}
+	}
+}
+
+// Stats returns the buffer's counters
+func (b *PositionBuffer) Stats() models.PositionBufferStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := b.stats
+	stats.Pending = len(b.pending)
+	return stats
+}
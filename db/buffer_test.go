@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyRepository fails the positions' writes while it's down
+type flakyRepository struct {
+	*InMemoryRepository
+	down bool
+}
+
+func (f *flakyRepository) RecordPositions(ctx context.Context, samples []models.LocationSample) (int, error) {
+	if f.down {
+		f.down = false
+		return 0, errors.New("connection refused")
+	}
+	return f.InMemoryRepository.RecordPositions(ctx, samples)
+}
+
+func TestPositionBuffer(t *testing.T) {
+	ctx := context.Background()
+	repo := &flakyRepository{InMemoryRepository: NewInMemory()}
+	SetRepository(repo)
+	defer SetRepository(nil)
+	for _, id := range []string{"sc1", "sc2", "sc3"} {
+		assert.NoError(t, repo.CreateScooter(ctx, &models.ScooterInfo{ID: id}))
+	}
+	at := func(id string, lat float64, t time.Time) models.LocationSample {
+		return models.LocationSample{ScooterID: id, Time: t, Location: models.Location{Latitude: lat, Longitude: 13.4}}
+	}
+	now := time.Now().UTC()
+
+	// every sample joins the history, the scooter is moved to its latest one and a late one doesn't replace it
+	b := NewPositionBuffer(2, 2)
+	assert.NoError(t, b.Add(ctx, at("sc1", 52.1, now)))
+	assert.NoError(t, b.Add(ctx, at("sc1", 52.3, now.Add(2*time.Second))))
+	assert.NoError(t, b.Add(ctx, at("sc1", 52.2, now.Add(time.Second))))
+	assert.NoError(t, b.Add(ctx, at("sc2", 52.4, now)))
+	assert.NoError(t, b.Flush(ctx))
+	sc, err := repo.GetScooter(ctx, "sc1")
+	assert.NoError(t, err)
+	assert.Equal(t, 52.3, sc.Location.Latitude)
+	track, err := repo.ScooterTrack(ctx, "sc1", now, now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, track, 3) {
+		assert.Equal(t, []float64{52.1, 52.2, 52.3}, []float64{track[0].Location.Latitude, track[1].Location.Latitude,
+			track[2].Location.Latitude})
+	}
+	stats := b.Stats()
+	assert.Equal(t, int64(4), stats.Received)
+	assert.Equal(t, int64(2), stats.Coalesced)
+	assert.Equal(t, int64(4), stats.Written)
+	assert.Equal(t, int64(1), stats.Batches)
+
+	// the full buffer holds the next scooter's sample back until it's flushed
+	later := now.Add(3 * time.Second)
+	assert.NoError(t, b.Add(ctx, at("sc1", 52.5, later)))
+	assert.NoError(t, b.Add(ctx, at("sc2", 52.5, later)))
+	<-b.Due()
+	added := make(chan error)
+	go func() {
+		added <- b.Add(ctx, at("sc3", 52.5, later))
+	}()
+	assert.Eventually(t, func() bool { return b.Stats().Stalls == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, b.Flush(ctx))
+	assert.NoError(t, <-added)
+	assert.Equal(t, 1, b.Stats().Pending)
+
+	// the last positions are written on close despite a failed flush, the later samples are refused
+	repo.down = true
+	assert.NoError(t, b.Close(ctx))
+	for _, id := range []string{"sc1", "sc2", "sc3"} {
+		sc, err := repo.GetScooter(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, 52.5, sc.Location.Latitude)
+	}
+	assert.Zero(t, b.Stats().Pending)
+	assert.GreaterOrEqual(t, b.Stats().FailedBatches, int64(1))
+	assert.ErrorIs(t, b.Add(ctx, at("sc1", 52.6, now.Add(4*time.Second))), ErrBufferClosed)
+}
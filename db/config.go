@@ -1,10 +1,35 @@
 package db
 
 import (
+	"database/sql"
 	"fmt"
 	"scootin/config"
 )
 
+const (
+	// PostgresBackend stores the data in postgres
+	PostgresBackend = "postgres"
+	// MemoryBackend keeps the data in the process memory
+	MemoryBackend = "memory"
+)
+
+// InitiateRepository sets up the repository chosen by the storage configuration
+func InitiateRepository() error {
+	sc, err := config.IniatilizeStorageConfig()
+	if err != nil {
+		return err
+	}
+	switch sc.Backend {
+	case PostgresBackend:
+		return InitiatePostgre()
+	case MemoryBackend:
+		SetRepository(NewInMemory())
+		return nil
+	default:
+		return fmt.Errorf("unknown storage backend %q", sc.Backend)
+	}
+}
+
 func InitiatePostgre() error {
 	pc, err := config.IniatilizePostgreConfig()
 	if err != nil {
@@ -13,9 +38,21 @@ func InitiatePostgre() error {
 	return setUpPostgre(pc)
 }
 
+// OpenPostgre opens the configured postgres database without migrating it
+func OpenPostgre() (*sql.DB, error) {
+	pc, err := config.IniatilizePostgreConfig()
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open("postgres", postgresAddress(pc))
+}
+
+func postgresAddress(pc *config.PostgreConfig) string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", pc.PostgresHost, pc.PostgresPort, pc.PostgresUser, pc.PostgresPassword, pc.PostgresDataBase)
+}
+
 func setUpPostgre(pc *config.PostgreConfig) error {
-	postgersAddress := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable", pc.PostgresHost, pc.PostgresPort, pc.PostgresUser, pc.PostgresPassword, pc.PostgresDataBase)
-	repository, err := NewPostgre(postgersAddress)
+	repository, err := NewPostgre(postgresAddress(pc))
 	if err != nil {
 		return err
 	}
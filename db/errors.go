@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"scootin/models"
+
+	"github.com/lib/pq"
+)
+
+// the repositories' domain errors, they're shared with the client through the models package
+var (
+	ErrScooterOccupied         = models.ErrScooterOccupied
+	ErrScooterNotFound         = models.ErrScooterNotFound
+	ErrUserNotFound            = models.ErrUserNotFound
+	ErrTripNotFound            = models.ErrTripNotFound
+	ErrNoActiveTrip            = models.ErrNoActiveTrip
+	ErrActiveTripExists        = models.ErrActiveTripExists
+	ErrScooterUnavailable      = models.ErrScooterUnavailable
+	ErrInvalidTransition       = models.ErrInvalidTransition
+	ErrReservationNotFound     = models.ErrReservationNotFound
+	ErrReservationNotActive    = models.ErrReservationNotActive
+	ErrActiveReservationExists = models.ErrActiveReservationExists
+	ErrPricePlanNotFound       = models.ErrPricePlanNotFound
+	ErrCheckoutNotFound        = models.ErrCheckoutNotFound
+	ErrTripAlreadyPaid         = models.ErrTripAlreadyPaid
+	ErrPaymentSagaNotFound     = models.ErrPaymentSagaNotFound
+	ErrDisputeNotFound         = models.ErrDisputeNotFound
+	ErrDisputeExists           = models.ErrDisputeExists
+	ErrInvalidDisputeStatus    = models.ErrInvalidDisputeStatus
+	ErrPromoNotFound           = models.ErrPromoNotFound
+	ErrPromoExists             = models.ErrPromoExists
+	ErrPromoUnavailable        = models.ErrPromoUnavailable
+	ErrPromoAlreadyRedeemed    = models.ErrPromoAlreadyRedeemed
+	ErrPassPlanNotFound        = models.ErrPassPlanNotFound
+	ErrPassNotFound            = models.ErrPassNotFound
+	ErrTaxRuleNotFound         = models.ErrTaxRuleNotFound
+	ErrInvoiceNotFound         = models.ErrInvoiceNotFound
+	ErrInvoiceExists           = models.ErrInvoiceExists
+	ErrZoneNotFound            = models.ErrZoneNotFound
+	ErrOutsideOperatingArea    = models.ErrOutsideOperatingArea
+	ErrNoParkingZone           = models.ErrNoParkingZone
+	ErrScooterTooFar           = models.ErrScooterTooFar
+	ErrCommandNotFound         = models.ErrCommandNotFound
+	ErrCommandNotDelivered     = models.ErrCommandNotDelivered
+	ErrForbidden               = models.ErrForbidden
+)
+
+// IsUnavailable reports whether the error is caused by the database being unreachable or overloaded
+// rather than by the request itself.
+func IsUnavailable(err error) bool {
+	var (
+		netErr net.Error
+		pqErr  *pq.Error
+	)
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.As(err, &netErr):
+		return true
+	case errors.As(err, &pqErr):
+		// connection exception, insufficient resources and operator intervention classes
+		class := string(pqErr.Code.Class())
+		return class == "08" || class == "53" || class == "57"
+	}
+	return false
+}
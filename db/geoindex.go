@@ -0,0 +1,55 @@
+package db
+
+import (
+	"sort"
+	"strings"
+)
+
+// geoIndex is a geohash ordered index of the scooters' locations for the in-memory repository,
+// the scooters within a geohash cell are a contiguous range of it.
+type geoIndex struct {
+	entries []geoEntry // sorted by hash then id
+	hashes  map[string]string
+}
+
+type geoEntry struct {
+	hash string
+	id   string
+}
+
+func newGeoIndex() *geoIndex {
+	return &geoIndex{hashes: make(map[string]string)}
+}
+
+func (g *geoIndex) search(e geoEntry) int {
+	return sort.Search(len(g.entries), func(i int) bool {
+		x := g.entries[i]
+		return x.hash > e.hash || (x.hash == e.hash && x.id >= e.id)
+	})
+}
+
+// set indexes the scooter under its new geohash
+func (g *geoIndex) set(id, hash string) {
+	if old, ok := g.hashes[id]; ok {
+		if old == hash {
+			return
+		}
+		i := g.search(geoEntry{old, id})
+		g.entries = append(g.entries[:i], g.entries[i+1:]...)
+	}
+	e := geoEntry{hash, id}
+	i := g.search(e)
+	g.entries = append(g.entries, geoEntry{})
+	copy(g.entries[i+1:], g.entries[i:])
+	g.entries[i] = e
+	g.hashes[id] = hash
+}
+
+// within returns the ids of the scooters inside the geohash cell
+func (g *geoIndex) within(cell string) []string {
+	ids := make([]string, 0)
+	for i := g.search(geoEntry{hash: cell}); i < len(g.entries) && strings.HasPrefix(g.entries[i].hash, cell); i++ {
+		ids = append(ids, g.entries[i].id)
+	}
+	return ids
+}
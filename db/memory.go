@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"scootin/geo"
+	"scootin/ledger"
+	"scootin/models"
+	"scootin/pricing"
+	"scootin/zone"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InMemoryRepository keeps the scooters and users in the process memory,
+// it mirrors the PostgreRepository behavior and is meant for development and tests.
+type InMemoryRepository struct {
+	mu       sync.RWMutex
+	scooters map[string]*models.ScooterInfo
+	order    []string // scooters' ids in their creation order
+	geo      *geoIndex
+	users    map[string]models.User
+	trips    map[string]*models.Trip
+	userTrip map[string][]string // users' trips ids in their starting order
+	// reservations by their id
+	reservations map[string]*models.Reservation
+	pricePlans   []models.PricePlan // in their creation order
+	checkouts    map[string]*models.Checkout
+	sagas        map[string]*models.PaymentSaga
+	ledger       []models.LedgerTransaction // in their posting order
+	disputes     map[string]*models.Dispute
+	promoCodes   map[string]*models.PromoCode
+	redemptions  map[string][]*models.PromoRedemption // users' redeemed promo codes in their redemption order
+	passPlans    []models.PassPlan                    // in their creation order
+	passes       []*models.Pass                       // in their creation order
+	passUsage    map[passDay]models.PassUsage
+	taxRules     map[string]models.TaxRule // by city
+	invoices     []*models.Invoice         // in their issuing order
+	sequences    map[string]int64          // the legal entities' last invoice sequence
+	zones        []*models.Zone            // in their creation order
+	zoneIndex    *zone.Index
+	zoneAlerts   []models.ZoneAlert // in their raising order
+	stations     []models.Station   // in their creation order
+	commands     []*models.Command  // in their creation order
+	// scooters' location history in their recording order
+	locations map[string][]models.LocationSample
+	reported  map[string]time.Time // scooters' last reported time, it outlives the history's retention
+}
+
+// NewInMemory returns an empty in-memory repository
+func NewInMemory() *InMemoryRepository {
+	return &InMemoryRepository{
+		scooters:     make(map[string]*models.ScooterInfo),
+		order:        make([]string, 0),
+		geo:          newGeoIndex(),
+		users:        make(map[string]models.User),
+		trips:        make(map[string]*models.Trip),
+		userTrip:     make(map[string][]string),
+		reservations: make(map[string]*models.Reservation),
+		pricePlans:   []models.PricePlan{defaultPricePlan},
+		checkouts:    make(map[string]*models.Checkout),
+		sagas:        make(map[string]*models.PaymentSaga),
+		disputes:     make(map[string]*models.Dispute),
+		promoCodes:   make(map[string]*models.PromoCode),
+		redemptions:  make(map[string][]*models.PromoRedemption),
+		passUsage:    make(map[passDay]models.PassUsage),
+		taxRules:     make(map[string]models.TaxRule),
+		sequences:    make(map[string]int64),
+		zoneIndex:    zone.NewIndex(nil),
+		locations:    make(map[string][]models.LocationSample),
+		reported:     make(map[string]time.Time),
+	}
+}
+
+func (m *InMemoryRepository) Close() {}
+
+func (m *InMemoryRepository) CreateScooter(ctx context.Context, scooter *models.ScooterInfo) error {
+	if scooter == nil {
+		return errors.New("couldn't create an empty scooter")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.scooters[scooter.ID]; ok {
+		return fmt.Errorf("scooter %s already exists", scooter.ID)
+	}
+	sc := &models.ScooterInfo{ID: scooter.ID, Location: scooter.Location, Status: models.StatusAvailable,
+		City: scooter.City, VehicleType: vehicleType(scooter.VehicleType)}
+	m.scooters[sc.ID] = sc
+	m.order = append(m.order, sc.ID)
+	m.geo.set(sc.ID, geo.Encode(sc.Location, geo.HashPrecision))
+	return nil
+}
+
+func (m *InMemoryRepository) CreateUser(ctx context.Context, user *models.User) error {
+	if user == nil {
+		return errors.New("couldn't create an empty user")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[user.ID]; ok {
+		return fmt.Errorf("user %s already exists", user.ID)
+	}
+	m.users[user.ID] = *user
+	return nil
+}
+
+// BookScooter ...
+func (m *InMemoryRepository) BookScooter(ctx context.Context, ScooterID, userID string, surge float64, near *models.Proximity) (*models.Trip, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	// do the booking only if the scooter is not booked by another user
+	sc, ok := m.scooters[ScooterID]
+	if !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", ScooterID)
+	} else if err := bookable(sc.Status, ScooterID, userID); err != nil {
+		return nil, err
+	} else if err := nearby(near, ScooterID, sc.Location); err != nil {
+		return nil, err
+	}
+	if err := m.userHolds(userID); err != nil {
+		return nil, err
+	}
+	t := *m.startTrip(sc, userID, surge)
+	return &t, nil
+}
+
+// userHolds checks the user holds no scooter yet, a user rides or reserves one scooter at a time
+func (m *InMemoryRepository) userHolds(userID string) error {
+	for _, id := range m.userTrip[userID] {
+		if m.trips[id].EndedAt == nil {
+			return models.Errorf(ErrActiveTripExists, "user %s", userID)
+		}
+	}
+	for _, r := range m.reservations {
+		if r.UserID == userID && r.Status == models.ReservationActive {
+			return models.Errorf(ErrActiveReservationExists, "user %s", userID)
+		}
+	}
+	return nil
+}
+
+// startTrip puts the scooter in a trip of the user with the surge locked in, the trip starts from the scooter's current location
+func (m *InMemoryRepository) startTrip(sc *models.ScooterInfo, userID string, surge float64) *models.Trip {
+	sc.Status, sc.UserID = models.StatusInTrip, userID
+
+	trip := &models.Trip{ID: uuid.New().String(), ScooterID: sc.ID, UserID: userID, StartedAt: time.Now().UTC(), StartLocation: sc.Location,
+		Surge: surge}
+	m.trips[trip.ID] = trip
+	m.userTrip[userID] = append(m.userTrip[userID], trip.ID)
+	return trip
+}
+
+// ReleaseScooter ...
+func (m *InMemoryRepository) ReleaseScooter(ctx context.Context, userID string) (*models.Trip, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var trip *models.Trip
+	for _, id := range m.userTrip[userID] {
+		if m.trips[id].EndedAt == nil {
+			trip = m.trips[id]
+		}
+	}
+	if trip == nil {
+		return nil, models.Errorf(ErrNoActiveTrip, "user %s has no booked scooter to release", userID)
+	}
+	sc := m.scooters[trip.ScooterID]
+	if err := m.zoneIndex.CanPark(sc.Location); err != nil {
+		return nil, err
+	}
+
+	// the trip is priced by the plan in effect when it started, along the path the scooter reported
+	plan, err := m.pricePlanAt(sc.City, sc.VehicleType, trip.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	path := []models.Location{trip.StartLocation}
+	for _, sample := range m.locations[sc.ID] {
+		if !sample.Time.Before(trip.StartedAt) && sample.Time.Before(now) {
+			path = append(path, sample.Location)
+		}
+	}
+	path = append(path, sc.Location)
+	fare := pricing.Calculate(plan, now.Sub(trip.StartedAt), geo.PathLength(path))
+	fare.City = sc.City
+	pricing.ApplySurge(fare, trip.Surge)
+	usage, covered := m.applyPass(userID, fare, now)
+	promo := m.bestPromo(userID, sc.City, fare, now)
+	if station := pricing.NearestStation(m.stations, sc.Location); station != nil {
+		pricing.ApplyStation(fare, station, m.parkedNear(station.Location, station.Radius))
+	}
+
+	// the fare is charged to the rider's wallet, a free trip posts nothing
+	if fare.Total > 0 {
+		if err := m.post(ledger.Fare(userID, trip.ID, fare)); err != nil {
+			return nil, err
+		}
+	}
+	if covered {
+		m.passUsage[passDay{usage.PassID, usage.Day}] = usage
+	}
+	if promo != nil {
+		promo.Uses++
+	}
+
+	// end the trip where the scooter is now
+	endedAt, endLocation := now, sc.Location
+	trip.EndedAt, trip.EndLocation, trip.Fare = &endedAt, &endLocation, fare
+	sc.Status, sc.UserID = models.StatusAvailable, ""
+
+	t := copyTrip(trip)
+	return &t, nil
+}
+
+// GetTrip ...
+func (m *InMemoryRepository) GetTrip(ctx context.Context, tripID string) (*models.Trip, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	trip, ok := m.trips[tripID]
+	if !ok {
+		return nil, models.Errorf(ErrTripNotFound, "trip %s", tripID)
+	}
+	t := copyTrip(trip)
+	return &t, nil
+}
+
+// ListUserTrips ...
+func (m *InMemoryRepository) ListUserTrips(ctx context.Context, userID string) ([]models.Trip, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	trips := make([]models.Trip, 0, len(m.userTrip[userID]))
+	for _, id := range m.userTrip[userID] {
+		trips = append(trips, copyTrip(m.trips[id]))
+	}
+	return trips, nil
+}
+
+// ListAvailableScooter ...
+func (m *InMemoryRepository) ListAvailableScooter(ctx context.Context) ([]models.ScooterInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infx := make([]models.ScooterInfo, 0)
+	for _, id := range m.order {
+		if sc := m.scooters[id]; sc.Status == models.StatusAvailable {
+			infx = append(infx, *sc)
+		}
+	}
+	return infx, nil
+}
+
+// GetScooter ...
+func (m *InMemoryRepository) GetScooter(ctx context.Context, scooterID string) (*models.ScooterInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sc, ok := m.scooters[scooterID]
+	if !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	info := *sc
+	return &info, nil
+}
+
+// SetScooterStatus ...
+func (m *InMemoryRepository) SetScooterStatus(ctx context.Context, scooterID string, status models.ScooterStatus) (*models.ScooterInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sc, ok := m.scooters[scooterID]
+	if !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	if err := operationalTransition(sc.Status, status, scooterID); err != nil {
+		return nil, err
+	}
+	sc.Status = status
+	info := *sc
+	return &info, nil
+}
+
+// ListAvailableScooterInZone ...
+func (m *InMemoryRepository) ListAvailableScooterInZone(ctx context.Context, zone string) ([]models.ScooterInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infx := make([]models.ScooterInfo, 0)
+	for _, id := range m.geo.within(zone) {
+		if sc := m.scooters[id]; sc.Status == models.StatusAvailable {
+			infx = append(infx, *sc)
+		}
+	}
+	return infx, nil
+}
+
+// ListAvailableScooterNear ...
+func (m *InMemoryRepository) ListAvailableScooterNear(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyScooter, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// only the scooters within the geohash cells covering the radius are candidates
+	candidates := make([]models.ScooterInfo, 0)
+	for _, cell := range geo.CoveringHashes(center, radius) {
+		for _, id := range m.geo.within(cell) {
+			if sc := m.scooters[id]; sc.Status == models.StatusAvailable {
+				candidates = append(candidates, *sc)
+			}
+		}
+	}
+	return nearestScooters(center, radius, limit, candidates), nil
+}
+
+// copyTrip returns a trip copy which doesn't share the stored trip's end fields
+func copyTrip(trip *models.Trip) models.Trip {
+	t := *trip
+	if trip.EndedAt != nil {
+		endedAt := *trip.EndedAt
+		t.EndedAt = &endedAt
+	}
+	if trip.EndLocation != nil {
+		endLocation := *trip.EndLocation
+		t.EndLocation = &endLocation
+	}
+	if trip.Fare != nil {
+		fare := *trip.Fare
+		t.Fare = &fare
+	}
+	return t
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"scootin/ledger"
+	"scootin/models"
+)
+
+// CreateCheckout ...
+func (m *InMemoryRepository) CreateCheckout(ctx context.Context, checkout *models.Checkout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.checkouts {
+		if c.TripID == checkout.TripID && (c.Status == models.CheckoutPending || c.Status == models.CheckoutCaptured) {
+			return models.Errorf(ErrTripAlreadyPaid, "trip %s has the %s checkout %s", c.TripID, c.Status, c.ID)
+		}
+	}
+	c := copyCheckout(checkout)
+	m.checkouts[c.ID] = &c
+	return nil
+}
+
+// UpdateCheckout ...
+func (m *InMemoryRepository) UpdateCheckout(ctx context.Context, checkout *models.Checkout) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.checkouts[checkout.ID]; !ok {
+		return models.Errorf(ErrCheckoutNotFound, "checkout %s", checkout.ID)
+	}
+	// the captured payment is credited to the rider's wallet
+	if checkout.Status == models.CheckoutCaptured {
+		if err := m.post(ledger.Payment(checkout)); err != nil {
+			return err
+		}
+	}
+	c := copyCheckout(checkout)
+	m.checkouts[c.ID] = &c
+	return nil
+}
+
+// GetCheckout ...
+func (m *InMemoryRepository) GetCheckout(ctx context.Context, checkoutID string) (*models.Checkout, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	c, ok := m.checkouts[checkoutID]
+	if !ok {
+		return nil, models.Errorf(ErrCheckoutNotFound, "checkout %s", checkoutID)
+	}
+	checkout := copyCheckout(c)
+	return &checkout, nil
+}
+
+// CapturedCheckout ...
+func (m *InMemoryRepository) CapturedCheckout(ctx context.Context, tripID string) (*models.Checkout, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.checkouts {
+		if c.TripID == tripID && c.Status == models.CheckoutCaptured {
+			checkout := copyCheckout(c)
+			return &checkout, nil
+		}
+	}
+	return nil, models.Errorf(ErrCheckoutNotFound, "trip %s has no captured checkout", tripID)
+}
+
+// copyCheckout returns a checkout copy which doesn't share the completion time
+func copyCheckout(c *models.Checkout) models.Checkout {
+	checkout := *c
+	if c.CompletedAt != nil {
+		completedAt := *c.CompletedAt
+		checkout.CompletedAt = &completedAt
+	}
+	return checkout
+}
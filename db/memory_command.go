@@ -0,0 +1,114 @@
+package db
+
+import (
+	"context"
+	"scootin/models"
+	"time"
+)
+
+// commandExpiredReason is the reason of the commands failed by their expiry
+const commandExpiredReason = "expired"
+
+// CreateCommand ...
+func (m *InMemoryRepository) CreateCommand(ctx context.Context, command *models.Command) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.scooters[command.ScooterID]; !ok {
+		return models.Errorf(ErrScooterNotFound, "scooter %s", command.ScooterID)
+	}
+	c := *command
+	m.commands = append(m.commands, &c)
+	return nil
+}
+
+// GetCommand ...
+func (m *InMemoryRepository) GetCommand(ctx context.Context, commandID string) (*models.Command, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, c := range m.commands {
+		if c.ID == commandID {
+			command := *c
+			return &command, nil
+		}
+	}
+	return nil, models.Errorf(ErrCommandNotFound, "command %s", commandID)
+}
+
+// ListCommands ...
+func (m *InMemoryRepository) ListCommands(ctx context.Context, scooterID string) ([]models.Command, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.scooters[scooterID]; !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	commands := make([]models.Command, 0)
+	for _, c := range m.commands {
+		if c.ScooterID == scooterID {
+			commands = append(commands, *c)
+		}
+	}
+	return commands, nil
+}
+
+// PollCommands ...
+func (m *InMemoryRepository) PollCommands(ctx context.Context, scooterID string, now time.Time) ([]models.Command, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.scooters[scooterID]; !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	commands := make([]models.Command, 0)
+	for _, c := range m.commands {
+		if c.ScooterID != scooterID || !commandPending(c.State) || !now.Before(c.ExpiresAt) {
+			continue
+		}
+		delivered := now
+		c.State, c.DeliveredAt = models.CommandDelivered, &delivered
+		commands = append(commands, *c)
+	}
+	return commands, nil
+}
+
+// AckCommand ...
+func (m *InMemoryRepository) AckCommand(ctx context.Context, scooterID, commandID string, ack models.CommandAck, now time.Time) (*models.Command, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.commands {
+		if c.ID != commandID || c.ScooterID != scooterID {
+			continue
+		}
+		if c.State != models.CommandDelivered {
+			return nil, models.Errorf(ErrCommandNotDelivered, "command %s is %s", commandID, c.State)
+		}
+		c.State, c.Reason, c.EndedAt = ack.State, ack.Reason, &now
+		command := *c
+		return &command, nil
+	}
+	return nil, models.Errorf(ErrCommandNotFound, "command %s", commandID)
+}
+
+// ExpireCommands ...
+func (m *InMemoryRepository) ExpireCommands(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := 0
+	for _, c := range m.commands {
+		if commandPending(c.State) && !now.Before(c.ExpiresAt) {
+			ended := now
+			c.State, c.Reason, c.EndedAt = models.CommandFailed, commandExpiredReason, &ended
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// commandPending reports whether a command in the state waits for the scooter's acknowledgment
+func commandPending(state models.CommandState) bool {
+	return state == models.CommandQueued || state == models.CommandDelivered
+}
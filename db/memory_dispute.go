@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"scootin/models"
+	"sort"
+)
+
+// CreateDispute ...
+func (m *InMemoryRepository) CreateDispute(ctx context.Context, dispute *models.Dispute) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, d := range m.disputes {
+		if d.TripID == dispute.TripID {
+			return models.Errorf(ErrDisputeExists, "trip %s has the dispute %s", d.TripID, d.ID)
+		}
+	}
+	d := copyDispute(dispute)
+	m.disputes[d.ID] = &d
+	return nil
+}
+
+// GetDispute ...
+func (m *InMemoryRepository) GetDispute(ctx context.Context, disputeID string) (*models.Dispute, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	d, ok := m.disputes[disputeID]
+	if !ok {
+		return nil, models.Errorf(ErrDisputeNotFound, "dispute %s", disputeID)
+	}
+	dispute := copyDispute(d)
+	return &dispute, nil
+}
+
+// ListDisputes ...
+func (m *InMemoryRepository) ListDisputes(ctx context.Context, status models.DisputeStatus) ([]models.Dispute, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	disputes := make([]models.Dispute, 0)
+	for _, d := range m.disputes {
+		if len(status) == 0 || d.Status == status {
+			disputes = append(disputes, copyDispute(d))
+		}
+	}
+	sort.Slice(disputes, func(i, j int) bool { return disputes[i].CreatedAt.Before(disputes[j].CreatedAt) })
+	return disputes, nil
+}
+
+// UpdateDispute ...
+func (m *InMemoryRepository) UpdateDispute(ctx context.Context, dispute *models.Dispute, event models.DisputeEvent, refund *models.LedgerTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d, ok := m.disputes[dispute.ID]
+	if !ok {
+		return models.Errorf(ErrDisputeNotFound, "dispute %s", dispute.ID)
+	} else if !d.Status.CanTransitionTo(event.Status) {
+		return models.Errorf(ErrInvalidDisputeStatus, "dispute %s can't move from %s to %s", d.ID, d.Status, event.Status)
+	}
+	if refund != nil {
+		if err := m.post(refund); err != nil {
+			return err
+		}
+	}
+	d.Status, d.UpdatedAt = event.Status, event.At
+	d.RefundAmount, d.Currency, d.RefundReference = dispute.RefundAmount, dispute.Currency, dispute.RefundReference
+	d.Events = append(d.Events, event)
+	*dispute = copyDispute(d)
+	return nil
+}
+
+// copyDispute returns a dispute copy which doesn't share the audit trail
+func copyDispute(d *models.Dispute) models.Dispute {
+	dispute := *d
+	dispute.Events = append([]models.DisputeEvent(nil), d.Events...)
+	return dispute
+}
@@ -0,0 +1,130 @@
+package db
+
+import (
+	"context"
+	"scootin/invoice"
+	"scootin/models"
+	"sort"
+	"time"
+)
+
+// SetTaxRule ...
+func (m *InMemoryRepository) SetTaxRule(ctx context.Context, rule *models.TaxRule) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.taxRules[rule.City] = *rule
+	return nil
+}
+
+// ListTaxRules ...
+func (m *InMemoryRepository) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rules := make([]models.TaxRule, 0, len(m.taxRules))
+	for _, r := range m.taxRules {
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].City < rules[j].City })
+	return rules, nil
+}
+
+// ListCharges ...
+func (m *InMemoryRepository) ListCharges(ctx context.Context, from, to time.Time) ([]models.Charge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	within := func(at time.Time) bool { return !at.Before(from) && at.Before(to) }
+	charges := make([]models.Charge, 0)
+	for _, trip := range m.trips {
+		if trip.EndedAt != nil && trip.Fare != nil && trip.Fare.Total > 0 && within(*trip.EndedAt) {
+			charges = append(charges, models.Charge{UserID: trip.UserID, Kind: models.ChargeTrip, Reference: trip.ID,
+				City: trip.Fare.City, Amount: trip.Fare.Total, Currency: trip.Fare.Currency, At: *trip.EndedAt})
+		}
+	}
+	// the passes' charges are their payments earned in the ledger, the disputes' refunds are taken off the revenue
+	// in their trip's city
+	for _, tx := range m.ledger {
+		if (tx.Kind != models.LedgerPass && tx.Kind != models.LedgerRefund) || !within(tx.CreatedAt) {
+			continue
+		}
+		charge := models.Charge{UserID: tx.UserID, Kind: models.ChargePass, Reference: tx.Reference, Currency: tx.Currency, At: tx.CreatedAt}
+		if tx.Kind == models.LedgerRefund {
+			charge.Kind = models.ChargeRefund
+			if d, ok := m.disputes[tx.Reference]; ok {
+				if trip := m.trips[d.TripID]; trip != nil && trip.Fare != nil {
+					charge.City = trip.Fare.City
+				}
+			}
+		}
+		for _, e := range tx.Entries {
+			if e.Account == models.AccountRevenue {
+				charge.Amount = e.Amount
+				charges = append(charges, charge)
+			}
+		}
+	}
+	sort.SliceStable(charges, func(i, j int) bool { return charges[i].At.Before(charges[j].At) })
+	return charges, nil
+}
+
+// CreateInvoice ...
+func (m *InMemoryRepository) CreateInvoice(ctx context.Context, inv *models.Invoice) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[inv.UserID]; !ok {
+		return models.Errorf(ErrUserNotFound, "user %s", inv.UserID)
+	}
+	for _, issued := range m.invoices {
+		if issued.UserID == inv.UserID && issued.Period == inv.Period && issued.LegalEntity == inv.LegalEntity &&
+			issued.Currency == inv.Currency {
+			return models.Errorf(ErrInvoiceExists, "invoice %s of user %s for %s", issued.Number, inv.UserID, inv.Period)
+		}
+	}
+	m.sequences[inv.LegalEntity]++
+	inv.Sequence = m.sequences[inv.LegalEntity]
+	inv.Number = invoice.Number(inv.LegalEntity, inv.Sequence)
+	i := copyInvoice(inv)
+	m.invoices = append(m.invoices, &i)
+	return nil
+}
+
+// GetInvoice ...
+func (m *InMemoryRepository) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, inv := range m.invoices {
+		if inv.ID == invoiceID {
+			i := copyInvoice(inv)
+			return &i, nil
+		}
+	}
+	return nil, models.Errorf(ErrInvoiceNotFound, "invoice %s", invoiceID)
+}
+
+// ListUserInvoices ...
+func (m *InMemoryRepository) ListUserInvoices(ctx context.Context, userID string) ([]models.Invoice, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	invoices := make([]models.Invoice, 0)
+	for _, inv := range m.invoices {
+		if inv.UserID == userID {
+			invoices = append(invoices, copyInvoice(inv))
+		}
+	}
+	return invoices, nil
+}
+
+// copyInvoice returns an invoice copy which doesn't share the lines
+func copyInvoice(inv *models.Invoice) models.Invoice {
+	i := *inv
+	i.Lines = append([]models.InvoiceLine(nil), inv.Lines...)
+	return i
+}
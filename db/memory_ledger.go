@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"scootin/ledger"
+	"scootin/models"
+	"time"
+)
+
+// PostLedgerTransaction ...
+func (m *InMemoryRepository) PostLedgerTransaction(ctx context.Context, tx *models.LedgerTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[tx.UserID]; !ok {
+		return models.Errorf(ErrUserNotFound, "user %s", tx.UserID)
+	}
+	return m.post(tx)
+}
+
+// post appends the transaction to the ledger unless its kind and reference are already posted
+func (m *InMemoryRepository) post(tx *models.LedgerTransaction) error {
+	if !ledger.Balanced(tx) {
+		return fmt.Errorf("couldn't post the unbalanced %s transaction %s", tx.Kind, tx.ID)
+	}
+	for _, posted := range m.ledger {
+		if posted.Kind == tx.Kind && posted.Reference == tx.Reference {
+			return nil
+		}
+	}
+	m.ledger = append(m.ledger, copyLedgerTransaction(tx))
+	return nil
+}
+
+// GetWallet ...
+func (m *InMemoryRepository) GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	return &models.Wallet{UserID: userID, Balances: ledger.Balances(m.ledger, models.WalletAccount(userID))}, nil
+}
+
+// ListLedgerTransactions ...
+func (m *InMemoryRepository) ListLedgerTransactions(ctx context.Context, userID string) ([]models.LedgerTransaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	transactions := make([]models.LedgerTransaction, 0)
+	for i := range m.ledger {
+		if m.ledger[i].UserID == userID {
+			transactions = append(transactions, copyLedgerTransaction(&m.ledger[i]))
+		}
+	}
+	return transactions, nil
+}
+
+// ListLedgerTransactionsBetween ...
+func (m *InMemoryRepository) ListLedgerTransactionsBetween(ctx context.Context, from, to time.Time) ([]models.LedgerTransaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	transactions := make([]models.LedgerTransaction, 0)
+	for i := range m.ledger {
+		if at := m.ledger[i].CreatedAt; !at.Before(from) && at.Before(to) {
+			transactions = append(transactions, copyLedgerTransaction(&m.ledger[i]))
+		}
+	}
+	return transactions, nil
+}
+
+// copyLedgerTransaction returns a transaction copy which doesn't share the entries
+func copyLedgerTransaction(tx *models.LedgerTransaction) models.LedgerTransaction {
+	t := *tx
+	t.Entries = append([]models.LedgerEntry(nil), tx.Entries...)
+	return t
+}
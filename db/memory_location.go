@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"scootin/geo"
+	"scootin/models"
+	"sort"
+	"time"
+)
+
+// ScooterTrack ...
+func (m *InMemoryRepository) ScooterTrack(ctx context.Context, scooterID string, from, to time.Time) ([]models.LocationSample, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	track := make([]models.LocationSample, 0)
+	for _, sample := range m.locations[scooterID] {
+		if !sample.Time.Before(from) && sample.Time.Before(to) {
+			track = append(track, sample)
+		}
+	}
+	return track, nil
+}
+
+// MaintainLocationHistory ...
+func (m *InMemoryRepository) MaintainLocationHistory(ctx context.Context, now time.Time, retention time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldest := now.Add(-retention)
+	for scooterID, samples := range m.locations {
+		kept := samples[:0]
+		for _, sample := range samples {
+			if !sample.Time.Before(oldest) {
+				kept = append(kept, sample)
+			}
+		}
+		m.locations[scooterID] = kept
+	}
+	return nil
+}
+
+// RecordTelemetry ...
+func (m *InMemoryRepository) RecordTelemetry(ctx context.Context, scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sc, ok := m.scooters[scooterID]
+	if !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	last := m.reported[scooterID]
+	receipt := &models.TelemetryReceipt{}
+	for _, s := range samples {
+		if !s.Time.After(last) {
+			receipt.Duplicates++
+			continue
+		}
+		m.raiseZoneAlerts(sc, s.Location, s.Time)
+		sc.Location = s.Location
+		m.locations[scooterID] = append(m.locations[scooterID], models.LocationSample{ScooterID: scooterID, Time: s.Time,
+			Location: s.Location, Speed: s.Speed, Battery: s.Battery})
+		last = s.Time
+		receipt.Accepted++
+	}
+	if receipt.Accepted > 0 {
+		m.geo.set(scooterID, geo.Encode(sc.Location, geo.HashPrecision))
+		m.reported[scooterID] = last
+	}
+	return receipt, nil
+}
+
+// RecordPositions ...
+func (m *InMemoryRepository) RecordPositions(ctx context.Context, samples []models.LocationSample) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// every sample joins the history in its time order, the scooters end at their latest
+	samples = append([]models.LocationSample(nil), samples...)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	recorded := 0
+	for _, s := range samples {
+		sc, ok := m.scooters[s.ScooterID]
+		if !ok {
+			continue
+		}
+		if !s.Time.After(m.reported[s.ScooterID]) {
+			continue
+		}
+		m.raiseZoneAlerts(sc, s.Location, s.Time)
+		sc.Location = s.Location
+		m.geo.set(s.ScooterID, geo.Encode(s.Location, geo.HashPrecision))
+		m.locations[s.ScooterID] = append(m.locations[s.ScooterID], s)
+		m.reported[s.ScooterID] = s.Time
+		recorded++
+	}
+	return recorded, nil
+}
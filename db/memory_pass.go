@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"scootin/models"
+	"scootin/pricing"
+	"sort"
+	"time"
+)
+
+// passDay keys a pass's usage on a day
+type passDay struct {
+	passID string
+	day    string
+}
+
+// CreatePassPlan ...
+func (m *InMemoryRepository) CreatePassPlan(ctx context.Context, plan *models.PassPlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.passPlans = append(m.passPlans, *plan)
+	return nil
+}
+
+// ListPassPlans ...
+func (m *InMemoryRepository) ListPassPlans(ctx context.Context) ([]models.PassPlan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	plans := make([]models.PassPlan, len(m.passPlans))
+	copy(plans, m.passPlans)
+	return plans, nil
+}
+
+// GetPassPlan ...
+func (m *InMemoryRepository) GetPassPlan(ctx context.Context, planID string) (*models.PassPlan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	plan := m.passPlan(planID)
+	if plan == nil {
+		return nil, models.Errorf(ErrPassPlanNotFound, "pass plan %s", planID)
+	}
+	p := *plan
+	return &p, nil
+}
+
+// CreatePass ...
+func (m *InMemoryRepository) CreatePass(ctx context.Context, pass *models.Pass, payment *models.LedgerTransaction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[pass.UserID]; !ok {
+		return models.Errorf(ErrUserNotFound, "user %s", pass.UserID)
+	}
+	if m.passPlan(pass.PlanID) == nil {
+		return models.Errorf(ErrPassPlanNotFound, "pass plan %s", pass.PlanID)
+	}
+	for _, p := range m.passes {
+		if len(pass.RenewalOf) > 0 && p.RenewalOf == pass.RenewalOf {
+			return nil
+		}
+	}
+	if payment != nil {
+		if err := m.post(payment); err != nil {
+			return err
+		}
+	}
+	p := *pass
+	m.passes = append(m.passes, &p)
+	return nil
+}
+
+// ListRenewablePasses ...
+func (m *InMemoryRepository) ListRenewablePasses(ctx context.Context, before time.Time) ([]models.Pass, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	renewed := make(map[string]bool)
+	for _, p := range m.passes {
+		if len(p.RenewalOf) > 0 {
+			renewed[p.RenewalOf] = true
+		}
+	}
+	passes := make([]models.Pass, 0)
+	for _, p := range m.passes {
+		if p.AutoRenew && p.EndsAt.Before(before) && !renewed[p.ID] {
+			passes = append(passes, *p)
+		}
+	}
+	sort.SliceStable(passes, func(i, j int) bool { return passes[i].EndsAt.Before(passes[j].EndsAt) })
+	return passes, nil
+}
+
+// StopPassRenewal ...
+func (m *InMemoryRepository) StopPassRenewal(ctx context.Context, passID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.passes {
+		if p.ID == passID {
+			p.AutoRenew = false
+			return nil
+		}
+	}
+	return models.Errorf(ErrPassNotFound, "pass %s", passID)
+}
+
+// ListPassAllowances ...
+func (m *InMemoryRepository) ListPassAllowances(ctx context.Context, userID string, at time.Time) ([]models.PassAllowance, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	day := pricing.PassDay(at)
+	allowances := make([]models.PassAllowance, 0)
+	for _, p := range m.validPasses(userID, at) {
+		plan := m.passPlan(p.PlanID)
+		unlocks, minutes := pricing.Allowance(plan, m.passUsage[passDay{p.ID, day}])
+		allowances = append(allowances, models.PassAllowance{Pass: *p, Plan: *plan, Day: day, UnlocksLeft: unlocks, MinutesLeft: minutes})
+	}
+	return allowances, nil
+}
+
+// applyPass takes the allowances of the first of the user's valid passes covering the ride ended at the time
+// off the fare, returns the pass's usage counting the ride or false when no pass covers it.
+func (m *InMemoryRepository) applyPass(userID string, fare *models.Fare, at time.Time) (models.PassUsage, bool) {
+	day := pricing.PassDay(at)
+	for _, p := range m.validPasses(userID, at) {
+		usage, ok := m.passUsage[passDay{p.ID, day}]
+		if !ok {
+			usage = models.PassUsage{PassID: p.ID, Day: day}
+		}
+		if usage, covered := pricing.ApplyPass(fare, p, m.passPlan(p.PlanID), usage); covered {
+			return usage, true
+		}
+	}
+	return models.PassUsage{}, false
+}
+
+// validPasses returns the user's passes valid at the time, the oldest first
+func (m *InMemoryRepository) validPasses(userID string, at time.Time) []*models.Pass {
+	passes := make([]*models.Pass, 0)
+	for _, p := range m.passes {
+		if p.UserID == userID && !at.Before(p.StartsAt) && at.Before(p.EndsAt) {
+			passes = append(passes, p)
+		}
+	}
+	return passes
+}
+
+func (m *InMemoryRepository) passPlan(planID string) *models.PassPlan {
+	for i := range m.passPlans {
+		if m.passPlans[i].ID == planID {
+			return &m.passPlans[i]
+		}
+	}
+	return nil
+}
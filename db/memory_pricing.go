@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"scootin/models"
+	"scootin/pricing"
+	"time"
+)
+
+// CreatePricePlan ...
+func (m *InMemoryRepository) CreatePricePlan(ctx context.Context, plan *models.PricePlan) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	plan.Version = 1
+	for _, p := range m.pricePlans {
+		if p.City == plan.City && p.VehicleType == plan.VehicleType && p.Version >= plan.Version {
+			plan.Version = p.Version + 1
+		}
+	}
+	m.pricePlans = append(m.pricePlans, *plan)
+	return nil
+}
+
+// ListPricePlans ...
+func (m *InMemoryRepository) ListPricePlans(ctx context.Context) ([]models.PricePlan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append(make([]models.PricePlan, 0, len(m.pricePlans)), m.pricePlans...), nil
+}
+
+// PricePlanAt ...
+func (m *InMemoryRepository) PricePlanAt(ctx context.Context, city, vehicleType string, at time.Time) (*models.PricePlan, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pricePlanAt(city, vehicleType, at)
+}
+
+func (m *InMemoryRepository) pricePlanAt(city, vehicleType string, at time.Time) (*models.PricePlan, error) {
+	var plan *models.PricePlan
+	for i := range m.pricePlans {
+		if p := &m.pricePlans[i]; pricing.Applies(p, city, vehicleType, at) && (plan == nil || pricing.Preferred(p, plan)) {
+			plan = p
+		}
+	}
+	if plan == nil {
+		return nil, models.Errorf(ErrPricePlanNotFound, "city %q vehicle type %q", city, vehicleType)
+	}
+	p := *plan
+	return &p, nil
+}
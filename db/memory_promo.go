@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"scootin/ledger"
+	"scootin/models"
+	"scootin/pricing"
+	"sort"
+	"time"
+)
+
+// CreatePromoCode ...
+func (m *InMemoryRepository) CreatePromoCode(ctx context.Context, promo *models.PromoCode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.promoCodes[promo.Code]; ok {
+		return models.Errorf(ErrPromoExists, "promo code %s", promo.Code)
+	}
+	p := copyPromoCode(promo)
+	m.promoCodes[p.Code] = &p
+	return nil
+}
+
+// ListPromoCodes ...
+func (m *InMemoryRepository) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	promos := make([]models.PromoCode, 0, len(m.promoCodes))
+	for _, p := range m.promoCodes {
+		promos = append(promos, copyPromoCode(p))
+	}
+	sort.Slice(promos, func(i, j int) bool { return promos[i].CreatedAt.Before(promos[j].CreatedAt) })
+	return promos, nil
+}
+
+// RedeemPromoCode ...
+func (m *InMemoryRepository) RedeemPromoCode(ctx context.Context, code, userID string, at time.Time) (*models.PromoRedemption, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	p, ok := m.promoCodes[code]
+	if !ok {
+		return nil, models.Errorf(ErrPromoNotFound, "promo code %s", code)
+	}
+	for _, r := range m.redemptions[userID] {
+		if r.Code == code {
+			return nil, models.Errorf(ErrPromoAlreadyRedeemed, "promo code %s by user %s", code, userID)
+		}
+	}
+	if !pricing.Redeemable(p, at) {
+		return nil, models.Errorf(ErrPromoUnavailable, "promo code %s", code)
+	}
+	if p.Kind == models.PromoVoucher {
+		if err := m.post(ledger.Promo(userID, voucherReference(code, userID), models.Money{Amount: p.Amount, Currency: p.Currency})); err != nil {
+			return nil, err
+		}
+	}
+
+	p.Redemptions++
+	r := &models.PromoRedemption{Code: code, UserID: userID, RedeemedAt: at}
+	m.redemptions[userID] = append(m.redemptions[userID], r)
+	redemption := *r
+	return &redemption, nil
+}
+
+// ListPromoRedemptions ...
+func (m *InMemoryRepository) ListPromoRedemptions(ctx context.Context, userID string) ([]models.PromoRedemption, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	redemptions := make([]models.PromoRedemption, 0, len(m.redemptions[userID]))
+	for _, r := range m.redemptions[userID] {
+		redemptions = append(redemptions, *r)
+	}
+	return redemptions, nil
+}
+
+// bestPromo takes the best discount of the promo codes the user redeemed off the fare of the ride in the city,
+// returns the applied redemption or nil.
+func (m *InMemoryRepository) bestPromo(userID, city string, fare *models.Fare, at time.Time) *models.PromoRedemption {
+	promos := make([]models.PromoCode, 0)
+	for _, r := range m.redemptions[userID] {
+		if p := m.promoCodes[r.Code]; p.Kind != models.PromoVoucher && pricing.Usable(p, r) {
+			promos = append(promos, *p)
+		}
+	}
+	firstRide := true
+	for _, id := range m.userTrip[userID] {
+		if m.trips[id].EndedAt != nil {
+			firstRide = false
+		}
+	}
+	applied := pricing.ApplyBestPromo(fare, promos, city, firstRide, at)
+	if applied == nil {
+		return nil
+	}
+	for _, r := range m.redemptions[userID] {
+		if r.Code == applied.Code {
+			return r
+		}
+	}
+	return nil
+}
+
+// copyPromoCode returns a promo code copy which doesn't share the expiry
+func copyPromoCode(p *models.PromoCode) models.PromoCode {
+	promo := *p
+	if p.ExpiresAt != nil {
+		expiresAt := *p.ExpiresAt
+		promo.ExpiresAt = &expiresAt
+	}
+	return promo
+}
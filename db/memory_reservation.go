@@ -0,0 +1,153 @@
+package db
+
+import (
+	"context"
+	"scootin/geo"
+	"scootin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReserveScooter ...
+func (m *InMemoryRepository) ReserveScooter(ctx context.Context, scooterID, userID string, hold time.Duration, surge float64) (*models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[userID]; !ok {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	// a scooter is reserved on the same terms it's booked
+	sc, ok := m.scooters[scooterID]
+	if !ok {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	} else if err := bookable(sc.Status, scooterID, userID); err != nil {
+		return nil, err
+	}
+	if err := m.userHolds(userID); err != nil {
+		return nil, err
+	}
+	sc.Status, sc.UserID = models.StatusReserved, userID
+
+	now := time.Now().UTC()
+	r := &models.Reservation{ID: uuid.New().String(), ScooterID: scooterID, UserID: userID, Status: models.ReservationActive,
+		CreatedAt: now, ExpiresAt: now.Add(hold), Surge: surge}
+	m.reservations[r.ID] = r
+	res := copyReservation(r)
+	return &res, nil
+}
+
+// GetReservation ...
+func (m *InMemoryRepository) GetReservation(ctx context.Context, reservationID string) (*models.Reservation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	r, ok := m.reservations[reservationID]
+	if !ok {
+		return nil, models.Errorf(ErrReservationNotFound, "reservation %s", reservationID)
+	}
+	res := copyReservation(r)
+	return &res, nil
+}
+
+// ConvertReservation ...
+func (m *InMemoryRepository) ConvertReservation(ctx context.Context, reservationID, userID string, near *models.Proximity) (*models.Trip, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := m.activeReservation(reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// an elapsed hold isn't converted even if the sweeper hasn't expired it yet
+	now := time.Now().UTC()
+	if !now.Before(r.ExpiresAt) {
+		m.endReservation(r, models.ReservationExpired, now)
+		return nil, models.Errorf(ErrReservationNotActive, "reservation %s has expired", reservationID)
+	}
+	sc := m.scooters[r.ScooterID]
+	if err := nearby(near, r.ScooterID, sc.Location); err != nil {
+		return nil, err
+	}
+
+	trip := m.startTrip(sc, userID, r.Surge)
+	r.Status, r.EndedAt, r.TripID = models.ReservationConverted, &now, trip.ID
+	t := *trip
+	return &t, nil
+}
+
+// CancelReservation ...
+func (m *InMemoryRepository) CancelReservation(ctx context.Context, reservationID, userID string) (*models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r, err := m.activeReservation(reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	m.endReservation(r, models.ReservationCancelled, time.Now().UTC())
+	res := copyReservation(r)
+	return &res, nil
+}
+
+// CountActiveReservations ...
+func (m *InMemoryRepository) CountActiveReservations(ctx context.Context, zone string) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	count := 0
+	for _, r := range m.reservations {
+		if r.Status == models.ReservationActive && geo.Zone(m.scooters[r.ScooterID].Location) == zone {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ExpireReservations ...
+func (m *InMemoryRepository) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	expired := 0
+	for _, r := range m.reservations {
+		if r.Status == models.ReservationActive && !now.Before(r.ExpiresAt) {
+			m.endReservation(r, models.ReservationExpired, now)
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// activeReservation returns the user's reservation as long as it's still active
+func (m *InMemoryRepository) activeReservation(reservationID, userID string) (*models.Reservation, error) {
+	r, ok := m.reservations[reservationID]
+	switch {
+	case !ok:
+		return nil, models.Errorf(ErrReservationNotFound, "reservation %s", reservationID)
+	case r.UserID != userID:
+		return nil, models.Errorf(ErrForbidden, "reservation %s isn't held by user %s", reservationID, userID)
+	case r.Status != models.ReservationActive:
+		return nil, models.Errorf(ErrReservationNotActive, "reservation %s is %s", reservationID, r.Status)
+	}
+	return r, nil
+}
+
+// endReservation ends the reservation without a trip, its scooter is available again
+func (m *InMemoryRepository) endReservation(r *models.Reservation, status models.ReservationStatus, now time.Time) {
+	endedAt := now
+	r.Status, r.EndedAt = status, &endedAt
+	if sc, ok := m.scooters[r.ScooterID]; ok && sc.Status == models.StatusReserved && sc.UserID == r.UserID {
+		sc.Status, sc.UserID = models.StatusAvailable, ""
+	}
+}
+
+// copyReservation returns a reservation copy which doesn't share the stored reservation's end time
+func copyReservation(r *models.Reservation) models.Reservation {
+	res := *r
+	if r.EndedAt != nil {
+		endedAt := *r.EndedAt
+		res.EndedAt = &endedAt
+	}
+	return res
+}
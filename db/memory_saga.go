@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"scootin/models"
+	"sort"
+	"time"
+)
+
+// CreatePaymentSaga ...
+func (m *InMemoryRepository) CreatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sagas[saga.ID]; ok {
+		return fmt.Errorf("payment saga %s already exists", saga.ID)
+	}
+	s := *saga
+	m.sagas[s.ID] = &s
+	return nil
+}
+
+// UpdatePaymentSaga ...
+func (m *InMemoryRepository) UpdatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.sagas[saga.ID]; !ok {
+		return models.Errorf(ErrPaymentSagaNotFound, "payment saga %s", saga.ID)
+	}
+	s := *saga
+	m.sagas[s.ID] = &s
+	return nil
+}
+
+// PaymentSagaByTrip ...
+func (m *InMemoryRepository) PaymentSagaByTrip(ctx context.Context, tripID string) (*models.PaymentSaga, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, saga := range m.sagas {
+		if len(tripID) > 0 && saga.TripID == tripID {
+			s := *saga
+			return &s, nil
+		}
+	}
+	return nil, models.Errorf(ErrPaymentSagaNotFound, "trip %s", tripID)
+}
+
+// ListStalePaymentSagas ...
+func (m *InMemoryRepository) ListStalePaymentSagas(ctx context.Context, before time.Time) ([]models.PaymentSaga, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sagas := make([]models.PaymentSaga, 0)
+	for _, saga := range m.sagas {
+		if !saga.Step.Done() && saga.UpdatedAt.Before(before) {
+			sagas = append(sagas, *saga)
+		}
+	}
+	sort.Slice(sagas, func(i, j int) bool { return sagas[i].UpdatedAt.Before(sagas[j].UpdatedAt) })
+	return sagas, nil
+}
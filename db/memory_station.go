@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"scootin/geo"
+	"scootin/models"
+	"scootin/pricing"
+)
+
+// CreateStation ...
+func (m *InMemoryRepository) CreateStation(ctx context.Context, station *models.Station) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stations = append(m.stations, *station)
+	return nil
+}
+
+// ListStations ...
+func (m *InMemoryRepository) ListStations(ctx context.Context, city string) ([]models.StationOccupancy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stations := make([]models.StationOccupancy, 0)
+	for _, station := range m.stations {
+		if len(city) == 0 || station.City == city {
+			stations = append(stations, pricing.Occupancy(station, m.parkedNear(station.Location, station.Radius)))
+		}
+	}
+	return stations, nil
+}
+
+// parkedNear counts the scooters parked within the radius in meters around the center, the ones not in a trip
+func (m *InMemoryRepository) parkedNear(center models.Location, radius float64) int {
+	parked := 0
+	for _, cell := range geo.CoveringHashes(center, radius) {
+		for _, id := range m.geo.within(cell) {
+			if sc := m.scooters[id]; sc.Status != models.StatusInTrip && geo.Distance(center, sc.Location) <= radius {
+				parked++
+			}
+		}
+	}
+	return parked
+}
@@ -0,0 +1,699 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"scootin/geo"
+	"scootin/ledger"
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryBooking(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	assert.Error(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc2"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u2"}))
+
+	// a scooter can only be booked while it's not occupied
+	trip, err := m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	_, err = m.BookScooter(ctx, "sc1", "u2", 1, nil)
+	assert.ErrorIs(t, err, ErrScooterOccupied)
+	sc, err := m.GetScooter(ctx, "sc1")
+	assert.NoError(t, err)
+	assert.Equal(t, "u1", sc.UserID)
+	_, err = m.BookScooter(ctx, "unknown", "u2", 1, nil)
+	assert.ErrorIs(t, err, ErrScooterNotFound)
+	_, err = m.BookScooter(ctx, "sc2", "unknown", 1, nil)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	// a user rides one scooter at a time
+	_, err = m.BookScooter(ctx, "sc2", "u1", 1, nil)
+	assert.ErrorIs(t, err, ErrActiveTripExists)
+
+	scs, err := m.ListAvailableScooter(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scs, 1)
+
+	ended, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, trip.ID, ended.ID)
+	_, err = m.ReleaseScooter(ctx, "u1")
+	assert.ErrorIs(t, err, ErrNoActiveTrip)
+	scs, err = m.ListAvailableScooter(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scs, 2)
+	assert.Equal(t, models.StatusAvailable, scs[0].Status)
+	assert.Empty(t, scs[0].UserID)
+
+	// releasing ends the trip
+	trip, err = m.GetTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, trip.EndedAt)
+	_, err = m.GetTrip(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrTripNotFound)
+}
+
+func TestInMemoryLocationHistory(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	moveScooter(t, m, "sc1", models.Location{Latitude: 1, Longitude: 2})
+	moveScooter(t, m, "sc1", models.Location{Latitude: 3, Longitude: 4})
+	recorded, err := m.RecordPositions(ctx, []models.LocationSample{{ScooterID: "sc2", Time: time.Now().UTC(),
+		Location: models.Location{Latitude: 5, Longitude: 6}}})
+	assert.NoError(t, err)
+	assert.Zero(t, recorded)
+
+	now := time.Now()
+	track, err := m.ScooterTrack(ctx, "sc1", now.Add(-time.Minute), now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, track, 2)
+	assert.Equal(t, models.Location{Latitude: 3, Longitude: 4}, track[1].Location)
+
+	// nothing is kept beyond the retention
+	assert.NoError(t, m.MaintainLocationHistory(ctx, now.Add(time.Hour), time.Minute))
+	track, err = m.ScooterTrack(ctx, "sc1", now.Add(-time.Minute), now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, track)
+}
+
+func TestInMemoryNearbyScooters(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	center := models.Location{Latitude: 52.52, Longitude: 13.405}
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "far", Location: geo.Move(center, 3000, 0)}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "near", Location: geo.Move(center, 50, 90)}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "booked", Location: geo.Move(center, 10, 180)}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "moved", Location: center}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	_, err := m.BookScooter(ctx, "booked", "u1", 1, nil)
+	assert.NoError(t, err)
+	// the index follows the scooter's moves
+	moveScooter(t, m, "moved", geo.Move(center, 400, 270))
+
+	near, err := m.ListAvailableScooterNear(ctx, center, 1000, 10)
+	assert.NoError(t, err)
+	assert.Len(t, near, 2)
+	assert.Equal(t, "near", near[0].ID)
+	assert.Equal(t, "moved", near[1].ID)
+	assert.InDelta(t, 400, near[1].Distance, 1)
+
+	// a zone holds the available scooters of its geohash cell only
+	zone, err := m.ListAvailableScooterInZone(ctx, geo.Zone(geo.Move(center, 50, 90)))
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	for _, sc := range zone {
+		ids = append(ids, sc.ID)
+	}
+	assert.Contains(t, ids, "near")
+	assert.NotContains(t, ids, "booked")
+	assert.NotContains(t, ids, "far")
+}
+
+func TestInMemoryScooterStatus(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+
+	sc, err := m.SetScooterStatus(ctx, "sc1", models.StatusLowBattery)
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusLowBattery, sc.Status)
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.ErrorIs(t, err, ErrScooterUnavailable)
+
+	_, err = m.SetScooterStatus(ctx, "sc1", models.StatusRetired)
+	assert.NoError(t, err)
+	// a retired scooter never comes back
+	_, err = m.SetScooterStatus(ctx, "sc1", models.StatusAvailable)
+	assert.ErrorIs(t, err, ErrInvalidTransition)
+	_, err = m.SetScooterStatus(ctx, "unknown", models.StatusAvailable)
+	assert.ErrorIs(t, err, ErrScooterNotFound)
+}
+
+func TestInMemoryReservations(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc2"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u2"}))
+
+	// a reserved scooter is held for its user only, its zone's demand counts it
+	r, err := m.ReserveScooter(ctx, "sc1", "u1", time.Minute, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationActive, r.Status)
+	demand, err := m.CountActiveReservations(ctx, geo.Zone(models.Location{}))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, demand)
+	_, err = m.BookScooter(ctx, "sc1", "u2", 1, nil)
+	assert.ErrorIs(t, err, ErrScooterOccupied)
+	_, err = m.ReserveScooter(ctx, "sc2", "u1", time.Minute, 1)
+	assert.ErrorIs(t, err, ErrActiveReservationExists)
+	_, err = m.BookScooter(ctx, "sc2", "u1", 1, nil)
+	assert.ErrorIs(t, err, ErrActiveReservationExists)
+	_, err = m.ConvertReservation(ctx, r.ID, "u2", nil)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	// the rider must be by the scooter when the trip starts
+	far := &models.Proximity{Position: geo.Move(models.Location{}, 500, 0), MaxDistance: 100}
+	_, err = m.ConvertReservation(ctx, r.ID, "u1", far)
+	assert.ErrorIs(t, err, ErrScooterTooFar)
+	trip, err := m.ConvertReservation(ctx, r.ID, "u1", &models.Proximity{MaxDistance: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, "sc1", trip.ScooterID)
+	assert.Equal(t, 2.0, trip.Surge)
+	r, err = m.GetReservation(ctx, r.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationConverted, r.Status)
+	assert.Equal(t, trip.ID, r.TripID)
+	_, err = m.CancelReservation(ctx, r.ID, "u1")
+	assert.ErrorIs(t, err, ErrReservationNotActive)
+	_, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+
+	// cancelling returns the scooter
+	r, err = m.ReserveScooter(ctx, "sc2", "u2", time.Minute, 1)
+	assert.NoError(t, err)
+	r, err = m.CancelReservation(ctx, r.ID, "u2")
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationCancelled, r.Status)
+	scs, err := m.ListAvailableScooter(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scs, 2)
+
+	// the elapsed holds are expired, even before the sweeper runs
+	r, err = m.ReserveScooter(ctx, "sc2", "u2", 0, 1)
+	assert.NoError(t, err)
+	_, err = m.ConvertReservation(ctx, r.ID, "u2", nil)
+	assert.ErrorIs(t, err, ErrReservationNotActive)
+	r, err = m.ReserveScooter(ctx, "sc2", "u2", time.Minute, 1)
+	assert.NoError(t, err)
+	n, err := m.ExpireReservations(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = m.ExpireReservations(ctx, time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	r, err = m.GetReservation(ctx, r.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.ReservationExpired, r.Status)
+	scs, err = m.ListAvailableScooter(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, scs, 2)
+	_, err = m.GetReservation(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+}
+
+func TestInMemoryFares(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	start := models.Location{Latitude: 52.52, Longitude: 13.405}
+
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", Location: start, City: "berlin"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc2", City: "paris"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+
+	// berlin's own plan replaces the default one, a scheduled version isn't effective yet
+	berlin := &models.PricePlan{ID: "berlin", City: "berlin", VehicleType: models.DefaultVehicleType, Currency: "EUR",
+		UnlockFee: 50, PerKilometer: 100}
+	assert.NoError(t, m.CreatePricePlan(ctx, berlin))
+	assert.Equal(t, 1, berlin.Version)
+	later := &models.PricePlan{ID: "later", City: "berlin", VehicleType: models.DefaultVehicleType, Currency: "EUR",
+		EffectiveFrom: time.Now().Add(time.Hour)}
+	assert.NoError(t, m.CreatePricePlan(ctx, later))
+	assert.Equal(t, 2, later.Version)
+
+	plan, err := m.PricePlanAt(ctx, "paris", models.DefaultVehicleType, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultPricePlan.ID, plan.ID)
+	_, err = m.PricePlanAt(ctx, "berlin", "bike", time.Now())
+	assert.ErrorIs(t, err, ErrPricePlanNotFound)
+
+	// the fare follows the reported path
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	moveScooter(t, m, "sc1", geo.Move(start, 1000, 0))
+	moveScooter(t, m, "sc1", start)
+	trip, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "berlin", trip.Fare.PlanID)
+	assert.InDelta(t, 2000, trip.Fare.Distance, 1)
+	assert.Equal(t, int64(200), trip.Fare.DistanceCharge)
+	assert.Equal(t, int64(1), trip.Fare.Minutes)
+	assert.Equal(t, int64(250), trip.Fare.Total)
+
+	trip, err = m.GetTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(250), trip.Fare.Total)
+}
+
+func TestInMemoryCheckouts(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+
+	checkout := &models.Checkout{ID: "c1", TripID: "t1", Status: models.CheckoutPending}
+	assert.NoError(t, m.CreateCheckout(ctx, checkout))
+	// a trip is paid once
+	assert.ErrorIs(t, m.CreateCheckout(ctx, &models.Checkout{ID: "c2", TripID: "t1", Status: models.CheckoutPending}), ErrTripAlreadyPaid)
+
+	// a declined checkout doesn't keep the trip from being paid
+	checkout.Status = models.CheckoutDeclined
+	assert.NoError(t, m.UpdateCheckout(ctx, checkout))
+	assert.NoError(t, m.CreateCheckout(ctx, &models.Checkout{ID: "c2", TripID: "t1", Status: models.CheckoutPending}))
+
+	checkout, err := m.GetCheckout(ctx, "c1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.CheckoutDeclined, checkout.Status)
+	_, err = m.GetCheckout(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrCheckoutNotFound)
+	assert.ErrorIs(t, m.UpdateCheckout(ctx, &models.Checkout{ID: "unknown"}), ErrCheckoutNotFound)
+}
+
+func TestInMemoryLedger(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+
+	// the fare is charged at release and the captured checkout pays it
+	trip, err := m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	wallet, err := m.GetWallet(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: -trip.Fare.Total, Currency: "EUR"}}, wallet.Balances)
+
+	checkout := &models.Checkout{ID: "c1", TripID: trip.ID, UserID: "u1", Amount: trip.Fare.Total, Currency: "EUR", Status: models.CheckoutPending}
+	assert.NoError(t, m.CreateCheckout(ctx, checkout))
+	checkout.Status = models.CheckoutCaptured
+	assert.NoError(t, m.UpdateCheckout(ctx, checkout))
+	// a posting is retried safely
+	assert.NoError(t, m.UpdateCheckout(ctx, checkout))
+	assert.NoError(t, m.PostLedgerTransaction(ctx, ledger.Payment(checkout)))
+	wallet, err = m.GetWallet(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 0, Currency: "EUR"}}, wallet.Balances)
+
+	transactions, err := m.ListLedgerTransactions(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+	assert.Equal(t, models.LedgerFare, transactions[0].Kind)
+	assert.Equal(t, models.LedgerPayment, transactions[1].Kind)
+
+	unbalanced := ledger.Promo("u1", "p1", models.Money{Amount: 100, Currency: "EUR"})
+	unbalanced.Entries = unbalanced.Entries[:1]
+	assert.Error(t, m.PostLedgerTransaction(ctx, unbalanced))
+	assert.ErrorIs(t, m.PostLedgerTransaction(ctx, ledger.Promo("unknown", "p1", models.Money{Amount: 100, Currency: "EUR"})), ErrUserNotFound)
+	_, err = m.GetWallet(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestInMemoryDisputes(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+
+	now := time.Now().UTC()
+	dispute := &models.Dispute{ID: "d1", TripID: "t1", UserID: "u1", Status: models.DisputeOpen, CreatedAt: now,
+		Events: []models.DisputeEvent{{Status: models.DisputeOpen, Actor: models.ActorRider, At: now}}}
+	assert.NoError(t, m.CreateDispute(ctx, dispute))
+	assert.ErrorIs(t, m.CreateDispute(ctx, &models.Dispute{ID: "d2", TripID: "t1"}), ErrDisputeExists)
+
+	// an open dispute is reviewed before it's decided
+	refunded := models.DisputeEvent{Status: models.DisputeRefunded, Actor: models.ActorSupport, At: now}
+	assert.ErrorIs(t, m.UpdateDispute(ctx, dispute, refunded, nil), ErrInvalidDisputeStatus)
+	assert.NoError(t, m.UpdateDispute(ctx, dispute, models.DisputeEvent{Status: models.DisputeUnderReview, Actor: models.ActorSupport, At: now}, nil))
+	dispute.RefundAmount, dispute.Currency = 100, "EUR"
+	refund := ledger.Refund("u1", "d1", models.Money{Amount: 100, Currency: "EUR"}, models.WalletAccount("u1"))
+	assert.NoError(t, m.UpdateDispute(ctx, dispute, refunded, refund))
+	assert.Equal(t, models.DisputeRefunded, dispute.Status)
+	assert.Len(t, dispute.Events, 3)
+	assert.ErrorIs(t, m.UpdateDispute(ctx, dispute, refunded, refund), ErrInvalidDisputeStatus)
+
+	wallet, err := m.GetWallet(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 100, Currency: "EUR"}}, wallet.Balances)
+	disputes, err := m.ListDisputes(ctx, models.DisputeOpen)
+	assert.NoError(t, err)
+	assert.Empty(t, disputes)
+	disputes, err = m.ListDisputes(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, disputes, 1)
+	_, err = m.GetDispute(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrDisputeNotFound)
+}
+
+func TestInMemoryPromos(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	now := time.Now().UTC()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u2"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+
+	unlock := &models.PromoCode{Code: "UNLOCK", Kind: models.PromoPercentOff, Percent: 100, Target: models.PromoTargetUnlock,
+		MaxRedemptions: 1, PerUserLimit: 1, CreatedAt: now}
+	assert.NoError(t, m.CreatePromoCode(ctx, unlock))
+	assert.ErrorIs(t, m.CreatePromoCode(ctx, unlock), ErrPromoExists)
+	assert.NoError(t, m.CreatePromoCode(ctx, &models.PromoCode{Code: "GIFT", Kind: models.PromoVoucher, Amount: 500, Currency: "EUR", CreatedAt: now}))
+
+	// the redemptions are capped
+	_, err := m.RedeemPromoCode(ctx, "UNLOCK", "u1", now)
+	assert.NoError(t, err)
+	_, err = m.RedeemPromoCode(ctx, "UNLOCK", "u1", now)
+	assert.ErrorIs(t, err, ErrPromoAlreadyRedeemed)
+	_, err = m.RedeemPromoCode(ctx, "UNLOCK", "u2", now)
+	assert.ErrorIs(t, err, ErrPromoUnavailable)
+	_, err = m.RedeemPromoCode(ctx, "UNKNOWN", "u2", now)
+	assert.ErrorIs(t, err, ErrPromoNotFound)
+
+	// a voucher is credited to the wallet
+	_, err = m.RedeemPromoCode(ctx, "GIFT", "u2", now)
+	assert.NoError(t, err)
+	wallet, err := m.GetWallet(ctx, "u2")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 500, Currency: "EUR"}}, wallet.Balances)
+
+	// the discount applies to as many rides as the user's limit
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "UNLOCK", trip.Fare.PromoCode)
+	assert.Equal(t, trip.Fare.UnlockFee, trip.Fare.Discount)
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Empty(t, trip.Fare.PromoCode)
+
+	redemptions, err := m.ListPromoRedemptions(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, redemptions[0].Uses)
+	promos, err := m.ListPromoCodes(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, promos, 2)
+}
+
+func TestInMemoryPasses(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	now := time.Now().UTC()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+
+	plan := &models.PassPlan{ID: "day", Name: "Day", Period: models.PassDay, Price: 300, Currency: defaultPricePlan.Currency,
+		FreeUnlocks: 1, CreatedAt: now}
+	assert.NoError(t, m.CreatePassPlan(ctx, plan))
+	_, err := m.GetPassPlan(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrPassPlanNotFound)
+	pass := &models.Pass{ID: "p1", PlanID: plan.ID, UserID: "u1", StartsAt: now.Add(-time.Hour), EndsAt: now.Add(time.Hour), AutoRenew: true}
+	assert.ErrorIs(t, m.CreatePass(ctx, &models.Pass{ID: "p0", PlanID: "unknown", UserID: "u1"}, nil), ErrPassPlanNotFound)
+	assert.NoError(t, m.CreatePass(ctx, pass, ledger.Pass("u1", pass.ID, models.Money{Amount: plan.Price, Currency: plan.Currency})))
+
+	// the day's free unlock covers the first ride only
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, pass.ID, trip.Fare.PassID)
+	assert.True(t, trip.Fare.FreeUnlock)
+	assert.Equal(t, trip.Fare.UnlockFee, trip.Fare.PassCredit)
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Empty(t, trip.Fare.PassID)
+	allowances, err := m.ListPassAllowances(ctx, "u1", now)
+	assert.NoError(t, err)
+	assert.Len(t, allowances, 1)
+	assert.Zero(t, allowances[0].UnlocksLeft)
+
+	// a pass is renewed once
+	renewable, err := m.ListRenewablePasses(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, renewable, 1)
+	renewal := &models.Pass{ID: "p2", PlanID: plan.ID, UserID: "u1", StartsAt: pass.EndsAt, EndsAt: pass.EndsAt.Add(24 * time.Hour), RenewalOf: pass.ID}
+	assert.NoError(t, m.CreatePass(ctx, renewal, nil))
+	assert.NoError(t, m.CreatePass(ctx, &models.Pass{ID: "p3", PlanID: plan.ID, UserID: "u1", RenewalOf: pass.ID}, nil))
+	renewable, err = m.ListRenewablePasses(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, renewable)
+	assert.ErrorIs(t, m.StopPassRenewal(ctx, "unknown"), ErrPassNotFound)
+}
+
+func TestInMemoryInvoices(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	now := time.Now().UTC()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u2"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", City: "Berlin"}))
+	assert.NoError(t, m.SetTaxRule(ctx, &models.TaxRule{City: "Berlin", LegalEntity: "DE01", EntityName: "Scootin GmbH", VATRate: 1900}))
+	rules, err := m.ListTaxRules(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+
+	// the ride's fare is charged in the city it ended
+	_, err = m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	charges, err := m.ListCharges(ctx, now.Add(-time.Minute), time.Now().UTC().Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, charges, 1) {
+		assert.Equal(t, trip.ID, charges[0].Reference)
+		assert.Equal(t, "Berlin", charges[0].City)
+	}
+
+	// the entity's invoices are numbered without gaps, once per user and period
+	for _, userID := range []string{"u1", "u2"} {
+		assert.NoError(t, m.CreateInvoice(ctx, &models.Invoice{ID: "i-" + userID, LegalEntity: "DE01", UserID: userID,
+			Period: "2021-03", Currency: "EUR"}))
+	}
+	assert.ErrorIs(t, m.CreateInvoice(ctx, &models.Invoice{ID: "i3", LegalEntity: "DE01", UserID: "u1", Period: "2021-03",
+		Currency: "EUR"}), ErrInvoiceExists)
+	inv, err := m.GetInvoice(ctx, "i-u2")
+	assert.NoError(t, err)
+	assert.Equal(t, "DE01-000002", inv.Number)
+	invoices, err := m.ListUserInvoices(ctx, "u1")
+	assert.NoError(t, err)
+	if assert.Len(t, invoices, 1) {
+		assert.Equal(t, int64(1), invoices[0].Sequence)
+	}
+	_, err = m.GetInvoice(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrInvoiceNotFound)
+}
+
+func TestInMemoryZones(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", Location: models.Location{Latitude: 52.5, Longitude: 13.4}}))
+	square := func(lon, lat, half float64) models.Geometry {
+		return models.Geometry{Type: "Polygon", Coordinates: json.RawMessage(fmt.Sprintf("[[[%[1]f,%[2]f],[%[3]f,%[2]f],[%[3]f,%[4]f],[%[1]f,%[4]f],[%[1]f,%[2]f]]]",
+			lon-half, lat-half, lon+half, lat+half))}
+	}
+	city := &models.Zone{ID: "city", Tags: []models.ZoneTag{models.ZoneOperatingArea}, Geometry: square(13.4, 52.5, 0.1)}
+	station := &models.Zone{ID: "station", Tags: []models.ZoneTag{models.ZoneNoParking, models.ZoneNoRiding}, Geometry: square(13.45, 52.5, 0.001)}
+	assert.NoError(t, m.CreateZone(ctx, city))
+	assert.NoError(t, m.CreateZone(ctx, station))
+
+	// riding into the no-riding zone raises an alert, the trip can't end there nor out of the city
+	_, err := m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	before := time.Now().UTC()
+	moveScooter(t, m, "sc1", models.Location{Latitude: 52.5, Longitude: 13.45})
+	moveScooter(t, m, "sc1", models.Location{Latitude: 52.5, Longitude: 13.4501})
+	alerts, err := m.ListZoneAlerts(ctx, before, time.Now().UTC().Add(time.Second))
+	assert.NoError(t, err)
+	if assert.Len(t, alerts, 1) {
+		assert.Equal(t, "station", alerts[0].ZoneID)
+		assert.Equal(t, "u1", alerts[0].UserID)
+	}
+	_, err = m.ReleaseScooter(ctx, "u1")
+	assert.ErrorIs(t, err, ErrNoParkingZone)
+	moveScooter(t, m, "sc1", models.Location{Latitude: 53, Longitude: 13.4})
+	_, err = m.ReleaseScooter(ctx, "u1")
+	assert.ErrorIs(t, err, ErrOutsideOperatingArea)
+
+	// the city grows to reach the scooter
+	city.Geometry = square(13.4, 52.5, 1)
+	assert.NoError(t, m.UpdateZone(ctx, city))
+	_, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.DeleteZone(ctx, station.ID))
+	zones, err := m.ListZones(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, zones, 1)
+	_, err = m.GetZone(ctx, station.ID)
+	assert.ErrorIs(t, err, ErrZoneNotFound)
+	assert.ErrorIs(t, m.UpdateZone(ctx, station), ErrZoneNotFound)
+	assert.ErrorIs(t, m.DeleteZone(ctx, station.ID), ErrZoneNotFound)
+}
+
+func TestInMemoryStations(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	dock := models.Location{Latitude: 52.52, Longitude: 13.405}
+	assert.NoError(t, m.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", Location: dock, City: "berlin"}))
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc2", Location: models.Location{Latitude: 52.53, Longitude: 13.405}, City: "berlin"}))
+	station := &models.Station{ID: "s1", City: "berlin", Location: dock, Radius: 50, Capacity: 1, DiscountPercent: 50}
+	assert.NoError(t, m.CreateStation(ctx, station))
+
+	// the scooter leaving the station frees its slot, the trip ending there gets the discount
+	_, err := m.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	stations, err := m.ListStations(ctx, "berlin")
+	assert.NoError(t, err)
+	if assert.Len(t, stations, 1) {
+		assert.Equal(t, 1, stations[0].FreeSlots)
+	}
+	trip, err := m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, station.ID, trip.Fare.StationID)
+	charges := trip.Fare.UnlockFee + trip.Fare.TimeCharge + trip.Fare.DistanceCharge
+	assert.Equal(t, charges*50/100, trip.Fare.StationCredit)
+	assert.Equal(t, charges-trip.Fare.StationCredit, trip.Fare.Total)
+
+	// the full station gives no discount and is over capacity once another scooter is parked at it
+	_, err = m.BookScooter(ctx, "sc2", "u1", 1, nil)
+	assert.NoError(t, err)
+	moveScooter(t, m, "sc2", dock)
+	trip, err = m.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Empty(t, trip.Fare.StationID)
+	stations, err = m.ListStations(ctx, "")
+	assert.NoError(t, err)
+	if assert.Len(t, stations, 1) {
+		assert.Equal(t, 2, stations[0].Occupancy)
+		assert.True(t, stations[0].Rebalance)
+	}
+	stations, err = m.ListStations(ctx, "hamburg")
+	assert.NoError(t, err)
+	assert.Empty(t, stations)
+}
+
+func TestInMemoryTelemetry(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	start := models.Location{Latitude: 52.5, Longitude: 13.4}
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", Location: start}))
+	station := &models.Zone{ID: "station", Tags: []models.ZoneTag{models.ZoneNoRiding}, Geometry: models.Geometry{Type: "Polygon",
+		Coordinates: json.RawMessage("[[[13.409,52.499],[13.411,52.499],[13.411,52.501],[13.409,52.501],[13.409,52.499]]]")}}
+	assert.NoError(t, m.CreateZone(ctx, station))
+
+	// the scooter rides through the no-riding zone between its reports
+	now := time.Now().UTC()
+	samples := []models.TelemetrySample{
+		{Time: now, Location: models.Location{Latitude: 52.5, Longitude: 13.41}, Speed: 15, Battery: 90},
+		{Time: now.Add(time.Second), Location: models.Location{Latitude: 52.5, Longitude: 13.42}, Speed: 16, Battery: 89},
+	}
+	receipt, err := m.RecordTelemetry(ctx, "sc1", samples)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Accepted: 2}, receipt)
+	sc, err := m.GetScooter(ctx, "sc1")
+	assert.NoError(t, err)
+	assert.Equal(t, samples[1].Location, sc.Location)
+	alerts, err := m.ListZoneAlerts(ctx, now, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Len(t, alerts, 1)
+
+	// the samples already recorded are duplicates
+	receipt, err = m.RecordTelemetry(ctx, "sc1", append(samples, models.TelemetrySample{Time: now.Add(2 * time.Second), Location: start}))
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Accepted: 1, Duplicates: 2}, receipt)
+	track, err := m.ScooterTrack(ctx, "sc1", now, now.Add(time.Minute))
+	assert.NoError(t, err)
+	if assert.Len(t, track, 3) {
+		assert.Equal(t, 89, track[1].Battery)
+		assert.Equal(t, start, track[2].Location)
+	}
+
+	// the replayed samples are still duplicates once the history's retention dropped them
+	assert.NoError(t, m.MaintainLocationHistory(ctx, now.Add(time.Hour), time.Minute))
+	receipt, err = m.RecordTelemetry(ctx, "sc1", samples)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TelemetryReceipt{Duplicates: 2}, receipt)
+
+	_, err = m.RecordTelemetry(ctx, "sc2", samples)
+	assert.ErrorIs(t, err, ErrScooterNotFound)
+}
+
+func TestInMemoryCommands(t *testing.T) {
+	ctx := context.Background()
+	m := NewInMemory()
+	assert.NoError(t, m.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	now := time.Now().UTC()
+	command := func(id string, ttl time.Duration) *models.Command {
+		return &models.Command{ID: id, ScooterID: "sc1", Kind: models.CommandLock, State: models.CommandQueued,
+			CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	}
+	assert.NoError(t, m.CreateCommand(ctx, command("c1", time.Minute)))
+	assert.NoError(t, m.CreateCommand(ctx, command("c2", time.Second)))
+	assert.ErrorIs(t, m.CreateCommand(ctx, &models.Command{ID: "c3", ScooterID: "sc2"}), ErrScooterNotFound)
+
+	// a queued command can't be acknowledged, a delivered one is delivered again until it's acknowledged
+	_, err := m.AckCommand(ctx, "sc1", "c1", models.CommandAck{State: models.CommandAcked}, now)
+	assert.ErrorIs(t, err, ErrCommandNotDelivered)
+	commands, err := m.PollCommands(ctx, "sc1", now)
+	assert.NoError(t, err)
+	assert.Len(t, commands, 2)
+	c, err := m.AckCommand(ctx, "sc1", "c1", models.CommandAck{State: models.CommandAcked}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CommandAcked, c.State)
+	assert.NotNil(t, c.EndedAt)
+	_, err = m.AckCommand(ctx, "sc1", "c1", models.CommandAck{State: models.CommandFailed}, now)
+	assert.ErrorIs(t, err, ErrCommandNotDelivered)
+	commands, err = m.PollCommands(ctx, "sc1", now)
+	assert.NoError(t, err)
+	if assert.Len(t, commands, 1) {
+		assert.Equal(t, "c2", commands[0].ID)
+	}
+
+	// the command not acknowledged in time fails
+	commands, err = m.PollCommands(ctx, "sc1", now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, commands)
+	n, err := m.ExpireCommands(ctx, now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	c, err = m.GetCommand(ctx, "c2")
+	assert.NoError(t, err)
+	assert.Equal(t, models.CommandFailed, c.State)
+	assert.Equal(t, "expired", c.Reason)
+
+	commands, err = m.ListCommands(ctx, "sc1")
+	assert.NoError(t, err)
+	assert.Len(t, commands, 2)
+	_, err = m.GetCommand(ctx, "c3")
+	assert.ErrorIs(t, err, ErrCommandNotFound)
+	_, err = m.PollCommands(ctx, "sc2", now)
+	assert.ErrorIs(t, err, ErrScooterNotFound)
+}
+
+// moveScooter reports the scooter's location now
+func moveScooter(t *testing.T, m *InMemoryRepository, scooterID string, location models.Location) {
+	recorded, err := m.RecordPositions(context.Background(), []models.LocationSample{{ScooterID: scooterID,
+		Time: time.Now().UTC(), Location: location}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, recorded)
+}
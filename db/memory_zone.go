@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"scootin/models"
+	"scootin/zone"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateZone ...
+func (m *InMemoryRepository) CreateZone(ctx context.Context, z *models.Zone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := copyZone(z)
+	m.zones = append(m.zones, &stored)
+	m.indexZones()
+	return nil
+}
+
+// UpdateZone ...
+func (m *InMemoryRepository) UpdateZone(ctx context.Context, z *models.Zone) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, stored := range m.zones {
+		if stored.ID == z.ID {
+			updated := copyZone(z)
+			updated.CreatedAt = stored.CreatedAt
+			m.zones[i] = &updated
+			m.indexZones()
+			return nil
+		}
+	}
+	return models.Errorf(ErrZoneNotFound, "zone %s", z.ID)
+}
+
+// DeleteZone ...
+func (m *InMemoryRepository) DeleteZone(ctx context.Context, zoneID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, stored := range m.zones {
+		if stored.ID == zoneID {
+			m.zones = append(m.zones[:i], m.zones[i+1:]...)
+			m.indexZones()
+			return nil
+		}
+	}
+	return models.Errorf(ErrZoneNotFound, "zone %s", zoneID)
+}
+
+// GetZone ...
+func (m *InMemoryRepository) GetZone(ctx context.Context, zoneID string) (*models.Zone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, stored := range m.zones {
+		if stored.ID == zoneID {
+			z := copyZone(stored)
+			return &z, nil
+		}
+	}
+	return nil, models.Errorf(ErrZoneNotFound, "zone %s", zoneID)
+}
+
+// ListZones ...
+func (m *InMemoryRepository) ListZones(ctx context.Context) ([]models.Zone, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	zones := make([]models.Zone, 0, len(m.zones))
+	for _, z := range m.zones {
+		zones = append(zones, copyZone(z))
+	}
+	return zones, nil
+}
+
+// ListZoneAlerts ...
+func (m *InMemoryRepository) ListZoneAlerts(ctx context.Context, from, to time.Time) ([]models.ZoneAlert, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	alerts := make([]models.ZoneAlert, 0)
+	for _, a := range m.zoneAlerts {
+		if !a.At.Before(from) && a.At.Before(to) {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+// indexZones rebuilds the zones' index after they've changed
+func (m *InMemoryRepository) indexZones() {
+	zones := make([]models.Zone, 0, len(m.zones))
+	for _, z := range m.zones {
+		zones = append(zones, *z)
+	}
+	m.zoneIndex = zone.NewIndex(zones)
+}
+
+// raiseZoneAlerts records an alert for every no-riding zone the scooter entered moving to the location
+func (m *InMemoryRepository) raiseZoneAlerts(sc *models.ScooterInfo, to models.Location, at time.Time) {
+	for _, z := range m.zoneIndex.Entered(sc.Location, to, models.ZoneNoRiding) {
+		m.zoneAlerts = append(m.zoneAlerts, models.ZoneAlert{ID: uuid.New().String(), ScooterID: sc.ID, UserID: sc.UserID,
+			ZoneID: z.ID, Tag: models.ZoneNoRiding, Location: to, At: at})
+	}
+}
+
+// copyZone returns a zone copy which doesn't share the tags nor the geometry
+func copyZone(z *models.Zone) models.Zone {
+	c := *z
+	c.Tags = append([]models.ZoneTag(nil), z.Tags...)
+	c.Geometry.Coordinates = append([]byte(nil), z.Geometry.Coordinates...)
+	return c
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migrationLockID is the postgres advisory lock key held while migrating,
+// so replicas starting at the same time apply the migrations only once.
+const migrationLockID = 7264118
+
+const migrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations
+(
+    version      INT           NOT NULL PRIMARY KEY,
+    name         TEXT          NOT NULL,
+    applied_at   TIMESTAMPTZ   NOT NULL DEFAULT now()
+);`
+
+// Migration is a versioned schema change and its rollback.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells whether a migration has been applied and when.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies and rolls back the migrations tracked in the schema_migrations table.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator for the service's schema migrations
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	if err := validateMigrations(migrations); err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// validateMigrations ensures the migrations are ordered by unique positive versions.
+func validateMigrations(ms []Migration) error {
+	for i, m := range ms {
+		if m.Version <= 0 {
+			return fmt.Errorf("migration %q has an invalid version %d", m.Name, m.Version)
+		}
+		if len(m.Up) == 0 || len(m.Down) == 0 {
+			return fmt.Errorf("migration %d %q must have both up and down statements", m.Version, m.Name)
+		}
+		if i > 0 && ms[i-1].Version >= m.Version {
+			return fmt.Errorf("migration %d %q is out of order", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies all the pending migrations, returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	applied := make([]Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := done[mig.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig.Up, "INSERT INTO schema_migrations(version,name) VALUES($1,$2)", mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("couldn't apply migration %d %q: %s", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the latest n applied migrations, returns the rolled back ones.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	rolledBack := make([]Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(rolledBack) < n; i-- {
+			mig := m.migrations[i]
+			if _, ok := done[mig.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, mig.Down, "DELETE FROM schema_migrations WHERE version = $1", mig.Version); err != nil {
+				return fmt.Errorf("couldn't roll back migration %d %q: %s", mig.Version, mig.Name, err)
+			}
+			rolledBack = append(rolledBack, mig)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Status lists every known migration with its applied state.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		status = make([]MigrationStatus, 0, len(m.migrations))
+		for _, mig := range m.migrations {
+			at, ok := done[mig.Version]
+			status = append(status, MigrationStatus{Migration: mig, Applied: ok, AppliedAt: at})
+		}
+		return nil
+	})
+	return status, err
+}
+
+// withLock runs f on a single connection holding the migrations advisory lock.
+func (m *Migrator) withLock(ctx context.Context, f func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("couldn't acquire the migrations lock: %s", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, migrationsTable); err != nil {
+		return fmt.Errorf("couldn't initate the migrations table: %s", err)
+	}
+	return f(conn)
+}
+
+// appliedVersions returns the applied migrations' versions with their applying time.
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var (
+			version int
+			at      time.Time
+		)
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		done[version] = at
+	}
+	return done, rows.Err()
+}
+
+// runMigration executes the schema statement and its tracking statement in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, statement, tracking string, args ...interface{}) error {
+	txn, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	if _, err := txn.ExecContext(ctx, statement); err != nil {
+		return err
+	}
+	if _, err := txn.ExecContext(ctx, tracking, args...); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
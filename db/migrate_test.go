@@ -0,0 +1,24 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrationsAreValid(t *testing.T) {
+	assert.NoError(t, validateMigrations(migrations))
+}
+
+func TestValidateMigrations(t *testing.T) {
+	assert.Error(t, validateMigrations([]Migration{{Version: 0, Name: "zero", Up: "up", Down: "down"}}))
+	assert.Error(t, validateMigrations([]Migration{{Version: 1, Name: "no_down", Up: "up"}}))
+	assert.Error(t, validateMigrations([]Migration{
+		{Version: 2, Name: "second", Up: "up", Down: "down"},
+		{Version: 1, Name: "first", Up: "up", Down: "down"},
+	}))
+	assert.Error(t, validateMigrations([]Migration{
+		{Version: 1, Name: "first", Up: "up", Down: "down"},
+		{Version: 1, Name: "duplicate", Up: "up", Down: "down"},
+	}))
+}
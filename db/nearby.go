@@ -0,0 +1,23 @@
+package db
+
+import (
+	"scootin/geo"
+	"scootin/models"
+	"sort"
+)
+
+// nearestScooters keeps the candidates within the radius in meters around the center,
+// returns up to limit of them sorted by distance, the nearest first.
+func nearestScooters(center models.Location, radius float64, limit int, candidates []models.ScooterInfo) []models.NearbyScooter {
+	nearby := make([]models.NearbyScooter, 0)
+	for _, sc := range candidates {
+		if d := geo.Distance(center, sc.Location); d <= radius {
+			nearby = append(nearby, models.NearbyScooter{ScooterInfo: sc, Distance: d})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool { return nearby[i].Distance < nearby[j].Distance })
+	if limit > 0 && len(nearby) > limit {
+		nearby = nearby[:limit]
+	}
+	return nearby
+}
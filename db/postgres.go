@@ -3,15 +3,25 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"scootin/geo"
+	"scootin/ledger"
 	"scootin/models"
+	"scootin/pricing"
+	"strings"
+	"sync"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 type PostgreRepository struct {
-	db *sql.DB
+	db                 *sql.DB
+	locationPartitions sync.Map // the location history partitions known to exist
+	zones              zoneCache
 }
 
 func NewPostgre(url string) (*PostgreRepository, error) {
@@ -19,14 +29,15 @@ func NewPostgre(url string) (*PostgreRepository, error) {
 	if err != nil {
 		return nil, err
 	}
-	if _, err := db.Exec(scooterTable); err != nil {
-		return nil, fmt.Errorf("couldn't initate the Scooter table: %s", err)
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		return nil, err
 	}
-	if _, err := db.Exec(userTable); err != nil {
-		return nil, fmt.Errorf("couldn't initate the User table: %s", err)
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return nil, fmt.Errorf("couldn't migrate the database: %s", err)
 	}
 	return &PostgreRepository{
-		db,
+		db: db,
 	}, nil
 }
 
@@ -34,8 +45,10 @@ func (p *PostgreRepository) Close() {
 	p.db.Close()
 }
 
-func (p *PostgreRepository) CreateScooter(ctx context.Context, scooterID string) error {
-	_, err := p.db.Exec("INSERT INTO scooters(id,coordinate,user_id) VALUES($1,$2,$3)", scooterID, 1, models.NotOccupied)
+func (p *PostgreRepository) CreateScooter(ctx context.Context, scooter *models.ScooterInfo) error {
+	_, err := p.db.Exec("INSERT INTO scooters(id,latitude,longitude,geohash,status,city,vehicle_type) VALUES($1,$2,$3,$4,$5,$6,$7)",
+		scooter.ID, scooter.Location.Latitude, scooter.Location.Longitude, geo.Encode(scooter.Location, geo.HashPrecision),
+		models.StatusAvailable, scooter.City, vehicleType(scooter.VehicleType))
 	return err
 }
 
@@ -45,49 +58,243 @@ func (p *PostgreRepository) CreateUser(ctx context.Context, user *models.User) e
 }
 
 // BookScooter ...
-func (p *PostgreRepository) BookScooter(ctx context.Context, ScooterID, userID string) error {
+func (p *PostgreRepository) BookScooter(ctx context.Context, ScooterID, userID string, surge float64, near *models.Proximity) (*models.Trip, error) {
 	var (
-		txn *sql.Tx
-		err error
-		//row        *sql.Row
+		txn    *sql.Tx
+		err    error
+		status models.ScooterStatus
 	)
-	// start the transaction
-	if txn, err = p.db.Begin(); err != nil {
-		return err
+	// start the transaction, it's rolled back unless committed
+	if txn, err = p.db.BeginTx(ctx, nil); err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if err = lockUser(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+
+	// lock the scooter row until the booking is done, so concurrent bookings of it wait for this one
+	trip := &models.Trip{ID: uuid.New().String(), ScooterID: ScooterID, UserID: userID, StartedAt: time.Now().UTC(), Surge: surge}
+	err = txn.QueryRowContext(ctx, "SELECT status, latitude, longitude FROM scooters WHERE id = $1 FOR UPDATE", ScooterID).
+		Scan(&status, &trip.StartLocation.Latitude, &trip.StartLocation.Longitude)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", ScooterID)
+	} else if err != nil {
+		return nil, err
+	}
+	if err = bookable(status, ScooterID, userID); err != nil {
+		return nil, err
+	}
+	// the rider may have walked off, or the scooter moved, since the handler checked
+	if err = nearby(near, ScooterID, trip.StartLocation); err != nil {
+		return nil, err
+	}
+	if err = userHolds(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+
+	if err = startTrip(ctx, txn, trip); err != nil {
+		return nil, err
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, constraintError(err, userID)
+	}
+	return trip, nil
+}
+
+// lockUser locks the user row until the transaction ends, so a user's bookings and reservations run one at a time
+func lockUser(ctx context.Context, txn *sql.Tx, userID string) error {
+	var id string
+	err := txn.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Errorf(ErrUserNotFound, "user %s", userID)
 	}
+	return err
+}
 
-	// checks whether the scooter is already occupied by a user
-	res, err := txn.Exec("Select user_id FROM scooters WHERE id = $1 ", ScooterID)
-	/*res.
-	if err := row.Scan(&occupation); err != nil {
+// userHolds checks the user holds no scooter yet, a user rides or reserves one scooter at a time
+func userHolds(ctx context.Context, txn *sql.Tx, userID string) error {
+	var trip, reservation bool
+	if err := txn.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM trips WHERE user_id = $1 AND ended_at IS NULL),
+	EXISTS(SELECT 1 FROM reservations WHERE user_id = $1 AND status = $2)`, userID, models.ReservationActive).Scan(&trip, &reservation); err != nil {
 		return err
 	}
-	if occupation != models.NotOccupied {
-		return errors.New(fmt.Sprintf("we can't book the scooter %s for user $s as it's already occupied by user %s", ScooterID, userID, occupation))
+	switch {
+	case trip:
+		return models.Errorf(ErrActiveTripExists, "user %s", userID)
+	case reservation:
+		return models.Errorf(ErrActiveReservationExists, "user %s", userID)
 	}
-	*/
-	// do the booking only if the scooter is not booked by another user
-	if res, err = txn.Exec("UPDATE scooters SET user_id = $1 Where id = $2 AND user_id = $3", userID, ScooterID, models.NotOccupied); err != nil {
+	return nil
+}
+
+// startTrip puts the trip's scooter in the trip and stores it,
+// the trips_one_active_per_user_idx index still refuses a second active trip of the user
+func startTrip(ctx context.Context, txn *sql.Tx, trip *models.Trip) error {
+	if _, err := txn.ExecContext(ctx, "UPDATE scooters SET status = $1, rider_id = $2 Where id = $3", models.StatusInTrip, trip.UserID, trip.ScooterID); err != nil {
 		return err
 	}
-	if rowsCountAffected, err := res.RowsAffected(); err != nil {
+	if _, err := txn.ExecContext(ctx, "INSERT INTO trips(id,scooter_id,user_id,started_at,start_latitude,start_longitude,surge) VALUES($1,$2,$3,$4,$5,$6,$7)",
+		trip.ID, trip.ScooterID, trip.UserID, trip.StartedAt, trip.StartLocation.Latitude, trip.StartLocation.Longitude, trip.Surge); err != nil {
+		return constraintError(err, trip.UserID)
+	}
+	return nil
+}
+
+// constraintError translates the trips' and reservations' constraints violations to their domain errors
+func constraintError(err error, userID string) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
 		return err
-	} else if rowsCountAffected == 0 {
-		return errors.New(fmt.Sprintf("we can't book the scooter %s for user %s as it's already occupied", ScooterID, userID))
 	}
-	return txn.Commit()
+	switch pqErr.Constraint {
+	case "trips_one_active_per_user_idx":
+		return models.Errorf(ErrActiveTripExists, "user %s", userID)
+	case "reservations_one_active_per_user_idx":
+		return models.Errorf(ErrActiveReservationExists, "user %s", userID)
+	case "trips_user_id_fkey", "reservations_user_id_fkey":
+		return models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	return err
 }
 
 // ReleaseScooter ...
-func (p *PostgreRepository) ReleaseScooter(ctx context.Context, userID string) error {
-	_, err := p.db.Exec("UPDATE scooters SET user_id = $1 Where user_id = $2", models.NotOccupied, userID)
-	return err
+func (p *PostgreRepository) ReleaseScooter(ctx context.Context, userID string) (*models.Trip, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	// lock the user's open trip and its scooter until the trip is ended
+	rows, err := txn.QueryContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE user_id = $1 AND ended_at IS NULL FOR UPDATE", userID)
+	if err != nil {
+		return nil, err
+	}
+	trips, err := extractTrips(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	} else if len(trips) == 0 {
+		return nil, models.Errorf(ErrNoActiveTrip, "user %s has no booked scooter to release", userID)
+	}
+	trip := &trips[0]
+	var (
+		endLocation       models.Location
+		city, vehicleType string
+	)
+	if err = txn.QueryRowContext(ctx, "SELECT latitude, longitude, city, vehicle_type FROM scooters WHERE id = $1 FOR UPDATE", trip.ScooterID).
+		Scan(&endLocation.Latitude, &endLocation.Longitude, &city, &vehicleType); err != nil {
+		return nil, err
+	}
+	index, err := p.zoneIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err = index.CanPark(endLocation); err != nil {
+		return nil, err
+	}
+
+	// the trip is priced by the plan in effect when it started, along the path the scooter reported
+	plan, err := pricePlanAt(ctx, txn, city, vehicleType, trip.StartedAt)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	path, err := tripPath(ctx, txn, trip, now)
+	if err != nil {
+		return nil, err
+	}
+	path = append(path, endLocation)
+	endedAt := now
+	trip.EndedAt, trip.EndLocation = &endedAt, &endLocation
+	trip.Fare = pricing.Calculate(plan, now.Sub(trip.StartedAt), geo.PathLength(path))
+	trip.Fare.City = city
+	pricing.ApplySurge(trip.Fare, trip.Surge)
+	if err = applyPass(ctx, txn, userID, trip.Fare, now); err != nil {
+		return nil, err
+	}
+	if err = applyBestPromo(ctx, txn, userID, city, trip.Fare, now); err != nil {
+		return nil, err
+	}
+	if err = applyStation(ctx, txn, trip.Fare, endLocation); err != nil {
+		return nil, err
+	}
+
+	// the fare is charged to the rider's wallet, a free trip posts nothing
+	if trip.Fare.Total > 0 {
+		if err = postLedger(ctx, txn, ledger.Fare(userID, trip.ID, trip.Fare)); err != nil {
+			return nil, err
+		}
+	}
+
+	// end the trip where the scooter is now
+	fare, err := json.Marshal(trip.Fare)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = txn.ExecContext(ctx, "UPDATE trips SET ended_at = $2, end_latitude = $3, end_longitude = $4, fare = $5 WHERE id = $1",
+		trip.ID, now, endLocation.Latitude, endLocation.Longitude, fare); err != nil {
+		return nil, err
+	}
+	if _, err = txn.ExecContext(ctx, "UPDATE scooters SET status = $1, rider_id = NULL Where id = $2 AND rider_id = $3 AND status = $4",
+		models.StatusAvailable, trip.ScooterID, userID, models.StatusInTrip); err != nil {
+		return nil, err
+	}
+	return trip, txn.Commit()
 }
 
-// UpdateScooterCoordinates ...
-func (p *PostgreRepository) UpdateScooterCoordinates(ctx context.Context, scooterID string, coordinates int64) error {
-	_, err := p.db.Exec("UPDATE scooters SET coordinate = $2 Where id = $1", scooterID, coordinates)
-	return err
+// tripPath returns the trip's start location followed by the locations its scooter reported until the end
+func tripPath(ctx context.Context, txn *sql.Tx, trip *models.Trip, end time.Time) ([]models.Location, error) {
+	rows, err := txn.QueryContext(ctx, `SELECT latitude, longitude FROM scooter_locations
+	WHERE scooter_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at`, trip.ScooterID, trip.StartedAt, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	path := []models.Location{trip.StartLocation}
+	for rows.Next() {
+		var l models.Location
+		if err := rows.Scan(&l.Latitude, &l.Longitude); err != nil {
+			return nil, err
+		}
+		path = append(path, l)
+	}
+	return path, rows.Err()
+}
+
+// GetTrip ...
+func (p *PostgreRepository) GetTrip(ctx context.Context, tripID string) (*models.Trip, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE id = $1", tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trips, err := extractTrips(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(trips) == 0 {
+		return nil, models.Errorf(ErrTripNotFound, "trip %s", tripID)
+	}
+	return &trips[0], nil
+}
+
+// ListUserTrips ...
+func (p *PostgreRepository) ListUserTrips(ctx context.Context, userID string) ([]models.Trip, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+
+	rows, err := p.db.QueryContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE user_id = $1 ORDER BY started_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractTrips(rows)
 }
 
 // ListAvailableScooter ...
@@ -96,18 +303,109 @@ func (p *PostgreRepository) ListAvailableScooter(ctx context.Context) ([]models.
 		rows *sql.Rows
 		err  error
 	)
-	if rows, err = p.db.Query("SELECT * FROM scooters Where user_id = $1", models.NotOccupied); err != nil {
+	if rows, err = p.db.Query("SELECT "+scooterColumns+" FROM scooters Where status = $1", models.StatusAvailable); err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractScooterInfo(rows)
+}
+
+// GetScooter ...
+func (p *PostgreRepository) GetScooter(ctx context.Context, scooterID string) (*models.ScooterInfo, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+scooterColumns+" FROM scooters WHERE id = $1", scooterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scooters, err := extractScooterInfo(rows)
+	if err != nil {
+		return nil, err
+	} else if len(scooters) == 0 {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	return &scooters[0], nil
+}
+
+// SetScooterStatus ...
+func (p *PostgreRepository) SetScooterStatus(ctx context.Context, scooterID string, status models.ScooterStatus) (*models.ScooterInfo, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	// lock the scooter row so its status can't change in between
+	rows, err := txn.QueryContext(ctx, "SELECT "+scooterColumns+" FROM scooters WHERE id = $1 FOR UPDATE", scooterID)
+	if err != nil {
+		return nil, err
+	}
+	scs, err := extractScooterInfo(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	} else if len(scs) == 0 {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	sc := scs[0]
+	if err = operationalTransition(sc.Status, status, scooterID); err != nil {
+		return nil, err
+	}
+
+	if _, err = txn.ExecContext(ctx, "UPDATE scooters SET status = $1 WHERE id = $2", status, scooterID); err != nil {
+		return nil, err
+	}
+	sc.Status = status
+	return &sc, txn.Commit()
+}
+
+// ListAvailableScooterInZone ...
+func (p *PostgreRepository) ListAvailableScooterInZone(ctx context.Context, zone string) ([]models.ScooterInfo, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+scooterColumns+" FROM scooters WHERE status = $1 AND geohash LIKE $2",
+		models.StatusAvailable, zone+"%")
+	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	return extractScooterInfo(rows)
 }
 
+// ListAvailableScooterNear ...
+func (p *PostgreRepository) ListAvailableScooterNear(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyScooter, error) {
+	// only the scooters within the geohash cells covering the radius are candidates
+	within, args := geohashWithin(center, radius, []interface{}{models.StatusAvailable})
+	rows, err := p.db.QueryContext(ctx, "SELECT "+scooterColumns+" FROM scooters Where status = $1 AND "+within, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	candidates, err := extractScooterInfo(rows)
+	if err != nil {
+		return nil, err
+	}
+	return nearestScooters(center, radius, limit, candidates), nil
+}
+
+// geohashWithin returns the condition matching the geohashes of the cells covering the radius in meters around the center,
+// its arguments are appended to args.
+func geohashWithin(center models.Location, radius float64, args []interface{}) (string, []interface{}) {
+	cells := geo.CoveringHashes(center, radius)
+	conditions := make([]string, 0, len(cells))
+	for _, cell := range cells {
+		args = append(args, cell+"%")
+		conditions = append(conditions, fmt.Sprintf("geohash LIKE $%d", len(args)))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+const scooterColumns = "id,latitude,longitude,status,COALESCE(rider_id,''),city,vehicle_type"
+
 func extractScooterInfo(rows *sql.Rows) ([]models.ScooterInfo, error) {
 	infx := make([]models.ScooterInfo, 0)
 	for rows.Next() {
 		info := models.ScooterInfo{}
-		if err := rows.Scan(&info.ID, &info.Coordination, &info.UserID); err != nil {
+		if err := rows.Scan(&info.ID, &info.Location.Latitude, &info.Location.Longitude, &info.Status, &info.UserID, &info.City, &info.VehicleType); err != nil {
 			return nil, err
 		}
 		infx = append(infx, info)
@@ -117,3 +415,37 @@ func extractScooterInfo(rows *sql.Rows) ([]models.ScooterInfo, error) {
 	}
 	return infx, nil
 }
+
+const tripColumns = "id,scooter_id,user_id,started_at,ended_at,start_latitude,start_longitude,end_latitude,end_longitude,surge,fare"
+
+func extractTrips(rows *sql.Rows) ([]models.Trip, error) {
+	trips := make([]models.Trip, 0)
+	for rows.Next() {
+		var (
+			trip           models.Trip
+			endedAt        sql.NullTime
+			endLat, endLon sql.NullFloat64
+			fare           []byte
+		)
+		if err := rows.Scan(&trip.ID, &trip.ScooterID, &trip.UserID, &trip.StartedAt, &endedAt,
+			&trip.StartLocation.Latitude, &trip.StartLocation.Longitude, &endLat, &endLon, &trip.Surge, &fare); err != nil {
+			return nil, err
+		}
+		if fare != nil {
+			if err := json.Unmarshal(fare, &trip.Fare); err != nil {
+				return nil, fmt.Errorf("invalid fare of trip %s: %s", trip.ID, err)
+			}
+		}
+		if endedAt.Valid {
+			trip.EndedAt = &endedAt.Time
+		}
+		if endLat.Valid && endLon.Valid {
+			trip.EndLocation = &models.Location{Latitude: endLat.Float64, Longitude: endLon.Float64}
+		}
+		trips = append(trips, trip)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return trips, nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/ledger"
+	"scootin/models"
+
+	"github.com/lib/pq"
+)
+
+// CreateCheckout ...
+func (p *PostgreRepository) CreateCheckout(ctx context.Context, c *models.Checkout) error {
+	// the checkouts_one_per_trip_idx index refuses a second pending or captured checkout of the trip
+	_, err := p.db.ExecContext(ctx, `INSERT INTO checkouts(id,trip_id,user_id,merchant_code,amount,currency,status,created_at)
+	VALUES($1,$2,$3,$4,$5,$6,$7,$8)`, c.ID, c.TripID, c.UserID, c.MerchantCode, c.Amount, c.Currency, c.Status, c.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "checkouts_one_per_trip_idx" {
+		return models.Errorf(ErrTripAlreadyPaid, "trip %s", c.TripID)
+	}
+	return err
+}
+
+// UpdateCheckout ...
+func (p *PostgreRepository) UpdateCheckout(ctx context.Context, c *models.Checkout) error {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	res, err := txn.ExecContext(ctx, "UPDATE checkouts SET status = $2, provider_reference = $3, failure_code = $4, completed_at = $5 WHERE id = $1",
+		c.ID, c.Status, c.ProviderReference, c.FailureCode, c.CompletedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.Errorf(ErrCheckoutNotFound, "checkout %s", c.ID)
+	}
+	// the captured payment is credited to the rider's wallet
+	if c.Status == models.CheckoutCaptured {
+		if err := postLedger(ctx, txn, ledger.Payment(c)); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// GetCheckout ...
+func (p *PostgreRepository) GetCheckout(ctx context.Context, checkoutID string) (*models.Checkout, error) {
+	c, err := scanCheckout(p.db.QueryRowContext(ctx, "SELECT "+checkoutColumns+" FROM checkouts WHERE id = $1", checkoutID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrCheckoutNotFound, "checkout %s", checkoutID)
+	}
+	return c, err
+}
+
+// CapturedCheckout ...
+func (p *PostgreRepository) CapturedCheckout(ctx context.Context, tripID string) (*models.Checkout, error) {
+	c, err := scanCheckout(p.db.QueryRowContext(ctx, "SELECT "+checkoutColumns+" FROM checkouts WHERE trip_id = $1 AND status = $2",
+		tripID, models.CheckoutCaptured))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrCheckoutNotFound, "trip %s has no captured checkout", tripID)
+	}
+	return c, err
+}
+
+const checkoutColumns = "id,trip_id,user_id,merchant_code,amount,currency,status,provider_reference,failure_code,created_at,completed_at"
+
+// scanCheckout scans the checkoutColumns row
+func scanCheckout(row *sql.Row) (*models.Checkout, error) {
+	var (
+		c           models.Checkout
+		completedAt sql.NullTime
+	)
+	if err := row.Scan(&c.ID, &c.TripID, &c.UserID, &c.MerchantCode, &c.Amount, &c.Currency, &c.Status,
+		&c.ProviderReference, &c.FailureCode, &c.CreatedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		c.CompletedAt = &completedAt.Time
+	}
+	return &c, nil
+}
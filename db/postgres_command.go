@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const commandColumns = "id,scooter_id,kind,speed_limit,state,reason,created_at,expires_at,delivered_at,ended_at"
+
+// pendingCommandStates are the states of the commands waiting for the scooter's acknowledgment
+var pendingCommandStates = pq.Array([]string{string(models.CommandQueued), string(models.CommandDelivered)})
+
+// CreateCommand ...
+func (p *PostgreRepository) CreateCommand(ctx context.Context, c *models.Command) error {
+	_, err := p.db.ExecContext(ctx, "INSERT INTO commands("+commandColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)",
+		c.ID, c.ScooterID, c.Kind, c.SpeedLimit, c.State, c.Reason, c.CreatedAt, c.ExpiresAt, c.DeliveredAt, c.EndedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "commands_scooter_id_fkey" {
+		return models.Errorf(ErrScooterNotFound, "scooter %s", c.ScooterID)
+	}
+	return err
+}
+
+// GetCommand ...
+func (p *PostgreRepository) GetCommand(ctx context.Context, commandID string) (*models.Command, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+commandColumns+" FROM commands WHERE id = $1", commandID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands, err := extractCommands(rows)
+	if err != nil {
+		return nil, err
+	} else if len(commands) == 0 {
+		return nil, models.Errorf(ErrCommandNotFound, "command %s", commandID)
+	}
+	return &commands[0], nil
+}
+
+// ListCommands ...
+func (p *PostgreRepository) ListCommands(ctx context.Context, scooterID string) ([]models.Command, error) {
+	if err := scooterExists(ctx, p.db, scooterID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, "SELECT "+commandColumns+" FROM commands WHERE scooter_id = $1 ORDER BY created_at, id", scooterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractCommands(rows)
+}
+
+// PollCommands ...
+func (p *PostgreRepository) PollCommands(ctx context.Context, scooterID string, now time.Time) ([]models.Command, error) {
+	if err := scooterExists(ctx, p.db, scooterID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `WITH delivered AS (UPDATE commands SET state = $1, delivered_at = $2
+	WHERE scooter_id = $3 AND state = ANY($4) AND expires_at > $2 RETURNING `+commandColumns+`)
+	SELECT `+commandColumns+` FROM delivered ORDER BY created_at, id`, models.CommandDelivered, now, scooterID, pendingCommandStates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractCommands(rows)
+}
+
+// AckCommand ...
+func (p *PostgreRepository) AckCommand(ctx context.Context, scooterID, commandID string, ack models.CommandAck, now time.Time) (*models.Command, error) {
+	rows, err := p.db.QueryContext(ctx, `UPDATE commands SET state = $1, reason = $2, ended_at = $3
+	WHERE id = $4 AND scooter_id = $5 AND state = $6 RETURNING `+commandColumns,
+		ack.State, ack.Reason, now, commandID, scooterID, models.CommandDelivered)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	commands, err := extractCommands(rows)
+	if err != nil {
+		return nil, err
+	} else if len(commands) == 1 {
+		return &commands[0], nil
+	}
+
+	// tells an unknown command from one not waiting for its acknowledgment
+	var state models.CommandState
+	err = p.db.QueryRowContext(ctx, "SELECT state FROM commands WHERE id = $1 AND scooter_id = $2", commandID, scooterID).Scan(&state)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrCommandNotFound, "command %s", commandID)
+	} else if err != nil {
+		return nil, err
+	}
+	return nil, models.Errorf(ErrCommandNotDelivered, "command %s is %s", commandID, state)
+}
+
+// ExpireCommands ...
+func (p *PostgreRepository) ExpireCommands(ctx context.Context, now time.Time) (int, error) {
+	res, err := p.db.ExecContext(ctx, `UPDATE commands SET state = $1, reason = $2, ended_at = $3
+	WHERE state = ANY($4) AND expires_at <= $3`, models.CommandFailed, commandExpiredReason, now, pendingCommandStates)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// scooterExists returns ErrScooterNotFound unless the scooter exists
+func scooterExists(ctx context.Context, q rowQuerier, scooterID string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM scooters WHERE id = $1)", scooterID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	}
+	return nil
+}
+
+func extractCommands(rows *sql.Rows) ([]models.Command, error) {
+	commands := make([]models.Command, 0)
+	for rows.Next() {
+		var (
+			c                    models.Command
+			deliveredAt, endedAt sql.NullTime
+		)
+		if err := rows.Scan(&c.ID, &c.ScooterID, &c.Kind, &c.SpeedLimit, &c.State, &c.Reason, &c.CreatedAt, &c.ExpiresAt,
+			&deliveredAt, &endedAt); err != nil {
+			return nil, err
+		}
+		if deliveredAt.Valid {
+			c.DeliveredAt = &deliveredAt.Time
+		}
+		if endedAt.Valid {
+			c.EndedAt = &endedAt.Time
+		}
+		commands = append(commands, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return commands, nil
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/models"
+
+	"github.com/lib/pq"
+)
+
+const disputeColumns = "id,trip_id,user_id,reason,status,refund_amount,currency,refund_reference,created_at,updated_at"
+
+// CreateDispute ...
+func (p *PostgreRepository) CreateDispute(ctx context.Context, d *models.Dispute) error {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	// the unique trip_id refuses a second dispute of the trip
+	_, err = txn.ExecContext(ctx, "INSERT INTO disputes("+disputeColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)",
+		d.ID, d.TripID, d.UserID, d.Reason, d.Status, d.RefundAmount, d.Currency, d.RefundReference, d.CreatedAt, d.UpdatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "disputes_trip_id_key" {
+		return models.Errorf(ErrDisputeExists, "trip %s", d.TripID)
+	} else if err != nil {
+		return err
+	}
+	for _, e := range d.Events {
+		if err := insertDisputeEvent(ctx, txn, d.ID, e); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// insertDisputeEvent appends the event to the dispute's audit trail
+func insertDisputeEvent(ctx context.Context, txn *sql.Tx, disputeID string, e models.DisputeEvent) error {
+	_, err := txn.ExecContext(ctx, "INSERT INTO dispute_events(dispute_id,status,actor,note,at) VALUES($1,$2,$3,$4,$5)",
+		disputeID, e.Status, e.Actor, e.Note, e.At)
+	return err
+}
+
+// GetDispute ...
+func (p *PostgreRepository) GetDispute(ctx context.Context, disputeID string) (*models.Dispute, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE id = $1", disputeID)
+	if err != nil {
+		return nil, err
+	}
+	disputes, err := extractDisputes(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	} else if len(disputes) == 0 {
+		return nil, models.Errorf(ErrDisputeNotFound, "dispute %s", disputeID)
+	}
+	if err := p.disputeEvents(ctx, disputes); err != nil {
+		return nil, err
+	}
+	return &disputes[0], nil
+}
+
+// ListDisputes ...
+func (p *PostgreRepository) ListDisputes(ctx context.Context, status models.DisputeStatus) ([]models.Dispute, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+disputeColumns+" FROM disputes WHERE $1 = '' OR status = $1 ORDER BY created_at", status)
+	if err != nil {
+		return nil, err
+	}
+	disputes, err := extractDisputes(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	return disputes, p.disputeEvents(ctx, disputes)
+}
+
+// disputeEvents loads the disputes' audit trails
+func (p *PostgreRepository) disputeEvents(ctx context.Context, disputes []models.Dispute) error {
+	if len(disputes) == 0 {
+		return nil
+	}
+	ids := make([]string, len(disputes))
+	index := make(map[string]int, len(disputes))
+	for i := range disputes {
+		ids[i], index[disputes[i].ID] = disputes[i].ID, i
+		disputes[i].Events = make([]models.DisputeEvent, 0)
+	}
+	rows, err := p.db.QueryContext(ctx, "SELECT dispute_id,status,actor,note,at FROM dispute_events WHERE dispute_id = ANY($1) ORDER BY id",
+		pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			disputeID string
+			e         models.DisputeEvent
+		)
+		if err := rows.Scan(&disputeID, &e.Status, &e.Actor, &e.Note, &e.At); err != nil {
+			return err
+		}
+		d := &disputes[index[disputeID]]
+		d.Events = append(d.Events, e)
+	}
+	return rows.Err()
+}
+
+// UpdateDispute ...
+func (p *PostgreRepository) UpdateDispute(ctx context.Context, d *models.Dispute, event models.DisputeEvent, refund *models.LedgerTransaction) error {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	// lock the dispute until it's moved, a concurrent decision waits then sees the outcome
+	var status models.DisputeStatus
+	err = txn.QueryRowContext(ctx, "SELECT status FROM disputes WHERE id = $1 FOR UPDATE", d.ID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Errorf(ErrDisputeNotFound, "dispute %s", d.ID)
+	} else if err != nil {
+		return err
+	} else if !status.CanTransitionTo(event.Status) {
+		return models.Errorf(ErrInvalidDisputeStatus, "dispute %s can't move from %s to %s", d.ID, status, event.Status)
+	}
+
+	if _, err = txn.ExecContext(ctx, `UPDATE disputes SET status = $2, refund_amount = $3, currency = $4, refund_reference = $5, updated_at = $6
+	WHERE id = $1`, d.ID, event.Status, d.RefundAmount, d.Currency, d.RefundReference, event.At); err != nil {
+		return err
+	}
+	if err = insertDisputeEvent(ctx, txn, d.ID, event); err != nil {
+		return err
+	}
+	if refund != nil {
+		if err = postLedger(ctx, txn, refund); err != nil {
+			return err
+		}
+	}
+	if err = txn.Commit(); err != nil {
+		return err
+	}
+	d.Status, d.UpdatedAt = event.Status, event.At
+	d.Events = append(d.Events, event)
+	return nil
+}
+
+func extractDisputes(rows *sql.Rows) ([]models.Dispute, error) {
+	disputes := make([]models.Dispute, 0)
+	for rows.Next() {
+		var d models.Dispute
+		if err := rows.Scan(&d.ID, &d.TripID, &d.UserID, &d.Reason, &d.Status, &d.RefundAmount, &d.Currency, &d.RefundReference,
+			&d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		disputes = append(disputes, d)
+	}
+	return disputes, rows.Err()
+}
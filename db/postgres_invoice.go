@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"scootin/invoice"
+	"scootin/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const invoiceColumns = "id,number,sequence,legal_entity,entity_name,vat_id,user_id,period,currency,lines,net,vat,total,issued_at"
+
+// SetTaxRule ...
+func (p *PostgreRepository) SetTaxRule(ctx context.Context, rule *models.TaxRule) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO tax_rules(city,legal_entity,entity_name,vat_id,vat_rate) VALUES($1,$2,$3,$4,$5)
+	ON CONFLICT (city) DO UPDATE SET legal_entity = EXCLUDED.legal_entity, entity_name = EXCLUDED.entity_name,
+	vat_id = EXCLUDED.vat_id, vat_rate = EXCLUDED.vat_rate`, rule.City, rule.LegalEntity, rule.EntityName, rule.VATID, rule.VATRate)
+	return err
+}
+
+// ListTaxRules ...
+func (p *PostgreRepository) ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT city,legal_entity,entity_name,vat_id,vat_rate FROM tax_rules ORDER BY city")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := make([]models.TaxRule, 0)
+	for rows.Next() {
+		var r models.TaxRule
+		if err := rows.Scan(&r.City, &r.LegalEntity, &r.EntityName, &r.VATID, &r.VATRate); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+// ListCharges ...
+func (p *PostgreRepository) ListCharges(ctx context.Context, from, to time.Time) ([]models.Charge, error) {
+	// the passes' charges are their payments earned in the ledger, the disputes' refunds are taken off the revenue
+	// in their trip's city
+	rows, err := p.db.QueryContext(ctx, `SELECT user_id, $3::TEXT, id, COALESCE(fare->>'City', ''), (fare->>'Total')::BIGINT, fare->>'Currency', ended_at
+	FROM trips WHERE ended_at >= $1 AND ended_at < $2 AND (fare->>'Total')::BIGINT > 0
+	UNION ALL
+	SELECT t.user_id, $4::TEXT, t.reference, '', e.amount, t.currency, t.created_at
+	FROM ledger_transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+	WHERE t.kind = $5 AND e.account = $6 AND t.created_at >= $1 AND t.created_at < $2
+	UNION ALL
+	SELECT t.user_id, $7::TEXT, t.reference, COALESCE(tr.fare->>'City', ''), e.amount, t.currency, t.created_at
+	FROM ledger_transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+	LEFT JOIN disputes d ON d.id = t.reference LEFT JOIN trips tr ON tr.id = d.trip_id
+	WHERE t.kind = $8 AND e.account = $6 AND t.created_at >= $1 AND t.created_at < $2
+	ORDER BY 7`, from, to, models.ChargeTrip, models.ChargePass, models.LedgerPass, models.AccountRevenue,
+		models.ChargeRefund, models.LedgerRefund)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	charges := make([]models.Charge, 0)
+	for rows.Next() {
+		var c models.Charge
+		if err := rows.Scan(&c.UserID, &c.Kind, &c.Reference, &c.City, &c.Amount, &c.Currency, &c.At); err != nil {
+			return nil, err
+		}
+		charges = append(charges, c)
+	}
+	return charges, rows.Err()
+}
+
+// CreateInvoice ...
+func (p *PostgreRepository) CreateInvoice(ctx context.Context, inv *models.Invoice) error {
+	lines, err := json.Marshal(inv.Lines)
+	if err != nil {
+		return err
+	}
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	// lock the legal entity's sequence so its invoices are numbered one at a time, a rolled back invoice leaves no gap
+	if _, err = txn.ExecContext(ctx, "INSERT INTO invoice_sequences(legal_entity,last) VALUES($1,0) ON CONFLICT (legal_entity) DO NOTHING",
+		inv.LegalEntity); err != nil {
+		return err
+	}
+	var last int64
+	if err = txn.QueryRowContext(ctx, "SELECT last FROM invoice_sequences WHERE legal_entity = $1 FOR UPDATE", inv.LegalEntity).
+		Scan(&last); err != nil {
+		return err
+	}
+	var number string
+	err = txn.QueryRowContext(ctx, "SELECT number FROM invoices WHERE user_id = $1 AND period = $2 AND legal_entity = $3 AND currency = $4",
+		inv.UserID, inv.Period, inv.LegalEntity, inv.Currency).Scan(&number)
+	if err == nil {
+		return models.Errorf(ErrInvoiceExists, "invoice %s of user %s for %s", number, inv.UserID, inv.Period)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	inv.Sequence = last + 1
+	inv.Number = invoice.Number(inv.LegalEntity, inv.Sequence)
+	_, err = txn.ExecContext(ctx, "INSERT INTO invoices("+invoiceColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14)",
+		inv.ID, inv.Number, inv.Sequence, inv.LegalEntity, inv.EntityName, inv.VATID, inv.UserID, inv.Period, inv.Currency, lines,
+		inv.Net, inv.VAT, inv.Total, inv.IssuedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "invoices_user_id_fkey" {
+		return models.Errorf(ErrUserNotFound, "user %s", inv.UserID)
+	} else if err != nil {
+		return err
+	}
+	if _, err = txn.ExecContext(ctx, "UPDATE invoice_sequences SET last = $2 WHERE legal_entity = $1", inv.LegalEntity, inv.Sequence); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// GetInvoice ...
+func (p *PostgreRepository) GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	inv, err := scanInvoice(p.db.QueryRowContext(ctx, "SELECT "+invoiceColumns+" FROM invoices WHERE id = $1", invoiceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrInvoiceNotFound, "invoice %s", invoiceID)
+	}
+	return inv, err
+}
+
+// ListUserInvoices ...
+func (p *PostgreRepository) ListUserInvoices(ctx context.Context, userID string) ([]models.Invoice, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, "SELECT "+invoiceColumns+" FROM invoices WHERE user_id = $1 ORDER BY issued_at, number", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := make([]models.Invoice, 0)
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, *inv)
+	}
+	return invoices, rows.Err()
+}
+
+// scanInvoice scans the invoiceColumns row
+func scanInvoice(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.Invoice, error) {
+	var (
+		inv   models.Invoice
+		lines []byte
+	)
+	if err := row.Scan(&inv.ID, &inv.Number, &inv.Sequence, &inv.LegalEntity, &inv.EntityName, &inv.VATID, &inv.UserID, &inv.Period,
+		&inv.Currency, &lines, &inv.Net, &inv.VAT, &inv.Total, &inv.IssuedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(lines, &inv.Lines); err != nil {
+		return nil, fmt.Errorf("invalid lines of invoice %s: %s", inv.ID, err)
+	}
+	return &inv, nil
+}
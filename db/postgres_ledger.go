@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"scootin/ledger"
+	"scootin/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PostLedgerTransaction ...
+func (p *PostgreRepository) PostLedgerTransaction(ctx context.Context, tx *models.LedgerTransaction) error {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	if err := postLedger(ctx, txn, tx); err != nil {
+		return err
+	}
+	return txn.Commit()
+}
+
+// postLedger inserts the transaction with its entries within txn unless its kind and reference are already posted,
+// the ledger tables refuse any update or delete.
+func postLedger(ctx context.Context, txn *sql.Tx, tx *models.LedgerTransaction) error {
+	if !ledger.Balanced(tx) {
+		return fmt.Errorf("couldn't post the unbalanced %s transaction %s", tx.Kind, tx.ID)
+	}
+	res, err := txn.ExecContext(ctx, `INSERT INTO ledger_transactions(id,kind,user_id,currency,reference,created_at)
+	VALUES($1,$2,$3,$4,$5,$6) ON CONFLICT (kind, reference) DO NOTHING`, tx.ID, tx.Kind, tx.UserID, tx.Currency, tx.Reference, tx.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "ledger_transactions_user_id_fkey" {
+		return models.Errorf(ErrUserNotFound, "user %s", tx.UserID)
+	} else if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	for i, e := range tx.Entries {
+		if _, err := txn.ExecContext(ctx, "INSERT INTO ledger_entries(transaction_id,position,account,amount) VALUES($1,$2,$3,$4)",
+			tx.ID, i, e.Account, e.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWallet ...
+func (p *PostgreRepository) GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `SELECT t.currency, SUM(e.amount) FROM ledger_entries e
+	JOIN ledger_transactions t ON t.id = e.transaction_id WHERE e.account = $1 GROUP BY t.currency ORDER BY t.currency`,
+		models.WalletAccount(userID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wallet := &models.Wallet{UserID: userID, Balances: make([]models.Money, 0)}
+	for rows.Next() {
+		var m models.Money
+		if err := rows.Scan(&m.Currency, &m.Amount); err != nil {
+			return nil, err
+		}
+		wallet.Balances = append(wallet.Balances, m)
+	}
+	return wallet, rows.Err()
+}
+
+// ListLedgerTransactions ...
+func (p *PostgreRepository) ListLedgerTransactions(ctx context.Context, userID string) ([]models.LedgerTransaction, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, `SELECT t.id, t.kind, t.user_id, t.currency, t.reference, t.created_at, e.account, e.amount
+	FROM ledger_transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+	WHERE t.user_id = $1 ORDER BY t.created_at, t.id, e.position`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanLedgerTransactions(rows)
+}
+
+// ListLedgerTransactionsBetween ...
+func (p *PostgreRepository) ListLedgerTransactionsBetween(ctx context.Context, from, to time.Time) ([]models.LedgerTransaction, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT t.id, t.kind, t.user_id, t.currency, t.reference, t.created_at, e.account, e.amount
+	FROM ledger_transactions t JOIN ledger_entries e ON e.transaction_id = t.id
+	WHERE t.created_at >= $1 AND t.created_at < $2 ORDER BY t.created_at, t.id, e.position`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanLedgerTransactions(rows)
+}
+
+// scanLedgerTransactions scans the transactions' entries rows ordered by transaction
+func scanLedgerTransactions(rows *sql.Rows) ([]models.LedgerTransaction, error) {
+	// the entries of a transaction are in consecutive rows
+	transactions := make([]models.LedgerTransaction, 0)
+	for rows.Next() {
+		var (
+			tx models.LedgerTransaction
+			e  models.LedgerEntry
+		)
+		if err := rows.Scan(&tx.ID, &tx.Kind, &tx.UserID, &tx.Currency, &tx.Reference, &tx.CreatedAt, &e.Account, &e.Amount); err != nil {
+			return nil, err
+		}
+		if n := len(transactions); n > 0 && transactions[n-1].ID == tx.ID {
+			transactions[n-1].Entries = append(transactions[n-1].Entries, e)
+			continue
+		}
+		tx.Entries = []models.LedgerEntry{e}
+		transactions = append(transactions, tx)
+	}
+	return transactions, rows.Err()
+}
+
+// userExists returns ErrUserNotFound unless the user exists
+func userExists(ctx context.Context, q rowQuerier, userID string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		return err
+	} else if !exists {
+		return models.Errorf(ErrUserNotFound, "user %s", userID)
+	}
+	return nil
+}
+
+// rowQuerier is a database or a transaction querying single rows
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
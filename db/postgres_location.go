@@ -0,0 +1,297 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"scootin/geo"
+	"scootin/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// the scooter_locations table is partitioned by day, each partition is named after its day
+const (
+	locationPartitionPrefix = "scooter_locations_"
+	locationPartitionLayout = "20060102"
+	// locationPartitionsAhead is the number of the coming days' partitions created by the maintenance
+	locationPartitionsAhead = 2
+	// locationPartitionLockID serializes the partitions' changes between replicas
+	locationPartitionLockID = 7264119
+)
+
+// recordLocation appends the scooter location to its history
+func (p *PostgreRepository) recordLocation(ctx context.Context, sample models.LocationSample) error {
+	if err := p.ensureLocationPartition(ctx, sample.Time); err != nil {
+		return err
+	}
+	return insertLocation(ctx, p.db, sample)
+}
+
+func insertLocation(ctx context.Context, q execer, sample models.LocationSample) error {
+	_, err := q.ExecContext(ctx, `INSERT INTO scooter_locations(scooter_id,recorded_at,latitude,longitude,speed,battery)
+	VALUES($1,$2,$3,$4,$5,$6)`, sample.ScooterID, sample.Time, sample.Location.Latitude, sample.Location.Longitude,
+		sample.Speed, sample.Battery)
+	return err
+}
+
+// ensureLocationPartition creates the partition holding the day of t unless it's known to exist
+func (p *PostgreRepository) ensureLocationPartition(ctx context.Context, t time.Time) error {
+	day := t.UTC().Truncate(24 * time.Hour)
+	name := locationPartitionPrefix + day.Format(locationPartitionLayout)
+	if _, ok := p.locationPartitions.Load(name); ok {
+		return nil
+	}
+
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	if _, err = txn.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", locationPartitionLockID); err != nil {
+		return err
+	}
+	if _, err = txn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF scooter_locations
+	FOR VALUES FROM ('%s') TO ('%s')`, name, day.Format(time.RFC3339), day.Add(24*time.Hour).Format(time.RFC3339))); err != nil {
+		return fmt.Errorf("couldn't create the location partition %s: %s", name, err)
+	}
+	if _, err = txn.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s_scooter_idx ON %s(scooter_id, recorded_at)", name, name)); err != nil {
+		return fmt.Errorf("couldn't index the location partition %s: %s", name, err)
+	}
+	if err = txn.Commit(); err != nil {
+		return err
+	}
+	p.locationPartitions.Store(name, true)
+	return nil
+}
+
+// ScooterTrack ...
+func (p *PostgreRepository) ScooterTrack(ctx context.Context, scooterID string, from, to time.Time) ([]models.LocationSample, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT scooter_id, recorded_at, latitude, longitude, speed, battery FROM scooter_locations
+	WHERE scooter_id = $1 AND recorded_at >= $2 AND recorded_at < $3 ORDER BY recorded_at`, scooterID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	track := make([]models.LocationSample, 0)
+	for rows.Next() {
+		var sample models.LocationSample
+		if err := rows.Scan(&sample.ScooterID, &sample.Time, &sample.Location.Latitude, &sample.Location.Longitude,
+			&sample.Speed, &sample.Battery); err != nil {
+			return nil, err
+		}
+		track = append(track, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return track, nil
+}
+
+// RecordTelemetry ...
+func (p *PostgreRepository) RecordTelemetry(ctx context.Context, scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error) {
+	// the partitions are created out of the transaction, they're shared with the other scooters
+	for _, s := range samples {
+		if err := p.ensureLocationPartition(ctx, s.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	var (
+		location models.Location
+		userID   string
+		last     sql.NullTime
+	)
+	// the scooter's row serializes its reports, a retried batch sees the samples recorded by the first try
+	err = txn.QueryRowContext(ctx, `SELECT latitude, longitude, COALESCE(rider_id, ''), reported_at FROM scooters
+	WHERE id = $1 FOR UPDATE`, scooterID).Scan(&location.Latitude, &location.Longitude, &userID, &last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	receipt := &models.TelemetryReceipt{}
+	for _, s := range samples {
+		if last.Valid && !s.Time.After(last.Time) {
+			receipt.Duplicates++
+			continue
+		}
+		if err := p.raiseZoneAlerts(ctx, txn, scooterID, userID, location, s.Location, s.Time); err != nil {
+			return nil, err
+		}
+		if err := insertLocation(ctx, txn, models.LocationSample{ScooterID: scooterID, Time: s.Time, Location: s.Location,
+			Speed: s.Speed, Battery: s.Battery}); err != nil {
+			return nil, err
+		}
+		location, last = s.Location, sql.NullTime{Time: s.Time, Valid: true}
+		receipt.Accepted++
+	}
+	if receipt.Accepted == 0 {
+		return receipt, nil
+	}
+
+	if _, err = txn.ExecContext(ctx, `UPDATE scooters SET latitude = $2, longitude = $3, geohash = $4, reported_at = $5
+	WHERE id = $1`, scooterID, location.Latitude, location.Longitude, geo.Encode(location, geo.HashPrecision), last.Time); err != nil {
+		return nil, err
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+// RecordPositions ...
+func (p *PostgreRepository) RecordPositions(ctx context.Context, samples []models.LocationSample) (int, error) {
+	if len(samples) == 0 {
+		return 0, nil
+	}
+	ids := make([]string, 0, len(samples))
+	for _, s := range samples {
+		if err := p.ensureLocationPartition(ctx, s.Time); err != nil {
+			return 0, err
+		}
+		ids = append(ids, s.ScooterID)
+	}
+
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	// the rows are locked in the ids' order, so the batches don't deadlock with each other
+	type reported struct {
+		location models.Location
+		userID   string
+		at       sql.NullTime
+		moved    bool // by one of the samples
+	}
+	rows, err := txn.QueryContext(ctx, `SELECT id, latitude, longitude, COALESCE(rider_id, ''), reported_at FROM scooters
+	WHERE id = ANY($1) ORDER BY id FOR UPDATE`, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	last := make(map[string]reported, len(ids))
+	for rows.Next() {
+		var (
+			id string
+			r  reported
+		)
+		if err := rows.Scan(&id, &r.location.Latitude, &r.location.Longitude, &r.userID, &r.at); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		last[id] = r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	// every sample joins the history in its time order, the scooters' rows are moved once to their latest
+	samples = append([]models.LocationSample(nil), samples...)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Time.Before(samples[j].Time) })
+	var (
+		inserts, updates       []string
+		insertArgs, updateArgs []interface{}
+		moved                  []string // the scooters' ids in their first recorded sample's order
+	)
+	for _, s := range samples {
+		r, ok := last[s.ScooterID]
+		if !ok || (r.at.Valid && !s.Time.After(r.at.Time)) {
+			continue
+		}
+		if err := p.raiseZoneAlerts(ctx, txn, s.ScooterID, r.userID, r.location, s.Location, s.Time); err != nil {
+			return 0, err
+		}
+		n := len(insertArgs)
+		inserts = append(inserts, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d)", n+1, n+2, n+3, n+4, n+5, n+6))
+		insertArgs = append(insertArgs, s.ScooterID, s.Time, s.Location.Latitude, s.Location.Longitude, s.Speed, s.Battery)
+		if !r.moved {
+			moved = append(moved, s.ScooterID)
+		}
+		last[s.ScooterID] = reported{location: s.Location, userID: r.userID, at: sql.NullTime{Time: s.Time, Valid: true}, moved: true}
+	}
+	for _, id := range moved {
+		r := last[id]
+		n := len(updateArgs)
+		updates = append(updates, fmt.Sprintf("($%d::TEXT,$%d::DOUBLE PRECISION,$%d::DOUBLE PRECISION,$%d::TEXT,$%d::TIMESTAMPTZ)",
+			n+1, n+2, n+3, n+4, n+5))
+		updateArgs = append(updateArgs, id, r.location.Latitude, r.location.Longitude,
+			geo.Encode(r.location, geo.HashPrecision), r.at.Time)
+	}
+	if len(inserts) == 0 {
+		return 0, nil
+	}
+
+	if _, err = txn.ExecContext(ctx, `INSERT INTO scooter_locations(scooter_id,recorded_at,latitude,longitude,speed,battery)
+	VALUES `+strings.Join(inserts, ","), insertArgs...); err != nil {
+		return 0, err
+	}
+	if _, err = txn.ExecContext(ctx, `UPDATE scooters s SET latitude = v.latitude, longitude = v.longitude, geohash = v.geohash,
+	reported_at = v.reported_at FROM (VALUES `+strings.Join(updates, ",")+`) AS v(id, latitude, longitude, geohash, reported_at)
+	WHERE s.id = v.id`, updateArgs...); err != nil {
+		return 0, err
+	}
+	if err = txn.Commit(); err != nil {
+		return 0, err
+	}
+	return len(inserts), nil
+}
+
+// MaintainLocationHistory creates the coming days' partitions and drops the ones older than the retention
+func (p *PostgreRepository) MaintainLocationHistory(ctx context.Context, now time.Time, retention time.Duration) error {
+	for i := 0; i <= locationPartitionsAhead; i++ {
+		if err := p.ensureLocationPartition(ctx, now.Add(time.Duration(i)*24*time.Hour)); err != nil {
+			return err
+		}
+	}
+
+	rows, err := p.db.QueryContext(ctx, `SELECT c.relname FROM pg_inherits i
+	JOIN pg_class c ON c.oid = i.inhrelid JOIN pg_class parent ON parent.oid = i.inhparent
+	WHERE parent.relname = 'scooter_locations'`)
+	if err != nil {
+		return err
+	}
+	expired := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		day, err := time.Parse(locationPartitionLayout, strings.TrimPrefix(name, locationPartitionPrefix))
+		if err != nil {
+			continue // not one of ours
+		}
+		// a partition is dropped once its whole day is out of the retention
+		if day.Add(24 * time.Hour).Before(now.Add(-retention)) {
+			expired = append(expired, name)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, name := range expired {
+		if _, err := p.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", name)); err != nil {
+			return fmt.Errorf("couldn't drop the location partition %s: %s", name, err)
+		}
+		p.locationPartitions.Delete(name)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/models"
+	"scootin/pricing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	passPlanColumns = "id,name,period,price,currency,free_unlocks,free_minutes,created_at"
+	passColumns     = "id,plan_id,user_id,starts_at,ends_at,auto_renew,renewal_of,payment_reference,created_at"
+)
+
+// CreatePassPlan ...
+func (p *PostgreRepository) CreatePassPlan(ctx context.Context, plan *models.PassPlan) error {
+	_, err := p.db.ExecContext(ctx, "INSERT INTO pass_plans("+passPlanColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8)",
+		plan.ID, plan.Name, plan.Period, plan.Price, plan.Currency, plan.FreeUnlocks, plan.FreeMinutes, plan.CreatedAt)
+	return err
+}
+
+// ListPassPlans ...
+func (p *PostgreRepository) ListPassPlans(ctx context.Context) ([]models.PassPlan, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+passPlanColumns+" FROM pass_plans ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans := make([]models.PassPlan, 0)
+	for rows.Next() {
+		plan, err := scanPassPlan(rows)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, *plan)
+	}
+	return plans, rows.Err()
+}
+
+// GetPassPlan ...
+func (p *PostgreRepository) GetPassPlan(ctx context.Context, planID string) (*models.PassPlan, error) {
+	plan, err := scanPassPlan(p.db.QueryRowContext(ctx, "SELECT "+passPlanColumns+" FROM pass_plans WHERE id = $1", planID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrPassPlanNotFound, "pass plan %s", planID)
+	}
+	return plan, err
+}
+
+// CreatePass ...
+func (p *PostgreRepository) CreatePass(ctx context.Context, pass *models.Pass, payment *models.LedgerTransaction) error {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer txn.Rollback()
+
+	res, err := txn.ExecContext(ctx, "INSERT INTO passes("+passColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT (renewal_of) DO NOTHING",
+		pass.ID, pass.PlanID, pass.UserID, pass.StartsAt, pass.EndsAt, pass.AutoRenew, sql.NullString{String: pass.RenewalOf, Valid: len(pass.RenewalOf) > 0},
+		pass.PaymentReference, pass.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "passes_user_id_fkey" {
+		return models.Errorf(ErrUserNotFound, "user %s", pass.UserID)
+	} else if errors.As(err, &pqErr) && pqErr.Constraint == "passes_plan_id_fkey" {
+		return models.Errorf(ErrPassPlanNotFound, "pass plan %s", pass.PlanID)
+	} else if err != nil {
+		return err
+	}
+	// the pass is already renewed
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+	if payment != nil {
+		if err = postLedger(ctx, txn, payment); err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
+// ListRenewablePasses ...
+func (p *PostgreRepository) ListRenewablePasses(ctx context.Context, before time.Time) ([]models.Pass, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT `+passColumns+` FROM passes p
+	WHERE auto_renew AND ends_at < $1 AND NOT EXISTS(SELECT 1 FROM passes r WHERE r.renewal_of = p.id) ORDER BY ends_at`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	passes := make([]models.Pass, 0)
+	for rows.Next() {
+		pass, err := scanPass(rows)
+		if err != nil {
+			return nil, err
+		}
+		passes = append(passes, *pass)
+	}
+	return passes, rows.Err()
+}
+
+// StopPassRenewal ...
+func (p *PostgreRepository) StopPassRenewal(ctx context.Context, passID string) error {
+	res, err := p.db.ExecContext(ctx, "UPDATE passes SET auto_renew = FALSE WHERE id = $1", passID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.Errorf(ErrPassNotFound, "pass %s", passID)
+	}
+	return nil
+}
+
+// ListPassAllowances ...
+func (p *PostgreRepository) ListPassAllowances(ctx context.Context, userID string, at time.Time) ([]models.PassAllowance, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+	day := pricing.PassDay(at)
+	rows, err := p.db.QueryContext(ctx, validPassesQuery, userID, at, day)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	allowances := make([]models.PassAllowance, 0)
+	for rows.Next() {
+		pass, plan, usage, err := scanValidPass(rows, day)
+		if err != nil {
+			return nil, err
+		}
+		unlocks, minutes := pricing.Allowance(plan, usage)
+		allowances = append(allowances, models.PassAllowance{Pass: *pass, Plan: *plan, Day: day, UnlocksLeft: unlocks, MinutesLeft: minutes})
+	}
+	return allowances, rows.Err()
+}
+
+// validPassesQuery selects the user's passes valid at the time with their plans and usage on the day, the oldest first
+const validPassesQuery = `SELECT p.id,p.plan_id,p.user_id,p.starts_at,p.ends_at,p.auto_renew,p.renewal_of,p.payment_reference,p.created_at,
+	l.id,l.name,l.period,l.price,l.currency,l.free_unlocks,l.free_minutes,l.created_at,
+	COALESCE(u.unlocks, 0), COALESCE(u.minutes, 0)
+	FROM passes p JOIN pass_plans l ON l.id = p.plan_id
+	LEFT JOIN pass_usage u ON u.pass_id = p.id AND u.day = $3
+	WHERE p.user_id = $1 AND p.starts_at <= $2 AND p.ends_at > $2 ORDER BY p.starts_at`
+
+// applyPass takes the allowances of the first of the user's valid passes covering the ride ended at the time
+// off the fare within txn, the pass's usage of the day counts the ride.
+func applyPass(ctx context.Context, txn *sql.Tx, userID string, fare *models.Fare, at time.Time) error {
+	day := pricing.PassDay(at)
+	// lock the user's passes so concurrent rides don't use the same allowance
+	if _, err := txn.ExecContext(ctx, "SELECT 1 FROM passes WHERE user_id = $1 AND starts_at <= $2 AND ends_at > $2 FOR UPDATE",
+		userID, at); err != nil {
+		return err
+	}
+	rows, err := txn.QueryContext(ctx, validPassesQuery, userID, at, day)
+	if err != nil {
+		return err
+	}
+	var (
+		usage   models.PassUsage
+		covered bool
+	)
+	for rows.Next() && !covered {
+		var (
+			pass *models.Pass
+			plan *models.PassPlan
+		)
+		if pass, plan, usage, err = scanValidPass(rows, day); err != nil {
+			break
+		}
+		usage, covered = pricing.ApplyPass(fare, pass, plan, usage)
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	} else if err = rows.Err(); err != nil || !covered {
+		return err
+	}
+	_, err = txn.ExecContext(ctx, `INSERT INTO pass_usage(pass_id,day,unlocks,minutes) VALUES($1,$2,$3,$4)
+	ON CONFLICT (pass_id, day) DO UPDATE SET unlocks = EXCLUDED.unlocks, minutes = EXCLUDED.minutes`,
+		usage.PassID, usage.Day, usage.Unlocks, usage.Minutes)
+	return err
+}
+
+// scanValidPass scans the validPassesQuery row of the day
+func scanValidPass(rows *sql.Rows, day string) (*models.Pass, *models.PassPlan, models.PassUsage, error) {
+	var (
+		pass      models.Pass
+		plan      models.PassPlan
+		renewalOf sql.NullString
+	)
+	usage := models.PassUsage{Day: day}
+	if err := rows.Scan(&pass.ID, &pass.PlanID, &pass.UserID, &pass.StartsAt, &pass.EndsAt, &pass.AutoRenew, &renewalOf,
+		&pass.PaymentReference, &pass.CreatedAt, &plan.ID, &plan.Name, &plan.Period, &plan.Price, &plan.Currency, &plan.FreeUnlocks,
+		&plan.FreeMinutes, &plan.CreatedAt, &usage.Unlocks, &usage.Minutes); err != nil {
+		return nil, nil, usage, err
+	}
+	pass.RenewalOf, usage.PassID = renewalOf.String, pass.ID
+	return &pass, &plan, usage, nil
+}
+
+// scanPassPlan scans the passPlanColumns row
+func scanPassPlan(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.PassPlan, error) {
+	var plan models.PassPlan
+	if err := row.Scan(&plan.ID, &plan.Name, &plan.Period, &plan.Price, &plan.Currency, &plan.FreeUnlocks, &plan.FreeMinutes,
+		&plan.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// scanPass scans the passColumns row
+func scanPass(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.Pass, error) {
+	var (
+		pass      models.Pass
+		renewalOf sql.NullString
+	)
+	if err := row.Scan(&pass.ID, &pass.PlanID, &pass.UserID, &pass.StartsAt, &pass.EndsAt, &pass.AutoRenew, &renewalOf,
+		&pass.PaymentReference, &pass.CreatedAt); err != nil {
+		return nil, err
+	}
+	pass.RenewalOf = renewalOf.String
+	return &pass, nil
+}
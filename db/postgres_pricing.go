@@ -0,0 +1,74 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"scootin/models"
+	"time"
+)
+
+// querier runs the queries on the database or within a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// CreatePricePlan ...
+func (p *PostgreRepository) CreatePricePlan(ctx context.Context, plan *models.PricePlan) error {
+	// the unique city, vehicle type and version refuse a concurrent plan of the same version
+	return p.db.QueryRowContext(ctx, `INSERT INTO price_plans(id,city,vehicle_type,version,currency,unlock_fee,per_minute,per_kilometer,effective_from)
+	SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5, $6, $7, $8 FROM price_plans WHERE city = $2 AND vehicle_type = $3
+	RETURNING version`, plan.ID, plan.City, plan.VehicleType, plan.Currency, plan.UnlockFee, plan.PerMinute, plan.PerKilometer, plan.EffectiveFrom).
+		Scan(&plan.Version)
+}
+
+// ListPricePlans ...
+func (p *PostgreRepository) ListPricePlans(ctx context.Context) ([]models.PricePlan, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+pricePlanColumns+" FROM price_plans ORDER BY created_at, version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractPricePlans(rows)
+}
+
+// PricePlanAt ...
+func (p *PostgreRepository) PricePlanAt(ctx context.Context, city, vehicleType string, at time.Time) (*models.PricePlan, error) {
+	return pricePlanAt(ctx, p.db, city, vehicleType, at)
+}
+
+// pricePlanAt prefers the city's own plans to the ones without a city, then the latest version
+func pricePlanAt(ctx context.Context, q querier, city, vehicleType string, at time.Time) (*models.PricePlan, error) {
+	rows, err := q.QueryContext(ctx, "SELECT "+pricePlanColumns+` FROM price_plans
+	WHERE vehicle_type = $2 AND (city = $1 OR city = '') AND effective_from <= $3
+	ORDER BY city <> '' DESC, version DESC LIMIT 1`, city, vehicleType, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	plans, err := extractPricePlans(rows)
+	if err != nil {
+		return nil, err
+	} else if len(plans) == 0 {
+		return nil, models.Errorf(ErrPricePlanNotFound, "city %q vehicle type %q", city, vehicleType)
+	}
+	return &plans[0], nil
+}
+
+const pricePlanColumns = "id,city,vehicle_type,version,currency,unlock_fee,per_minute,per_kilometer,effective_from"
+
+func extractPricePlans(rows *sql.Rows) ([]models.PricePlan, error) {
+	plans := make([]models.PricePlan, 0)
+	for rows.Next() {
+		var plan models.PricePlan
+		if err := rows.Scan(&plan.ID, &plan.City, &plan.VehicleType, &plan.Version, &plan.Currency,
+			&plan.UnlockFee, &plan.PerMinute, &plan.PerKilometer, &plan.EffectiveFrom); err != nil {
+			return nil, err
+		}
+		plans = append(plans, plan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return plans, nil
+}
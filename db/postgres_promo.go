@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/ledger"
+	"scootin/models"
+	"scootin/pricing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const promoColumns = "code,kind,percent,amount,currency,target,city,first_ride_only,max_redemptions,per_user_limit,expires_at,redemptions,created_at"
+
+// CreatePromoCode ...
+func (p *PostgreRepository) CreatePromoCode(ctx context.Context, promo *models.PromoCode) error {
+	_, err := p.db.ExecContext(ctx, "INSERT INTO promo_codes("+promoColumns+") VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)",
+		promo.Code, promo.Kind, promo.Percent, promo.Amount, promo.Currency, promo.Target, promo.City, promo.FirstRideOnly,
+		promo.MaxRedemptions, promo.PerUserLimit, promo.ExpiresAt, promo.Redemptions, promo.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "promo_codes_pkey" {
+		return models.Errorf(ErrPromoExists, "promo code %s", promo.Code)
+	}
+	return err
+}
+
+// ListPromoCodes ...
+func (p *PostgreRepository) ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+promoColumns+" FROM promo_codes ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	promos := make([]models.PromoCode, 0)
+	for rows.Next() {
+		promo, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		promos = append(promos, *promo)
+	}
+	return promos, rows.Err()
+}
+
+// RedeemPromoCode ...
+func (p *PostgreRepository) RedeemPromoCode(ctx context.Context, code, userID string, at time.Time) (*models.PromoRedemption, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	// lock the promo code so its redemptions stay within its cap
+	promo, err := scanPromoCode(txn.QueryRowContext(ctx, "SELECT "+promoColumns+" FROM promo_codes WHERE code = $1 FOR UPDATE", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrPromoNotFound, "promo code %s", code)
+	} else if err != nil {
+		return nil, err
+	}
+	var redeemed bool
+	if err = txn.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM promo_redemptions WHERE code = $1 AND user_id = $2)", code, userID).
+		Scan(&redeemed); err != nil {
+		return nil, err
+	} else if redeemed {
+		return nil, models.Errorf(ErrPromoAlreadyRedeemed, "promo code %s by user %s", code, userID)
+	} else if !pricing.Redeemable(promo, at) {
+		return nil, models.Errorf(ErrPromoUnavailable, "promo code %s", code)
+	}
+
+	_, err = txn.ExecContext(ctx, "INSERT INTO promo_redemptions(code,user_id,uses,redeemed_at) VALUES($1,$2,0,$3)", code, userID, at)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Constraint == "promo_redemptions_pkey" {
+		return nil, models.Errorf(ErrPromoAlreadyRedeemed, "promo code %s by user %s", code, userID)
+	} else if errors.As(err, &pqErr) && pqErr.Constraint == "promo_redemptions_user_id_fkey" {
+		return nil, models.Errorf(ErrUserNotFound, "user %s", userID)
+	} else if err != nil {
+		return nil, err
+	}
+	if _, err = txn.ExecContext(ctx, "UPDATE promo_codes SET redemptions = redemptions + 1 WHERE code = $1", code); err != nil {
+		return nil, err
+	}
+	if promo.Kind == models.PromoVoucher {
+		if err = postLedger(ctx, txn, ledger.Promo(userID, voucherReference(code, userID),
+			models.Money{Amount: promo.Amount, Currency: promo.Currency})); err != nil {
+			return nil, err
+		}
+	}
+	return &models.PromoRedemption{Code: code, UserID: userID, RedeemedAt: at}, txn.Commit()
+}
+
+// ListPromoRedemptions ...
+func (p *PostgreRepository) ListPromoRedemptions(ctx context.Context, userID string) ([]models.PromoRedemption, error) {
+	if err := userExists(ctx, p.db, userID); err != nil {
+		return nil, err
+	}
+	rows, err := p.db.QueryContext(ctx, "SELECT code,user_id,uses,redeemed_at FROM promo_redemptions WHERE user_id = $1 ORDER BY redeemed_at", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redemptions := make([]models.PromoRedemption, 0)
+	for rows.Next() {
+		var r models.PromoRedemption
+		if err := rows.Scan(&r.Code, &r.UserID, &r.Uses, &r.RedeemedAt); err != nil {
+			return nil, err
+		}
+		redemptions = append(redemptions, r)
+	}
+	return redemptions, rows.Err()
+}
+
+// applyBestPromo takes the best discount of the promo codes the user redeemed off the fare of the ride in the city
+// within txn, the applied redemption is locked and counted.
+func applyBestPromo(ctx context.Context, txn *sql.Tx, userID, city string, fare *models.Fare, at time.Time) error {
+	rows, err := txn.QueryContext(ctx, `SELECT p.code,p.kind,p.percent,p.amount,p.currency,p.target,p.city,p.first_ride_only,
+	p.max_redemptions,p.per_user_limit,p.expires_at,p.redemptions,p.created_at, r.uses
+	FROM promo_redemptions r JOIN promo_codes p ON p.code = r.code
+	WHERE r.user_id = $1 AND p.kind <> $2 FOR UPDATE OF r`, userID, models.PromoVoucher)
+	if err != nil {
+		return err
+	}
+	promos := make([]models.PromoCode, 0)
+	for rows.Next() {
+		var (
+			promo     models.PromoCode
+			r         models.PromoRedemption
+			expiresAt sql.NullTime
+		)
+		if err = rows.Scan(&promo.Code, &promo.Kind, &promo.Percent, &promo.Amount, &promo.Currency, &promo.Target, &promo.City,
+			&promo.FirstRideOnly, &promo.MaxRedemptions, &promo.PerUserLimit, &expiresAt, &promo.Redemptions, &promo.CreatedAt,
+			&r.Uses); err != nil {
+			break
+		}
+		if expiresAt.Valid {
+			promo.ExpiresAt = &expiresAt.Time
+		}
+		if pricing.Usable(&promo, &r) {
+			promos = append(promos, promo)
+		}
+	}
+	rows.Close()
+	if err != nil {
+		return err
+	} else if err = rows.Err(); err != nil {
+		return err
+	}
+
+	var firstRide bool
+	if err = txn.QueryRowContext(ctx, "SELECT NOT EXISTS(SELECT 1 FROM trips WHERE user_id = $1 AND ended_at IS NOT NULL)", userID).
+		Scan(&firstRide); err != nil {
+		return err
+	}
+	applied := pricing.ApplyBestPromo(fare, promos, city, firstRide, at)
+	if applied == nil {
+		return nil
+	}
+	_, err = txn.ExecContext(ctx, "UPDATE promo_redemptions SET uses = uses + 1 WHERE code = $1 AND user_id = $2", applied.Code, userID)
+	return err
+}
+
+// scanPromoCode scans the promoColumns row
+func scanPromoCode(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.PromoCode, error) {
+	var (
+		promo     models.PromoCode
+		expiresAt sql.NullTime
+	)
+	if err := row.Scan(&promo.Code, &promo.Kind, &promo.Percent, &promo.Amount, &promo.Currency, &promo.Target, &promo.City,
+		&promo.FirstRideOnly, &promo.MaxRedemptions, &promo.PerUserLimit, &expiresAt, &promo.Redemptions, &promo.CreatedAt); err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		promo.ExpiresAt = &expiresAt.Time
+	}
+	return &promo, nil
+}
@@ -0,0 +1,246 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"scootin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReserveScooter ...
+func (p *PostgreRepository) ReserveScooter(ctx context.Context, scooterID, userID string, hold time.Duration, surge float64) (*models.Reservation, error) {
+	var status models.ScooterStatus
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if err = lockUser(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+	// a scooter is reserved on the same terms it's booked
+	err = txn.QueryRowContext(ctx, "SELECT status FROM scooters WHERE id = $1 FOR UPDATE", scooterID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrScooterNotFound, "scooter %s", scooterID)
+	} else if err != nil {
+		return nil, err
+	}
+	if err = bookable(status, scooterID, userID); err != nil {
+		return nil, err
+	}
+	if err = userHolds(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err = txn.ExecContext(ctx, "UPDATE scooters SET status = $1, rider_id = $2 WHERE id = $3", models.StatusReserved, userID, scooterID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	r := &models.Reservation{ID: uuid.New().String(), ScooterID: scooterID, UserID: userID, Status: models.ReservationActive,
+		CreatedAt: now, ExpiresAt: now.Add(hold), Surge: surge}
+	if _, err = txn.ExecContext(ctx, "INSERT INTO reservations(id,scooter_id,user_id,status,created_at,expires_at,surge) VALUES($1,$2,$3,$4,$5,$6,$7)",
+		r.ID, r.ScooterID, r.UserID, r.Status, r.CreatedAt, r.ExpiresAt, r.Surge); err != nil {
+		return nil, constraintError(err, userID)
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, constraintError(err, userID)
+	}
+	return r, nil
+}
+
+// GetReservation ...
+func (p *PostgreRepository) GetReservation(ctx context.Context, reservationID string) (*models.Reservation, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+reservationColumns+" FROM reservations WHERE id = $1", reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rs, err := extractReservations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, models.Errorf(ErrReservationNotFound, "reservation %s", reservationID)
+	}
+	return &rs[0], nil
+}
+
+// ConvertReservation ...
+func (p *PostgreRepository) ConvertReservation(ctx context.Context, reservationID, userID string, near *models.Proximity) (*models.Trip, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if err = lockUser(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+	r, err := activeReservation(ctx, txn, reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// an elapsed hold isn't converted even if the sweeper hasn't expired it yet
+	now := time.Now().UTC()
+	if !now.Before(r.ExpiresAt) {
+		if err = endReservation(ctx, txn, r, models.ReservationExpired, now); err != nil {
+			return nil, err
+		}
+		if err = txn.Commit(); err != nil {
+			return nil, err
+		}
+		return nil, models.Errorf(ErrReservationNotActive, "reservation %s has expired", reservationID)
+	}
+
+	// start the trip from the scooter's current location
+	trip := &models.Trip{ID: uuid.New().String(), ScooterID: r.ScooterID, UserID: userID, StartedAt: now, Surge: r.Surge}
+	if err = txn.QueryRowContext(ctx, "SELECT latitude, longitude FROM scooters WHERE id = $1 FOR UPDATE", r.ScooterID).
+		Scan(&trip.StartLocation.Latitude, &trip.StartLocation.Longitude); err != nil {
+		return nil, err
+	}
+	if err = nearby(near, r.ScooterID, trip.StartLocation); err != nil {
+		return nil, err
+	}
+	if err = startTrip(ctx, txn, trip); err != nil {
+		return nil, err
+	}
+	if _, err = txn.ExecContext(ctx, "UPDATE reservations SET status = $2, ended_at = $3, trip_id = $4 WHERE id = $1",
+		r.ID, models.ReservationConverted, now, trip.ID); err != nil {
+		return nil, err
+	}
+	if err = txn.Commit(); err != nil {
+		return nil, constraintError(err, userID)
+	}
+	return trip, nil
+}
+
+// CountActiveReservations ...
+func (p *PostgreRepository) CountActiveReservations(ctx context.Context, zone string) (int, error) {
+	var count int
+	err := p.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM reservations r JOIN scooters s ON s.id = r.scooter_id
+	WHERE r.status = $1 AND s.geohash LIKE $2`, models.ReservationActive, zone+"%").Scan(&count)
+	return count, err
+}
+
+// CancelReservation ...
+func (p *PostgreRepository) CancelReservation(ctx context.Context, reservationID, userID string) (*models.Reservation, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer txn.Rollback()
+
+	if err = lockUser(ctx, txn, userID); err != nil {
+		return nil, err
+	}
+	r, err := activeReservation(ctx, txn, reservationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = endReservation(ctx, txn, r, models.ReservationCancelled, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return r, txn.Commit()
+}
+
+// ExpireReservations ...
+func (p *PostgreRepository) ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	txn, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer txn.Rollback()
+
+	// the reservations being converted or cancelled are locked, they're skipped once they're no longer active
+	rows, err := txn.QueryContext(ctx, `UPDATE reservations SET status = $1, ended_at = $2
+	WHERE status = $3 AND expires_at <= $2 RETURNING scooter_id, user_id`, models.ReservationExpired, now, models.ReservationActive)
+	if err != nil {
+		return 0, err
+	}
+	type hold struct{ scooterID, userID string }
+	expired := make([]hold, 0)
+	for rows.Next() {
+		var h hold
+		if err := rows.Scan(&h.scooterID, &h.userID); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		expired = append(expired, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, h := range expired {
+		if _, err = txn.ExecContext(ctx, "UPDATE scooters SET status = $1, rider_id = NULL WHERE id = $2 AND status = $3 AND rider_id = $4",
+			models.StatusAvailable, h.scooterID, models.StatusReserved, h.userID); err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), txn.Commit()
+}
+
+// activeReservation locks the user's reservation row as long as it's still active
+func activeReservation(ctx context.Context, txn *sql.Tx, reservationID, userID string) (*models.Reservation, error) {
+	rows, err := txn.QueryContext(ctx, "SELECT "+reservationColumns+" FROM reservations WHERE id = $1 FOR UPDATE", reservationID)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := extractReservations(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case len(rs) == 0:
+		return nil, models.Errorf(ErrReservationNotFound, "reservation %s", reservationID)
+	case rs[0].UserID != userID:
+		return nil, models.Errorf(ErrForbidden, "reservation %s isn't held by user %s", reservationID, userID)
+	case rs[0].Status != models.ReservationActive:
+		return nil, models.Errorf(ErrReservationNotActive, "reservation %s is %s", reservationID, rs[0].Status)
+	}
+	return &rs[0], nil
+}
+
+// endReservation ends the reservation without a trip, its scooter is available again
+func endReservation(ctx context.Context, txn *sql.Tx, r *models.Reservation, status models.ReservationStatus, now time.Time) error {
+	if _, err := txn.ExecContext(ctx, "UPDATE reservations SET status = $2, ended_at = $3 WHERE id = $1", r.ID, status, now); err != nil {
+		return err
+	}
+	if _, err := txn.ExecContext(ctx, "UPDATE scooters SET status = $1, rider_id = NULL WHERE id = $2 AND status = $3 AND rider_id = $4",
+		models.StatusAvailable, r.ScooterID, models.StatusReserved, r.UserID); err != nil {
+		return err
+	}
+	r.Status, r.EndedAt = status, &now
+	return nil
+}
+
+const reservationColumns = "id,scooter_id,user_id,status,created_at,expires_at,ended_at,COALESCE(trip_id,''),surge"
+
+func extractReservations(rows *sql.Rows) ([]models.Reservation, error) {
+	rs := make([]models.Reservation, 0)
+	for rows.Next() {
+		var (
+			r       models.Reservation
+			endedAt sql.NullTime
+		)
+		if err := rows.Scan(&r.ID, &r.ScooterID, &r.UserID, &r.Status, &r.CreatedAt, &r.ExpiresAt, &endedAt, &r.TripID, &r.Surge); err != nil {
+			return nil, err
+		}
+		if endedAt.Valid {
+			r.EndedAt = &endedAt.Time
+		}
+		rs = append(rs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"scootin/models"
+	"time"
+)
+
+// CreatePaymentSaga ...
+func (p *PostgreRepository) CreatePaymentSaga(ctx context.Context, s *models.PaymentSaga) error {
+	_, err := p.db.ExecContext(ctx, `INSERT INTO payment_sagas(id,user_id,scooter_id,trip_id,checkout_id,authorization_id,hold_amount,currency,step,failure_code,created_at,updated_at)
+	VALUES($1,$2,$3,NULLIF($4,''),NULLIF($5,''),$6,$7,$8,$9,$10,$11,$12)`, s.ID, s.UserID, s.ScooterID, s.TripID, s.CheckoutID, s.AuthorizationID,
+		s.HoldAmount, s.Currency, s.Step, s.FailureCode, s.CreatedAt, s.UpdatedAt)
+	return err
+}
+
+// UpdatePaymentSaga ...
+func (p *PostgreRepository) UpdatePaymentSaga(ctx context.Context, s *models.PaymentSaga) error {
+	res, err := p.db.ExecContext(ctx, `UPDATE payment_sagas SET trip_id = NULLIF($2,''), checkout_id = NULLIF($3,''), authorization_id = $4,
+	step = $5, failure_code = $6, updated_at = $7 WHERE id = $1`, s.ID, s.TripID, s.CheckoutID, s.AuthorizationID, s.Step, s.FailureCode, s.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.Errorf(ErrPaymentSagaNotFound, "payment saga %s", s.ID)
+	}
+	return nil
+}
+
+// PaymentSagaByTrip ...
+func (p *PostgreRepository) PaymentSagaByTrip(ctx context.Context, tripID string) (*models.PaymentSaga, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+sagaColumns+" FROM payment_sagas WHERE trip_id = $1", tripID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sagas, err := extractPaymentSagas(rows)
+	if err != nil {
+		return nil, err
+	} else if len(sagas) == 0 {
+		return nil, models.Errorf(ErrPaymentSagaNotFound, "trip %s", tripID)
+	}
+	return &sagas[0], nil
+}
+
+// ListStalePaymentSagas ...
+func (p *PostgreRepository) ListStalePaymentSagas(ctx context.Context, before time.Time) ([]models.PaymentSaga, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+sagaColumns+` FROM payment_sagas
+	WHERE step NOT IN ($1,$2,$3) AND updated_at < $4 ORDER BY updated_at`, models.SagaCaptured, models.SagaCancelled, models.SagaFailed, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return extractPaymentSagas(rows)
+}
+
+const sagaColumns = "id,user_id,scooter_id,COALESCE(trip_id,''),COALESCE(checkout_id,''),authorization_id,hold_amount,currency,step,failure_code,created_at,updated_at"
+
+func extractPaymentSagas(rows *sql.Rows) ([]models.PaymentSaga, error) {
+	sagas := make([]models.PaymentSaga, 0)
+	for rows.Next() {
+		var s models.PaymentSaga
+		if err := rows.Scan(&s.ID, &s.UserID, &s.ScooterID, &s.TripID, &s.CheckoutID, &s.AuthorizationID,
+			&s.HoldAmount, &s.Currency, &s.Step, &s.FailureCode, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sagas = append(sagas, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"scootin/geo"
+	"scootin/models"
+	"scootin/pricing"
+)
+
+const stationColumns = "id, name, city, latitude, longitude, radius, capacity, discount_percent, created_at"
+
+// CreateStation ...
+func (p *PostgreRepository) CreateStation(ctx context.Context, station *models.Station) error {
+	_, err := p.db.ExecContext(ctx, "INSERT INTO stations("+stationColumns+", geohash) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)",
+		station.ID, station.Name, station.City, station.Location.Latitude, station.Location.Longitude, station.Radius,
+		station.Capacity, station.DiscountPercent, station.CreatedAt, geo.Encode(station.Location, geo.HashPrecision))
+	return err
+}
+
+// ListStations ...
+func (p *PostgreRepository) ListStations(ctx context.Context, city string) ([]models.StationOccupancy, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+stationColumns+" FROM stations WHERE $1 = '' OR city = $1 ORDER BY created_at, id", city)
+	if err != nil {
+		return nil, err
+	}
+	stations, err := scanStations(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	occupancies := make([]models.StationOccupancy, 0, len(stations))
+	for _, station := range stations {
+		parked, err := parkedNear(ctx, p.db, station.Location, station.Radius)
+		if err != nil {
+			return nil, err
+		}
+		occupancies = append(occupancies, pricing.Occupancy(station, parked))
+	}
+	return occupancies, nil
+}
+
+// applyStation takes the discount of the station the trip ended at off its fare, when the station has a free slot
+func applyStation(ctx context.Context, txn *sql.Tx, fare *models.Fare, end models.Location) error {
+	// only the stations around the location may contain it
+	within, args := geohashWithin(end, pricing.MaxStationRadius, nil)
+	rows, err := txn.QueryContext(ctx, "SELECT "+stationColumns+" FROM stations WHERE "+within, args...)
+	if err != nil {
+		return err
+	}
+	stations, err := scanStations(rows)
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	station := pricing.NearestStation(stations, end)
+	if station == nil {
+		return nil
+	}
+	parked, err := parkedNear(ctx, txn, station.Location, station.Radius)
+	if err != nil {
+		return err
+	}
+	pricing.ApplyStation(fare, station, parked)
+	return nil
+}
+
+// parkedNear counts the scooters parked within the radius in meters around the center, the ones not in a trip
+func parkedNear(ctx context.Context, q rowsQuerier, center models.Location, radius float64) (int, error) {
+	within, args := geohashWithin(center, radius, []interface{}{models.StatusInTrip})
+	rows, err := q.QueryContext(ctx, "SELECT "+scooterColumns+" FROM scooters WHERE status <> $1 AND "+within, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	candidates, err := extractScooterInfo(rows)
+	if err != nil {
+		return 0, err
+	}
+	return len(nearestScooters(center, radius, 0, candidates)), nil
+}
+
+// rowsQuerier is a database or a transaction querying rows
+type rowsQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func scanStations(rows *sql.Rows) ([]models.Station, error) {
+	stations := make([]models.Station, 0)
+	for rows.Next() {
+		var s models.Station
+		if err := rows.Scan(&s.ID, &s.Name, &s.City, &s.Location.Latitude, &s.Location.Longitude, &s.Radius, &s.Capacity,
+			&s.DiscountPercent, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		stations = append(stations, s)
+	}
+	return stations, rows.Err()
+}
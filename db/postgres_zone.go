@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"scootin/models"
+	"scootin/zone"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// zoneCacheTTL is how long a replica's zones index is used before the zones are read again,
+// the other replicas' changes apply within it.
+const zoneCacheTTL = 30 * time.Second
+
+const zoneColumns = "id, name, tags, geometry, speed_limit, created_at, updated_at"
+
+// zoneCache is the replica's index of the zones, so the location updates don't read the zones every time
+type zoneCache struct {
+	mu       sync.Mutex
+	index    *zone.Index
+	loadedAt time.Time
+}
+
+// CreateZone ...
+func (p *PostgreRepository) CreateZone(ctx context.Context, z *models.Zone) error {
+	geometry, err := json.Marshal(z.Geometry)
+	if err != nil {
+		return err
+	}
+	if _, err := p.db.ExecContext(ctx, "INSERT INTO zones("+zoneColumns+") VALUES($1,$2,$3,$4,$5,$6,$7)",
+		z.ID, z.Name, zoneTags(z.Tags), geometry, z.SpeedLimit, z.CreatedAt, z.UpdatedAt); err != nil {
+		return err
+	}
+	p.invalidateZones()
+	return nil
+}
+
+// UpdateZone ...
+func (p *PostgreRepository) UpdateZone(ctx context.Context, z *models.Zone) error {
+	geometry, err := json.Marshal(z.Geometry)
+	if err != nil {
+		return err
+	}
+	res, err := p.db.ExecContext(ctx, "UPDATE zones SET name = $2, tags = $3, geometry = $4, speed_limit = $5, updated_at = $6 WHERE id = $1",
+		z.ID, z.Name, zoneTags(z.Tags), geometry, z.SpeedLimit, z.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.Errorf(ErrZoneNotFound, "zone %s", z.ID)
+	}
+	p.invalidateZones()
+	return nil
+}
+
+// DeleteZone ...
+func (p *PostgreRepository) DeleteZone(ctx context.Context, zoneID string) error {
+	res, err := p.db.ExecContext(ctx, "DELETE FROM zones WHERE id = $1", zoneID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return models.Errorf(ErrZoneNotFound, "zone %s", zoneID)
+	}
+	p.invalidateZones()
+	return nil
+}
+
+// GetZone ...
+func (p *PostgreRepository) GetZone(ctx context.Context, zoneID string) (*models.Zone, error) {
+	z, err := scanZone(p.db.QueryRowContext(ctx, "SELECT "+zoneColumns+" FROM zones WHERE id = $1", zoneID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.Errorf(ErrZoneNotFound, "zone %s", zoneID)
+	}
+	return z, err
+}
+
+// ListZones ...
+func (p *PostgreRepository) ListZones(ctx context.Context) ([]models.Zone, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+zoneColumns+" FROM zones ORDER BY created_at, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	zones := make([]models.Zone, 0)
+	for rows.Next() {
+		z, err := scanZone(rows)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, *z)
+	}
+	return zones, rows.Err()
+}
+
+// ListZoneAlerts ...
+func (p *PostgreRepository) ListZoneAlerts(ctx context.Context, from, to time.Time) ([]models.ZoneAlert, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT id, scooter_id, user_id, zone_id, tag, latitude, longitude, raised_at FROM zone_alerts
+	WHERE raised_at >= $1 AND raised_at < $2 ORDER BY raised_at, id`, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := make([]models.ZoneAlert, 0)
+	for rows.Next() {
+		var a models.ZoneAlert
+		if err := rows.Scan(&a.ID, &a.ScooterID, &a.UserID, &a.ZoneID, &a.Tag, &a.Location.Latitude, &a.Location.Longitude, &a.At); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+// zoneIndex returns the replica's zones index, read again once it's stale
+func (p *PostgreRepository) zoneIndex(ctx context.Context) (*zone.Index, error) {
+	p.zones.mu.Lock()
+	defer p.zones.mu.Unlock()
+
+	if p.zones.index != nil && time.Since(p.zones.loadedAt) < zoneCacheTTL {
+		return p.zones.index, nil
+	}
+	zones, err := p.ListZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.zones.index, p.zones.loadedAt = zone.NewIndex(zones), time.Now()
+	return p.zones.index, nil
+}
+
+// invalidateZones has the zones read again on the next use of the index
+func (p *PostgreRepository) invalidateZones() {
+	p.zones.mu.Lock()
+	p.zones.index = nil
+	p.zones.mu.Unlock()
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// raiseZoneAlerts records an alert for every no-riding zone the scooter of the rider entered moving from a location to another
+func (p *PostgreRepository) raiseZoneAlerts(ctx context.Context, q execer, scooterID, userID string, from, to models.Location, at time.Time) error {
+	index, err := p.zoneIndex(ctx)
+	if err != nil {
+		return err
+	}
+	for _, z := range index.Entered(from, to, models.ZoneNoRiding) {
+		if _, err := q.ExecContext(ctx, `INSERT INTO zone_alerts(id,scooter_id,user_id,zone_id,tag,latitude,longitude,raised_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8)`, uuid.New().String(), scooterID, userID, z.ID, models.ZoneNoRiding,
+			to.Latitude, to.Longitude, at); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// zoneTags returns the zone's tags as a text array
+func zoneTags(tags []models.ZoneTag) interface{} {
+	texts := make([]string, 0, len(tags))
+	for _, t := range tags {
+		texts = append(texts, string(t))
+	}
+	return pq.Array(texts)
+}
+
+func scanZone(row interface {
+	Scan(dest ...interface{}) error
+}) (*models.Zone, error) {
+	var (
+		z        models.Zone
+		tags     []string
+		geometry []byte
+	)
+	if err := row.Scan(&z.ID, &z.Name, pq.Array(&tags), &geometry, &z.SpeedLimit, &z.CreatedAt, &z.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(geometry, &z.Geometry); err != nil {
+		return nil, fmt.Errorf("invalid geometry of zone %s: %s", z.ID, err)
+	}
+	for _, t := range tags {
+		z.Tags = append(z.Tags, models.ZoneTag(t))
+	}
+	return &z, nil
+}
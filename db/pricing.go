@@ -0,0 +1,26 @@
+package db
+
+import (
+	"scootin/models"
+	"time"
+)
+
+// defaultPricePlan prices the scooters of every city until they get their own plans,
+// the "price_plans" migration stores the same plan in postgres.
+var defaultPricePlan = models.PricePlan{
+	ID:            "default-scooter-v1",
+	VehicleType:   models.DefaultVehicleType,
+	Version:       1,
+	Currency:      "EUR",
+	UnlockFee:     100,
+	PerMinute:     25,
+	EffectiveFrom: time.Unix(0, 0).UTC(),
+}
+
+// vehicleType returns the vehicle type, the default one when it's empty
+func vehicleType(t string) string {
+	if len(t) == 0 {
+		return models.DefaultVehicleType
+	}
+	return t
+}
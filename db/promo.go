@@ -0,0 +1,6 @@
+package db
+
+// voucherReference is the ledger reference of the voucher redeemed by the user
+func voucherReference(code, userID string) string {
+	return "voucher:" + code + ":" + userID
+}
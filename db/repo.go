@@ -3,27 +3,241 @@ package db
 import (
 	"context"
 	"scootin/models"
+	"time"
 )
 
 // Repository represents storage operations
 type Repository interface {
-	// BookScooter assign the scooter for a user.
-	BookScooter(ctx context.Context, ScooterID, userID string) error
+	// BookScooter assign the scooter for a user, returns the started trip.
+	// The trip's fare is multiplied by the surge locked in at booking, the scooter must be near the rider unless near is nil.
+	BookScooter(ctx context.Context, ScooterID, userID string, surge float64, near *models.Proximity) (*models.Trip, error)
 
-	// ReleaseScooter releases the scooter booking by userID
-	ReleaseScooter(ctx context.Context, userID string) error
+	// ReleaseScooter releases the scooter booking by userID and ends its trip, returns the ended trip with its fare
+	// less the best discount of the promo codes the user redeemed. The scooter must be parked within the operating
+	// area and out of the no-parking zones, ended at a station with a free slot the trip gets its discount.
+	ReleaseScooter(ctx context.Context, userID string) (*models.Trip, error)
+
+	// GetTrip returns the trip by its id
+	GetTrip(ctx context.Context, tripID string) (*models.Trip, error)
+
+	// ListUserTrips lists the user's trips, the oldest first
+	ListUserTrips(ctx context.Context, userID string) ([]models.Trip, error)
+
+	// ReserveScooter holds the scooter for the user until the hold elapses, returns the active reservation.
+	// The surge is locked in for the trip the reservation is converted to.
+	ReserveScooter(ctx context.Context, scooterID, userID string, hold time.Duration, surge float64) (*models.Reservation, error)
+
+	// GetReservation returns the reservation by its id
+	GetReservation(ctx context.Context, reservationID string) (*models.Reservation, error)
+
+	// ConvertReservation starts the trip on the user's reserved scooter, returns the started trip.
+	// The scooter must be near the rider unless near is nil.
+	ConvertReservation(ctx context.Context, reservationID, userID string, near *models.Proximity) (*models.Trip, error)
+
+	// CancelReservation cancels the user's active reservation and makes its scooter available again
+	CancelReservation(ctx context.Context, reservationID, userID string) (*models.Reservation, error)
+
+	// CountActiveReservations counts the active reservations of the scooters within the geohash zone
+	CountActiveReservations(ctx context.Context, zone string) (int, error)
+
+	// ExpireReservations expires the active reservations elapsed by now and makes their scooters available again,
+	// returns the number of expired reservations
+	ExpireReservations(ctx context.Context, now time.Time) (int, error)
+
+	// CreateCheckout stores a new pending checkout, a trip has one pending or captured checkout at most
+	CreateCheckout(ctx context.Context, checkout *models.Checkout) error
+
+	// UpdateCheckout stores the checkout's payment outcome
+	UpdateCheckout(ctx context.Context, checkout *models.Checkout) error
+
+	// GetCheckout returns the checkout by its id
+	GetCheckout(ctx context.Context, checkoutID string) (*models.Checkout, error)
+
+	// CreatePaymentSaga stores a new payment saga
+	CreatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error
+
+	// UpdatePaymentSaga stores the payment saga's progress
+	UpdatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error
+
+	// PaymentSagaByTrip returns the payment saga of the trip
+	PaymentSagaByTrip(ctx context.Context, tripID string) (*models.PaymentSaga, error)
+
+	// ListStalePaymentSagas lists the unfinished payment sagas not updated since before, the oldest first
+	ListStalePaymentSagas(ctx context.Context, before time.Time) ([]models.PaymentSaga, error)
+
+	// PostLedgerTransaction appends the balanced transaction to the ledger, a transaction of a kind and reference
+	// already posted is ignored so the postings can be retried
+	PostLedgerTransaction(ctx context.Context, tx *models.LedgerTransaction) error
+
+	// GetWallet returns the user's wallet balance per currency
+	GetWallet(ctx context.Context, userID string) (*models.Wallet, error)
+
+	// ListLedgerTransactions lists the user's ledger transactions, the oldest first
+	ListLedgerTransactions(ctx context.Context, userID string) ([]models.LedgerTransaction, error)
+
+	// CapturedCheckout returns the trip's captured checkout
+	CapturedCheckout(ctx context.Context, tripID string) (*models.Checkout, error)
+
+	// CreateDispute stores a new dispute with its audit trail, a trip is disputed once
+	CreateDispute(ctx context.Context, dispute *models.Dispute) error
+
+	// GetDispute returns the dispute by its id with its audit trail
+	GetDispute(ctx context.Context, disputeID string) (*models.Dispute, error)
+
+	// ListDisputes lists the disputes in the status, all of them when it's empty, the oldest first
+	ListDisputes(ctx context.Context, status models.DisputeStatus) ([]models.Dispute, error)
+
+	// UpdateDispute moves the dispute to the event's status with its refund fields, appends the event to its
+	// audit trail and posts the refund when it's not nil, all at once. The dispute is updated with the event.
+	UpdateDispute(ctx context.Context, dispute *models.Dispute, event models.DisputeEvent, refund *models.LedgerTransaction) error
+
+	// CreatePromoCode stores a new promo code
+	CreatePromoCode(ctx context.Context, promo *models.PromoCode) error
+
+	// ListPromoCodes lists the promo codes, the oldest first
+	ListPromoCodes(ctx context.Context) ([]models.PromoCode, error)
+
+	// RedeemPromoCode attaches the promo code to the user at the time, a voucher's amount is credited to the user's wallet
+	RedeemPromoCode(ctx context.Context, code, userID string, at time.Time) (*models.PromoRedemption, error)
+
+	// ListPromoRedemptions lists the promo codes the user redeemed, the oldest first
+	ListPromoRedemptions(ctx context.Context, userID string) ([]models.PromoRedemption, error)
+
+	// CreatePassPlan stores a new pass plan
+	CreatePassPlan(ctx context.Context, plan *models.PassPlan) error
+
+	// ListPassPlans lists the pass plans, the oldest first
+	ListPassPlans(ctx context.Context) ([]models.PassPlan, error)
+
+	// GetPassPlan returns the pass plan by its id
+	GetPassPlan(ctx context.Context, planID string) (*models.PassPlan, error)
+
+	// CreatePass stores the user's new pass along with the posting of its payment, nil for a free pass.
+	// A renewal of an already renewed pass is ignored.
+	CreatePass(ctx context.Context, pass *models.Pass, payment *models.LedgerTransaction) error
+
+	// ListRenewablePasses lists the auto-renewed passes ending before the time which aren't renewed yet, the first ending first
+	ListRenewablePasses(ctx context.Context, before time.Time) ([]models.Pass, error)
+
+	// StopPassRenewal stops renewing the pass
+	StopPassRenewal(ctx context.Context, passID string) error
+
+	// ListPassAllowances lists the user's passes valid at the time with what's left of their allowances that day,
+	// the oldest first
+	ListPassAllowances(ctx context.Context, userID string, at time.Time) ([]models.PassAllowance, error)
+
+	// ListLedgerTransactionsBetween lists every user's ledger transactions posted within [from, to), the oldest first
+	ListLedgerTransactionsBetween(ctx context.Context, from, to time.Time) ([]models.LedgerTransaction, error)
+
+	// SetTaxRule stores the city's tax rule, replacing its previous one
+	SetTaxRule(ctx context.Context, rule *models.TaxRule) error
+
+	// ListTaxRules lists the tax rules ordered by city
+	ListTaxRules(ctx context.Context) ([]models.TaxRule, error)
+
+	// ListCharges lists the ended trips' fares and the paid passes charged, and the disputes' refunds credited,
+	// within [from, to), the oldest first
+	ListCharges(ctx context.Context, from, to time.Time) ([]models.Charge, error)
+
+	// CreateInvoice numbers the invoice next in its legal entity's sequence and stores it, a user is invoiced once
+	// per period, legal entity and currency.
+	CreateInvoice(ctx context.Context, invoice *models.Invoice) error
+
+	// GetInvoice returns the invoice by its id
+	GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error)
+
+	// ListUserInvoices lists the user's invoices, the oldest first
+	ListUserInvoices(ctx context.Context, userID string) ([]models.Invoice, error)
+
+	// CreateZone stores the zone, it applies to the trips' ends and the location updates from then on
+	CreateZone(ctx context.Context, zone *models.Zone) error
+
+	// UpdateZone replaces the zone's name, tags, geometry and speed limit
+	UpdateZone(ctx context.Context, zone *models.Zone) error
+
+	// DeleteZone deletes the zone, its alerts are kept
+	DeleteZone(ctx context.Context, zoneID string) error
+
+	// GetZone returns the zone by its id
+	GetZone(ctx context.Context, zoneID string) (*models.Zone, error)
+
+	// ListZones lists the zones in their creation order
+	ListZones(ctx context.Context) ([]models.Zone, error)
+
+	// ListZoneAlerts lists the alerts raised within [from, to), the oldest first
+	ListZoneAlerts(ctx context.Context, from, to time.Time) ([]models.ZoneAlert, error)
+
+	// CreateStation stores the virtual parking station
+	CreateStation(ctx context.Context, station *models.Station) error
+
+	// ListStations lists the city's stations, every station when the city is empty, with the scooters parked
+	// at them in their creation order
+	ListStations(ctx context.Context, city string) ([]models.StationOccupancy, error)
+
+	// GetScooter returns the scooter by its id with its last reported location
+	GetScooter(ctx context.Context, scooterID string) (*models.ScooterInfo, error)
+
+	// SetScooterStatus moves the scooter to another operational status when the transition is allowed
+	SetScooterStatus(ctx context.Context, scooterID string, status models.ScooterStatus) (*models.ScooterInfo, error)
 
 	// ListAvailableScooter lists all available scooters
 	ListAvailableScooter(ctx context.Context) ([]models.ScooterInfo, error)
 
+	// ListAvailableScooterInZone lists the available scooters within the zone, a geohash cell
+	ListAvailableScooterInZone(ctx context.Context, zone string) ([]models.ScooterInfo, error)
+
+	// ListAvailableScooterNear lists up to limit available scooters within the radius in meters
+	// around the center, the nearest first
+	ListAvailableScooterNear(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyScooter, error)
+
 	// CreateUser stores a new user
 	CreateUser(ctx context.Context, user *models.User) error
 
-	// CreateScooter creates a new scooter
-	CreateScooter(ctx context.Context, scooterID string) error
+	// CreateScooter stores a new available scooter
+	CreateScooter(ctx context.Context, scooter *models.ScooterInfo) error
+
+	// CreatePricePlan stores the next version of the plan's city and vehicle type prices
+	CreatePricePlan(ctx context.Context, plan *models.PricePlan) error
+
+	// ListPricePlans lists all the price plans' versions
+	ListPricePlans(ctx context.Context) ([]models.PricePlan, error)
+
+	// PricePlanAt returns the plan pricing the rides of the city and vehicle type started at the time
+	PricePlanAt(ctx context.Context, city, vehicleType string, at time.Time) (*models.PricePlan, error)
+
+	// RecordTelemetry records the scooter's samples newer than its last report, the others are counted
+	// as duplicates. The scooter moves to the last sample's location and an alert is raised for every
+	// no-riding zone entered along the samples.
+	RecordTelemetry(ctx context.Context, scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error)
+
+	// RecordPositions records the scooters' samples at once, each scooter moves to its latest. The samples not newer
+	// than their scooter's last report and the unknown scooters' are skipped, returns how many were recorded.
+	RecordPositions(ctx context.Context, samples []models.LocationSample) (int, error)
+
+	// CreateCommand queues the command to its scooter
+	CreateCommand(ctx context.Context, command *models.Command) error
+
+	// GetCommand returns the command by its id
+	GetCommand(ctx context.Context, commandID string) (*models.Command, error)
+
+	// ListCommands lists the scooter's commands in their creation order
+	ListCommands(ctx context.Context, scooterID string) ([]models.Command, error)
+
+	// PollCommands delivers the scooter's unexpired commands waiting for an acknowledgment, the queued ones and
+	// the ones delivered before, in their creation order
+	PollCommands(ctx context.Context, scooterID string, now time.Time) ([]models.Command, error)
 
-	// UpdateScooterCoordinates update the scooter coordinates
-	UpdateScooterCoordinates(ctx context.Context, scooterID string, coordinates int64) error
+	// AckCommand ends the scooter's delivered command as acked or failed
+	AckCommand(ctx context.Context, scooterID, commandID string, ack models.CommandAck, now time.Time) (*models.Command, error)
+
+	// ExpireCommands fails the commands not acknowledged before they expired, returns how many
+	ExpireCommands(ctx context.Context, now time.Time) (int, error)
+
+	// ScooterTrack returns the scooter's location history within [from, to), the oldest first
+	ScooterTrack(ctx context.Context, scooterID string, from, to time.Time) ([]models.LocationSample, error)
+
+	// MaintainLocationHistory prepares the location history storage and drops what's older than the retention
+	MaintainLocationHistory(ctx context.Context, now time.Time, retention time.Duration) error
 
 	// Close closes the database connection
 	Close()
@@ -32,8 +246,8 @@ type Repository interface {
 var repositoryImpl Repository
 
 // BookScooter ...
-func BookScooter(ctx context.Context, ScooterID, userID string) error {
-	return repositoryImpl.BookScooter(ctx, ScooterID, userID)
+func BookScooter(ctx context.Context, ScooterID, userID string, surge float64, near *models.Proximity) (*models.Trip, error) {
+	return repositoryImpl.BookScooter(ctx, ScooterID, userID, surge, near)
 }
 
 // ListAvailableScooter ...
@@ -41,9 +255,39 @@ func ListAvailableScooter(ctx context.Context) ([]models.ScooterInfo, error) {
 	return repositoryImpl.ListAvailableScooter(ctx)
 }
 
+// SetScooterStatus ...
+func SetScooterStatus(ctx context.Context, scooterID string, status models.ScooterStatus) (*models.ScooterInfo, error) {
+	return repositoryImpl.SetScooterStatus(ctx, scooterID, status)
+}
+
+// ListAvailableScooterInZone ...
+func ListAvailableScooterInZone(ctx context.Context, zone string) ([]models.ScooterInfo, error) {
+	return repositoryImpl.ListAvailableScooterInZone(ctx, zone)
+}
+
+// ListAvailableScooterNear ...
+func ListAvailableScooterNear(ctx context.Context, center models.Location, radius float64, limit int) ([]models.NearbyScooter, error) {
+	return repositoryImpl.ListAvailableScooterNear(ctx, center, radius, limit)
+}
+
 // CreateScooter ...
-func CreateScooter(ctx context.Context, scooterID string) error {
-	return repositoryImpl.CreateScooter(ctx, scooterID)
+func CreateScooter(ctx context.Context, scooter *models.ScooterInfo) error {
+	return repositoryImpl.CreateScooter(ctx, scooter)
+}
+
+// CreatePricePlan ...
+func CreatePricePlan(ctx context.Context, plan *models.PricePlan) error {
+	return repositoryImpl.CreatePricePlan(ctx, plan)
+}
+
+// ListPricePlans ...
+func ListPricePlans(ctx context.Context) ([]models.PricePlan, error) {
+	return repositoryImpl.ListPricePlans(ctx)
+}
+
+// PricePlanAt ...
+func PricePlanAt(ctx context.Context, city, vehicleType string, at time.Time) (*models.PricePlan, error) {
+	return repositoryImpl.PricePlanAt(ctx, city, vehicleType, at)
 }
 
 // CreateUser ...
@@ -51,16 +295,96 @@ func CreateUser(ctx context.Context, user *models.User) error {
 	return repositoryImpl.CreateUser(ctx, user)
 }
 
-// UpdateScooterCoordinates ...
-func UpdateScooterCoordinates(ctx context.Context, scooterID string, coordinates int64) error {
-	return repositoryImpl.UpdateScooterCoordinates(ctx, scooterID, coordinates)
+// ScooterTrack ...
+func ScooterTrack(ctx context.Context, scooterID string, from, to time.Time) ([]models.LocationSample, error) {
+	return repositoryImpl.ScooterTrack(ctx, scooterID, from, to)
+}
+
+// MaintainLocationHistory ...
+func MaintainLocationHistory(ctx context.Context, now time.Time, retention time.Duration) error {
+	return repositoryImpl.MaintainLocationHistory(ctx, now, retention)
 }
 
 // ReleaseScooter ...
-func ReleaseScooter(ctx context.Context, userID string) error {
+func ReleaseScooter(ctx context.Context, userID string) (*models.Trip, error) {
 	return repositoryImpl.ReleaseScooter(ctx, userID)
 }
 
+// GetTrip ...
+func GetTrip(ctx context.Context, tripID string) (*models.Trip, error) {
+	return repositoryImpl.GetTrip(ctx, tripID)
+}
+
+// ListUserTrips ...
+func ListUserTrips(ctx context.Context, userID string) ([]models.Trip, error) {
+	return repositoryImpl.ListUserTrips(ctx, userID)
+}
+
+// CountActiveReservations ...
+func CountActiveReservations(ctx context.Context, zone string) (int, error) {
+	return repositoryImpl.CountActiveReservations(ctx, zone)
+}
+
+// ReserveScooter ...
+func ReserveScooter(ctx context.Context, scooterID, userID string, hold time.Duration, surge float64) (*models.Reservation, error) {
+	return repositoryImpl.ReserveScooter(ctx, scooterID, userID, hold, surge)
+}
+
+// GetReservation ...
+func GetReservation(ctx context.Context, reservationID string) (*models.Reservation, error) {
+	return repositoryImpl.GetReservation(ctx, reservationID)
+}
+
+// ConvertReservation ...
+func ConvertReservation(ctx context.Context, reservationID, userID string, near *models.Proximity) (*models.Trip, error) {
+	return repositoryImpl.ConvertReservation(ctx, reservationID, userID, near)
+}
+
+// CancelReservation ...
+func CancelReservation(ctx context.Context, reservationID, userID string) (*models.Reservation, error) {
+	return repositoryImpl.CancelReservation(ctx, reservationID, userID)
+}
+
+// ExpireReservations ...
+func ExpireReservations(ctx context.Context, now time.Time) (int, error) {
+	return repositoryImpl.ExpireReservations(ctx, now)
+}
+
+// CreateCheckout ...
+func CreateCheckout(ctx context.Context, checkout *models.Checkout) error {
+	return repositoryImpl.CreateCheckout(ctx, checkout)
+}
+
+// UpdateCheckout ...
+func UpdateCheckout(ctx context.Context, checkout *models.Checkout) error {
+	return repositoryImpl.UpdateCheckout(ctx, checkout)
+}
+
+// GetCheckout ...
+func GetCheckout(ctx context.Context, checkoutID string) (*models.Checkout, error) {
+	return repositoryImpl.GetCheckout(ctx, checkoutID)
+}
+
+// CreatePaymentSaga ...
+func CreatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error {
+	return repositoryImpl.CreatePaymentSaga(ctx, saga)
+}
+
+// UpdatePaymentSaga ...
+func UpdatePaymentSaga(ctx context.Context, saga *models.PaymentSaga) error {
+	return repositoryImpl.UpdatePaymentSaga(ctx, saga)
+}
+
+// PaymentSagaByTrip ...
+func PaymentSagaByTrip(ctx context.Context, tripID string) (*models.PaymentSaga, error) {
+	return repositoryImpl.PaymentSagaByTrip(ctx, tripID)
+}
+
+// ListStalePaymentSagas ...
+func ListStalePaymentSagas(ctx context.Context, before time.Time) ([]models.PaymentSaga, error) {
+	return repositoryImpl.ListStalePaymentSagas(ctx, before)
+}
+
 // Close ...
 func Close() {
 	repositoryImpl.Close()
@@ -70,3 +394,218 @@ func Close() {
 func SetRepository(repository Repository) {
 	repositoryImpl = repository
 }
+
+// PostLedgerTransaction ...
+func PostLedgerTransaction(ctx context.Context, tx *models.LedgerTransaction) error {
+	return repositoryImpl.PostLedgerTransaction(ctx, tx)
+}
+
+// GetWallet ...
+func GetWallet(ctx context.Context, userID string) (*models.Wallet, error) {
+	return repositoryImpl.GetWallet(ctx, userID)
+}
+
+// ListLedgerTransactions ...
+func ListLedgerTransactions(ctx context.Context, userID string) ([]models.LedgerTransaction, error) {
+	return repositoryImpl.ListLedgerTransactions(ctx, userID)
+}
+
+// CapturedCheckout ...
+func CapturedCheckout(ctx context.Context, tripID string) (*models.Checkout, error) {
+	return repositoryImpl.CapturedCheckout(ctx, tripID)
+}
+
+// CreateDispute ...
+func CreateDispute(ctx context.Context, dispute *models.Dispute) error {
+	return repositoryImpl.CreateDispute(ctx, dispute)
+}
+
+// GetDispute ...
+func GetDispute(ctx context.Context, disputeID string) (*models.Dispute, error) {
+	return repositoryImpl.GetDispute(ctx, disputeID)
+}
+
+// ListDisputes ...
+func ListDisputes(ctx context.Context, status models.DisputeStatus) ([]models.Dispute, error) {
+	return repositoryImpl.ListDisputes(ctx, status)
+}
+
+// UpdateDispute ...
+func UpdateDispute(ctx context.Context, dispute *models.Dispute, event models.DisputeEvent, refund *models.LedgerTransaction) error {
+	return repositoryImpl.UpdateDispute(ctx, dispute, event, refund)
+}
+
+// CreatePromoCode ...
+func CreatePromoCode(ctx context.Context, promo *models.PromoCode) error {
+	return repositoryImpl.CreatePromoCode(ctx, promo)
+}
+
+// ListPromoCodes ...
+func ListPromoCodes(ctx context.Context) ([]models.PromoCode, error) {
+	return repositoryImpl.ListPromoCodes(ctx)
+}
+
+// RedeemPromoCode ...
+func RedeemPromoCode(ctx context.Context, code, userID string, at time.Time) (*models.PromoRedemption, error) {
+	return repositoryImpl.RedeemPromoCode(ctx, code, userID, at)
+}
+
+// ListPromoRedemptions ...
+func ListPromoRedemptions(ctx context.Context, userID string) ([]models.PromoRedemption, error) {
+	return repositoryImpl.ListPromoRedemptions(ctx, userID)
+}
+
+// CreatePassPlan ...
+func CreatePassPlan(ctx context.Context, plan *models.PassPlan) error {
+	return repositoryImpl.CreatePassPlan(ctx, plan)
+}
+
+// ListPassPlans ...
+func ListPassPlans(ctx context.Context) ([]models.PassPlan, error) {
+	return repositoryImpl.ListPassPlans(ctx)
+}
+
+// GetPassPlan ...
+func GetPassPlan(ctx context.Context, planID string) (*models.PassPlan, error) {
+	return repositoryImpl.GetPassPlan(ctx, planID)
+}
+
+// CreatePass ...
+func CreatePass(ctx context.Context, pass *models.Pass, payment *models.LedgerTransaction) error {
+	return repositoryImpl.CreatePass(ctx, pass, payment)
+}
+
+// ListRenewablePasses ...
+func ListRenewablePasses(ctx context.Context, before time.Time) ([]models.Pass, error) {
+	return repositoryImpl.ListRenewablePasses(ctx, before)
+}
+
+// StopPassRenewal ...
+func StopPassRenewal(ctx context.Context, passID string) error {
+	return repositoryImpl.StopPassRenewal(ctx, passID)
+}
+
+// ListPassAllowances ...
+func ListPassAllowances(ctx context.Context, userID string, at time.Time) ([]models.PassAllowance, error) {
+	return repositoryImpl.ListPassAllowances(ctx, userID, at)
+}
+
+// ListLedgerTransactionsBetween ...
+func ListLedgerTransactionsBetween(ctx context.Context, from, to time.Time) ([]models.LedgerTransaction, error) {
+	return repositoryImpl.ListLedgerTransactionsBetween(ctx, from, to)
+}
+
+// SetTaxRule ...
+func SetTaxRule(ctx context.Context, rule *models.TaxRule) error {
+	return repositoryImpl.SetTaxRule(ctx, rule)
+}
+
+// ListTaxRules ...
+func ListTaxRules(ctx context.Context) ([]models.TaxRule, error) {
+	return repositoryImpl.ListTaxRules(ctx)
+}
+
+// ListCharges ...
+func ListCharges(ctx context.Context, from, to time.Time) ([]models.Charge, error) {
+	return repositoryImpl.ListCharges(ctx, from, to)
+}
+
+// CreateInvoice ...
+func CreateInvoice(ctx context.Context, invoice *models.Invoice) error {
+	return repositoryImpl.CreateInvoice(ctx, invoice)
+}
+
+// GetInvoice ...
+func GetInvoice(ctx context.Context, invoiceID string) (*models.Invoice, error) {
+	return repositoryImpl.GetInvoice(ctx, invoiceID)
+}
+
+// ListUserInvoices ...
+func ListUserInvoices(ctx context.Context, userID string) ([]models.Invoice, error) {
+	return repositoryImpl.ListUserInvoices(ctx, userID)
+}
+
+// CreateZone ...
+func CreateZone(ctx context.Context, zone *models.Zone) error {
+	return repositoryImpl.CreateZone(ctx, zone)
+}
+
+// UpdateZone ...
+func UpdateZone(ctx context.Context, zone *models.Zone) error {
+	return repositoryImpl.UpdateZone(ctx, zone)
+}
+
+// DeleteZone ...
+func DeleteZone(ctx context.Context, zoneID string) error {
+	return repositoryImpl.DeleteZone(ctx, zoneID)
+}
+
+// GetZone ...
+func GetZone(ctx context.Context, zoneID string) (*models.Zone, error) {
+	return repositoryImpl.GetZone(ctx, zoneID)
+}
+
+// ListZones ...
+func ListZones(ctx context.Context) ([]models.Zone, error) {
+	return repositoryImpl.ListZones(ctx)
+}
+
+// ListZoneAlerts ...
+func ListZoneAlerts(ctx context.Context, from, to time.Time) ([]models.ZoneAlert, error) {
+	return repositoryImpl.ListZoneAlerts(ctx, from, to)
+}
+
+// CreateStation ...
+func CreateStation(ctx context.Context, station *models.Station) error {
+	return repositoryImpl.CreateStation(ctx, station)
+}
+
+// ListStations ...
+func ListStations(ctx context.Context, city string) ([]models.StationOccupancy, error) {
+	return repositoryImpl.ListStations(ctx, city)
+}
+
+// GetScooter ...
+func GetScooter(ctx context.Context, scooterID string) (*models.ScooterInfo, error) {
+	return repositoryImpl.GetScooter(ctx, scooterID)
+}
+
+// RecordTelemetry ...
+func RecordTelemetry(ctx context.Context, scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error) {
+	return repositoryImpl.RecordTelemetry(ctx, scooterID, samples)
+}
+
+// RecordPositions ...
+func RecordPositions(ctx context.Context, samples []models.LocationSample) (int, error) {
+	return repositoryImpl.RecordPositions(ctx, samples)
+}
+
+// CreateCommand ...
+func CreateCommand(ctx context.Context, command *models.Command) error {
+	return repositoryImpl.CreateCommand(ctx, command)
+}
+
+// GetCommand ...
+func GetCommand(ctx context.Context, commandID string) (*models.Command, error) {
+	return repositoryImpl.GetCommand(ctx, commandID)
+}
+
+// ListCommands ...
+func ListCommands(ctx context.Context, scooterID string) ([]models.Command, error) {
+	return repositoryImpl.ListCommands(ctx, scooterID)
+}
+
+// PollCommands ...
+func PollCommands(ctx context.Context, scooterID string, now time.Time) ([]models.Command, error) {
+	return repositoryImpl.PollCommands(ctx, scooterID, now)
+}
+
+// AckCommand ...
+func AckCommand(ctx context.Context, scooterID, commandID string, ack models.CommandAck, now time.Time) (*models.Command, error) {
+	return repositoryImpl.AckCommand(ctx, scooterID, commandID, ack, now)
+}
+
+// ExpireCommands ...
+func ExpireCommands(ctx context.Context, now time.Time) (int, error) {
+	return repositoryImpl.ExpireCommands(ctx, now)
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"scootin/geo"
+	"scootin/models"
+)
+
+// bookable checks the scooter's status allows a rider to book it
+func bookable(status models.ScooterStatus, scooterID, userID string) error {
+	switch {
+	case status.Ridden():
+		return models.Errorf(ErrScooterOccupied, "we can't book the scooter %s for user %s as it's already occupied", scooterID, userID)
+	case status != models.StatusAvailable:
+		return models.Errorf(ErrScooterUnavailable, "scooter %s is %s", scooterID, status)
+	}
+	return nil
+}
+
+// nearby checks the scooter at the location is within the rider's reach, a nil proximity isn't checked
+func nearby(near *models.Proximity, scooterID string, location models.Location) error {
+	if near == nil {
+		return nil
+	}
+	if d := geo.Distance(near.Position, location); d > near.MaxDistance {
+		return models.Errorf(ErrScooterTooFar, "scooter %s is %.0fm away", scooterID, d)
+	}
+	return nil
+}
+
+// operationalTransition checks an operator may move the scooter from its status to the next one,
+// the riders' states are only entered and left through the bookings.
+func operationalTransition(from, to models.ScooterStatus, scooterID string) error {
+	if !to.Operational() || from.Ridden() || !from.CanTransitionTo(to) {
+		return models.Errorf(ErrInvalidTransition, "scooter %s can't move from %s to %s", scooterID, from, to)
+	}
+	return nil
+}
@@ -1,17 +1,473 @@
 package db
 
-var (
-	scooterTable = `CREATE TABLE IF NOT EXISTS scooters
+// the schema is changed only through the versioned migrations,
+// a new change gets a new migration and the applied ones are never edited.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_scooters",
+		Up: `CREATE TABLE IF NOT EXISTS scooters
 (
     id           TEXT          NOT NULL PRIMARY KEY,
 	coordinate   INT,
     user_id       TEXT
-);`
-
-	userTable = `CREATE TABLE IF NOT EXISTS users
+);`,
+		Down: `DROP TABLE IF EXISTS scooters;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_users",
+		Up: `CREATE TABLE IF NOT EXISTS users
 (
     id           TEXT   NOT NULL PRIMARY KEY,
 	Name         TEXT   NOT NULL,
     email        TEXT   NOT NULL
-);`
-)
+);`,
+		Down: `DROP TABLE IF EXISTS users;`,
+	},
+	{
+		Version: 3,
+		Name:    "create_trips",
+		Up: `CREATE TABLE trips
+(
+    id                 TEXT          NOT NULL PRIMARY KEY,
+    scooter_id         TEXT          NOT NULL REFERENCES scooters(id),
+    user_id            TEXT          NOT NULL,
+    started_at         TIMESTAMPTZ   NOT NULL,
+    ended_at           TIMESTAMPTZ,
+    start_coordinate   INT,
+    end_coordinate     INT
+);
+CREATE INDEX trips_user_id_started_at_idx ON trips(user_id, started_at);`,
+		Down: `DROP TABLE IF EXISTS trips;`,
+	},
+	{
+		Version: 4,
+		Name:    "create_scooter_locations",
+		// the partitions are daily and created by the repository when needed, see postgres_location.go
+		Up: `CREATE TABLE scooter_locations
+(
+    scooter_id    TEXT          NOT NULL,
+    recorded_at   TIMESTAMPTZ   NOT NULL,
+    coordinate    INT           NOT NULL
+) PARTITION BY RANGE (recorded_at);`,
+		Down: `DROP TABLE IF EXISTS scooter_locations;`,
+	},
+	{
+		Version: 5,
+		Name:    "scooters_geographic_locations",
+		// the former coordinate wasn't a position on the map, so it's dropped rather than converted.
+		// 's00000000' is the geohash of the default 0,0 location.
+		Up: `ALTER TABLE scooters DROP COLUMN coordinate,
+    ADD COLUMN latitude    DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    ADD COLUMN longitude   DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    ADD COLUMN geohash     TEXT               NOT NULL DEFAULT 's00000000';
+CREATE INDEX scooters_geohash_idx ON scooters(geohash text_pattern_ops);
+ALTER TABLE trips DROP COLUMN start_coordinate, DROP COLUMN end_coordinate,
+    ADD COLUMN start_latitude    DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    ADD COLUMN start_longitude   DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    ADD COLUMN end_latitude      DOUBLE PRECISION,
+    ADD COLUMN end_longitude     DOUBLE PRECISION;
+ALTER TABLE scooter_locations DROP COLUMN coordinate,
+    ADD COLUMN latitude    DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    ADD COLUMN longitude   DOUBLE PRECISION   NOT NULL DEFAULT 0;`,
+		Down: `DROP INDEX IF EXISTS scooters_geohash_idx;
+ALTER TABLE scooters DROP COLUMN latitude, DROP COLUMN longitude, DROP COLUMN geohash,
+    ADD COLUMN coordinate INT DEFAULT 1;
+ALTER TABLE trips DROP COLUMN start_latitude, DROP COLUMN start_longitude, DROP COLUMN end_latitude, DROP COLUMN end_longitude,
+    ADD COLUMN start_coordinate INT, ADD COLUMN end_coordinate INT;
+ALTER TABLE scooter_locations DROP COLUMN latitude, DROP COLUMN longitude,
+    ADD COLUMN coordinate INT NOT NULL DEFAULT 1;`,
+	},
+	{
+		Version: 6,
+		Name:    "trips_one_active_per_user",
+		// the older open trips of a user left by the former unrestricted bookings are ended first
+		Up: `UPDATE trips t SET ended_at = now() WHERE t.ended_at IS NULL AND EXISTS (
+    SELECT 1 FROM trips o WHERE o.user_id = t.user_id AND o.ended_at IS NULL AND (o.started_at, o.id) > (t.started_at, t.id)
+);
+CREATE UNIQUE INDEX trips_one_active_per_user_idx ON trips(user_id) WHERE ended_at IS NULL;
+CREATE UNIQUE INDEX trips_one_active_per_scooter_idx ON trips(scooter_id) WHERE ended_at IS NULL;
+ALTER TABLE trips ADD CONSTRAINT trips_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) NOT VALID;`,
+		Down: `ALTER TABLE trips DROP CONSTRAINT IF EXISTS trips_user_id_fkey;
+DROP INDEX IF EXISTS trips_one_active_per_scooter_idx;
+DROP INDEX IF EXISTS trips_one_active_per_user_idx;`,
+	},
+	{
+		Version: 7,
+		Name:    "scooters_status",
+		Up: `ALTER TABLE scooters
+    ADD COLUMN status     TEXT   NOT NULL DEFAULT 'available'
+        CHECK (status IN ('available','reserved','in_trip','maintenance','low_battery','offline','retired')),
+    ADD COLUMN rider_id   TEXT;
+UPDATE scooters SET status = 'in_trip', rider_id = user_id WHERE user_id <> 'NOT_OCCUPIED';
+ALTER TABLE scooters DROP COLUMN user_id,
+    ADD CONSTRAINT scooters_rider_id_fkey FOREIGN KEY (rider_id) REFERENCES users(id) NOT VALID,
+    ADD CONSTRAINT scooters_rider_status_check CHECK ((rider_id IS NOT NULL) = (status IN ('reserved','in_trip')));
+CREATE INDEX scooters_status_idx ON scooters(status);`,
+		Down: `ALTER TABLE scooters ADD COLUMN user_id TEXT;
+UPDATE scooters SET user_id = COALESCE(rider_id, 'NOT_OCCUPIED');
+ALTER TABLE scooters DROP COLUMN rider_id, DROP COLUMN status;`,
+	},
+	{
+		Version: 8,
+		Name:    "reservations",
+		Up: `CREATE TABLE reservations
+(
+    id           TEXT          NOT NULL PRIMARY KEY,
+    scooter_id   TEXT          NOT NULL REFERENCES scooters(id),
+    user_id      TEXT          NOT NULL REFERENCES users(id),
+    status       TEXT          NOT NULL CHECK (status IN ('active','converted','cancelled','expired')),
+    created_at   TIMESTAMPTZ   NOT NULL,
+    expires_at   TIMESTAMPTZ   NOT NULL,
+    ended_at     TIMESTAMPTZ,
+    trip_id      TEXT          REFERENCES trips(id)
+);
+CREATE UNIQUE INDEX reservations_one_active_per_user_idx ON reservations(user_id) WHERE status = 'active';
+CREATE UNIQUE INDEX reservations_one_active_per_scooter_idx ON reservations(scooter_id) WHERE status = 'active';
+CREATE INDEX reservations_active_expiry_idx ON reservations(expires_at) WHERE status = 'active';`,
+		// the scooters still held by the dropped reservations are made available again
+		Down: `UPDATE scooters SET status = 'available', rider_id = NULL WHERE status = 'reserved';
+DROP TABLE IF EXISTS reservations;`,
+	},
+	{
+		Version: 9,
+		Name:    "price_plans",
+		// the seeded plan is the repositories' defaultPricePlan
+		Up: `ALTER TABLE scooters
+    ADD COLUMN city           TEXT   NOT NULL DEFAULT '',
+    ADD COLUMN vehicle_type   TEXT   NOT NULL DEFAULT 'scooter';
+ALTER TABLE trips ADD COLUMN fare JSONB;
+CREATE TABLE price_plans
+(
+    id               TEXT          NOT NULL PRIMARY KEY,
+    city             TEXT          NOT NULL,
+    vehicle_type     TEXT          NOT NULL,
+    version          INT           NOT NULL,
+    currency         CHAR(3)       NOT NULL,
+    unlock_fee       BIGINT        NOT NULL CHECK (unlock_fee >= 0),
+    per_minute       BIGINT        NOT NULL CHECK (per_minute >= 0),
+    per_kilometer    BIGINT        NOT NULL CHECK (per_kilometer >= 0),
+    effective_from   TIMESTAMPTZ   NOT NULL,
+    created_at       TIMESTAMPTZ   NOT NULL DEFAULT now(),
+    UNIQUE (city, vehicle_type, version)
+);
+INSERT INTO price_plans(id,city,vehicle_type,version,currency,unlock_fee,per_minute,per_kilometer,effective_from)
+VALUES ('default-scooter-v1', '', 'scooter', 1, 'EUR', 100, 25, 0, '1970-01-01T00:00:00Z');`,
+		Down: `DROP TABLE IF EXISTS price_plans;
+ALTER TABLE trips DROP COLUMN fare;
+ALTER TABLE scooters DROP COLUMN city, DROP COLUMN vehicle_type;`,
+	},
+	{
+		Version: 10,
+		Name:    "checkouts",
+		Up: `CREATE TABLE checkouts
+(
+    id                   TEXT          NOT NULL PRIMARY KEY,
+    trip_id              TEXT          NOT NULL REFERENCES trips(id),
+    user_id              TEXT          NOT NULL REFERENCES users(id),
+    merchant_code        TEXT          NOT NULL,
+    amount               BIGINT        NOT NULL CHECK (amount >= 0),
+    currency             CHAR(3)       NOT NULL,
+    status               TEXT          NOT NULL CHECK (status IN ('pending','captured','declined','failed')),
+    provider_reference   TEXT          NOT NULL DEFAULT '',
+    failure_code         TEXT          NOT NULL DEFAULT '',
+    created_at           TIMESTAMPTZ   NOT NULL,
+    completed_at         TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX checkouts_one_per_trip_idx ON checkouts(trip_id) WHERE status IN ('pending','captured');`,
+		Down: `DROP TABLE IF EXISTS checkouts;`,
+	},
+	{
+		Version: 11,
+		Name:    "payment_sagas",
+		Up: `CREATE TABLE payment_sagas
+(
+    id                 TEXT          NOT NULL PRIMARY KEY,
+    user_id            TEXT          NOT NULL REFERENCES users(id),
+    scooter_id         TEXT          NOT NULL REFERENCES scooters(id),
+    trip_id            TEXT          REFERENCES trips(id),
+    checkout_id        TEXT          REFERENCES checkouts(id),
+    authorization_id   TEXT          NOT NULL DEFAULT '',
+    hold_amount        BIGINT        NOT NULL CHECK (hold_amount >= 0),
+    currency           CHAR(3)       NOT NULL,
+    step               TEXT          NOT NULL
+        CHECK (step IN ('authorizing','authorized','booked','capturing','captured','cancelling','cancelled','failed')),
+    failure_code       TEXT          NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ   NOT NULL,
+    updated_at         TIMESTAMPTZ   NOT NULL
+);
+CREATE UNIQUE INDEX payment_sagas_trip_idx ON payment_sagas(trip_id);
+CREATE INDEX payment_sagas_unfinished_idx ON payment_sagas(updated_at) WHERE step NOT IN ('captured','cancelled','failed');`,
+		Down: `DROP TABLE IF EXISTS payment_sagas;`,
+	},
+	{
+		Version: 12,
+		Name:    "ledger",
+		Up: `CREATE TABLE ledger_transactions
+(
+    id           TEXT          NOT NULL PRIMARY KEY,
+    kind         TEXT          NOT NULL CHECK (kind IN ('fare','payment','top_up','refund','promo')),
+    user_id      TEXT          NOT NULL REFERENCES users(id),
+    currency     CHAR(3)       NOT NULL,
+    reference    TEXT          NOT NULL,
+    created_at   TIMESTAMPTZ   NOT NULL,
+    UNIQUE (kind, reference)
+);
+CREATE INDEX ledger_transactions_user_idx ON ledger_transactions(user_id, created_at);
+CREATE TABLE ledger_entries
+(
+    transaction_id   TEXT     NOT NULL REFERENCES ledger_transactions(id),
+    position         INT      NOT NULL,
+    account          TEXT     NOT NULL,
+    amount           BIGINT   NOT NULL,
+    PRIMARY KEY (transaction_id, position)
+);
+CREATE INDEX ledger_entries_account_idx ON ledger_entries(account);
+CREATE FUNCTION ledger_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'the ledger postings are immutable';
+END;
+$$ LANGUAGE plpgsql;
+CREATE TRIGGER ledger_transactions_immutable BEFORE UPDATE OR DELETE ON ledger_transactions
+    FOR EACH ROW EXECUTE FUNCTION ledger_immutable();
+CREATE TRIGGER ledger_entries_immutable BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION ledger_immutable();`,
+		Down: `DROP TABLE IF EXISTS ledger_entries;
+DROP TABLE IF EXISTS ledger_transactions;
+DROP FUNCTION IF EXISTS ledger_immutable();`,
+	},
+	{
+		Version: 13,
+		Name:    "disputes",
+		Up: `CREATE TABLE disputes
+(
+    id                 TEXT          NOT NULL PRIMARY KEY,
+    trip_id            TEXT          NOT NULL UNIQUE REFERENCES trips(id),
+    user_id            TEXT          NOT NULL REFERENCES users(id),
+    reason             TEXT          NOT NULL,
+    status             TEXT          NOT NULL CHECK (status IN ('open','under_review','refunded','rejected')),
+    refund_amount      BIGINT        NOT NULL DEFAULT 0 CHECK (refund_amount >= 0),
+    currency           TEXT          NOT NULL DEFAULT '',
+    refund_reference   TEXT          NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ   NOT NULL,
+    updated_at         TIMESTAMPTZ   NOT NULL
+);
+CREATE INDEX disputes_status_idx ON disputes(status, created_at);
+CREATE TABLE dispute_events
+(
+    id           BIGSERIAL     NOT NULL PRIMARY KEY,
+    dispute_id   TEXT          NOT NULL REFERENCES disputes(id),
+    status       TEXT          NOT NULL,
+    actor        TEXT          NOT NULL,
+    note         TEXT          NOT NULL DEFAULT '',
+    at           TIMESTAMPTZ   NOT NULL
+);
+CREATE INDEX dispute_events_dispute_idx ON dispute_events(dispute_id);`,
+		Down: `DROP TABLE IF EXISTS dispute_events;
+DROP TABLE IF EXISTS disputes;`,
+	},
+	{
+		Version: 14,
+		Name:    "promo_codes",
+		Up: `CREATE TABLE promo_codes
+(
+    code              TEXT          NOT NULL PRIMARY KEY,
+    kind              TEXT          NOT NULL CHECK (kind IN ('percent_off','amount_off','voucher')),
+    percent           BIGINT        NOT NULL DEFAULT 0 CHECK (percent BETWEEN 0 AND 100),
+    amount            BIGINT        NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    currency          TEXT          NOT NULL DEFAULT '',
+    target            TEXT          NOT NULL DEFAULT '',
+    city              TEXT          NOT NULL DEFAULT '',
+    first_ride_only   BOOLEAN       NOT NULL DEFAULT FALSE,
+    max_redemptions   INT           NOT NULL DEFAULT 0 CHECK (max_redemptions >= 0),
+    per_user_limit    INT           NOT NULL DEFAULT 0 CHECK (per_user_limit >= 0),
+    expires_at        TIMESTAMPTZ,
+    redemptions       INT           NOT NULL DEFAULT 0,
+    created_at        TIMESTAMPTZ   NOT NULL
+);
+CREATE TABLE promo_redemptions
+(
+    code          TEXT          NOT NULL REFERENCES promo_codes(code),
+    user_id       TEXT          NOT NULL REFERENCES users(id),
+    uses          INT           NOT NULL DEFAULT 0,
+    redeemed_at   TIMESTAMPTZ   NOT NULL,
+    PRIMARY KEY (code, user_id)
+);
+CREATE INDEX promo_redemptions_user_idx ON promo_redemptions(user_id);`,
+		Down: `DROP TABLE IF EXISTS promo_redemptions;
+DROP TABLE IF EXISTS promo_codes;`,
+	},
+	{
+		Version: 15,
+		Name:    "passes",
+		Up: `CREATE TABLE pass_plans
+(
+    id             TEXT          NOT NULL PRIMARY KEY,
+    name           TEXT          NOT NULL,
+    period         TEXT          NOT NULL CHECK (period IN ('day','month')),
+    price          BIGINT        NOT NULL CHECK (price >= 0),
+    currency       CHAR(3)       NOT NULL,
+    free_unlocks   INT           NOT NULL DEFAULT 0 CHECK (free_unlocks >= 0),
+    free_minutes   BIGINT        NOT NULL DEFAULT 0 CHECK (free_minutes >= 0),
+    created_at     TIMESTAMPTZ   NOT NULL
+);
+CREATE TABLE passes
+(
+    id                  TEXT          NOT NULL PRIMARY KEY,
+    plan_id             TEXT          NOT NULL REFERENCES pass_plans(id),
+    user_id             TEXT          NOT NULL REFERENCES users(id),
+    starts_at           TIMESTAMPTZ   NOT NULL,
+    ends_at             TIMESTAMPTZ   NOT NULL,
+    auto_renew          BOOLEAN       NOT NULL DEFAULT FALSE,
+    renewal_of          TEXT          UNIQUE REFERENCES passes(id),
+    payment_reference   TEXT          NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ   NOT NULL,
+    CHECK (starts_at < ends_at)
+);
+CREATE INDEX passes_user_idx ON passes(user_id, ends_at);
+CREATE INDEX passes_renewal_idx ON passes(ends_at) WHERE auto_renew;
+CREATE TABLE pass_usage
+(
+    pass_id   TEXT     NOT NULL REFERENCES passes(id),
+    day       TEXT     NOT NULL,
+    unlocks   INT      NOT NULL DEFAULT 0,
+    minutes   BIGINT   NOT NULL DEFAULT 0,
+    PRIMARY KEY (pass_id, day)
+);
+ALTER TABLE ledger_transactions DROP CONSTRAINT ledger_transactions_kind_check,
+    ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN ('fare','payment','top_up','refund','promo','pass'));`,
+		Down: `ALTER TABLE ledger_transactions DROP CONSTRAINT ledger_transactions_kind_check,
+    ADD CONSTRAINT ledger_transactions_kind_check CHECK (kind IN ('fare','payment','top_up','refund','promo')) NOT VALID;
+DROP TABLE IF EXISTS pass_usage;
+DROP TABLE IF EXISTS passes;
+DROP TABLE IF EXISTS pass_plans;`,
+	},
+	{
+		Version: 16,
+		Name:    "surge",
+		Up: `ALTER TABLE trips ADD COLUMN surge DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (surge > 0);
+ALTER TABLE reservations ADD COLUMN surge DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (surge > 0);`,
+		Down: `ALTER TABLE reservations DROP COLUMN surge;
+ALTER TABLE trips DROP COLUMN surge;`,
+	},
+	{
+		Version: 17,
+		Name:    "invoices",
+		Up: `CREATE TABLE tax_rules
+(
+    city           TEXT     NOT NULL PRIMARY KEY,
+    legal_entity   TEXT     NOT NULL,
+    entity_name    TEXT     NOT NULL,
+    vat_id         TEXT     NOT NULL DEFAULT '',
+    vat_rate       BIGINT   NOT NULL CHECK (vat_rate BETWEEN 0 AND 10000)
+);
+CREATE TABLE invoice_sequences
+(
+    legal_entity   TEXT     NOT NULL PRIMARY KEY,
+    last           BIGINT   NOT NULL
+);
+CREATE TABLE invoices
+(
+    id             TEXT          NOT NULL PRIMARY KEY,
+    number         TEXT          NOT NULL UNIQUE,
+    sequence       BIGINT        NOT NULL,
+    legal_entity   TEXT          NOT NULL,
+    entity_name    TEXT          NOT NULL,
+    vat_id         TEXT          NOT NULL DEFAULT '',
+    user_id        TEXT          NOT NULL REFERENCES users(id),
+    period         CHAR(7)       NOT NULL,
+    currency       CHAR(3)       NOT NULL,
+    lines          JSONB         NOT NULL,
+    net            BIGINT        NOT NULL,
+    vat            BIGINT        NOT NULL,
+    total          BIGINT        NOT NULL,
+    issued_at      TIMESTAMPTZ   NOT NULL,
+    UNIQUE (legal_entity, sequence),
+    UNIQUE (user_id, period, legal_entity, currency)
+);
+CREATE INDEX trips_ended_at_idx ON trips(ended_at) WHERE ended_at IS NOT NULL;
+CREATE INDEX ledger_transactions_created_at_idx ON ledger_transactions(created_at);`,
+		Down: `DROP INDEX IF EXISTS ledger_transactions_created_at_idx;
+DROP INDEX IF EXISTS trips_ended_at_idx;
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_sequences;
+DROP TABLE IF EXISTS tax_rules;`,
+	},
+	{
+		Version: 18,
+		Name:    "zones",
+		Up: `CREATE TABLE zones
+(
+    id             TEXT          NOT NULL PRIMARY KEY,
+    name           TEXT          NOT NULL DEFAULT '',
+    tags           TEXT[]        NOT NULL,
+    geometry       JSONB         NOT NULL,
+    speed_limit    INTEGER       NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ   NOT NULL,
+    updated_at     TIMESTAMPTZ   NOT NULL
+);
+CREATE TABLE zone_alerts
+(
+    id             TEXT               NOT NULL PRIMARY KEY,
+    scooter_id     TEXT               NOT NULL REFERENCES scooters(id),
+    user_id        TEXT               NOT NULL DEFAULT '',
+    zone_id        TEXT               NOT NULL,
+    tag            TEXT               NOT NULL,
+    latitude       DOUBLE PRECISION   NOT NULL,
+    longitude      DOUBLE PRECISION   NOT NULL,
+    raised_at      TIMESTAMPTZ        NOT NULL
+);
+CREATE INDEX zone_alerts_raised_at_idx ON zone_alerts(raised_at);`,
+		Down: `DROP TABLE IF EXISTS zone_alerts;
+DROP TABLE IF EXISTS zones;`,
+	},
+	{
+		Version: 19,
+		Name:    "stations",
+		Up: `CREATE TABLE stations
+(
+    id                 TEXT               NOT NULL PRIMARY KEY,
+    name               TEXT               NOT NULL DEFAULT '',
+    city               TEXT               NOT NULL DEFAULT '',
+    latitude           DOUBLE PRECISION   NOT NULL,
+    longitude          DOUBLE PRECISION   NOT NULL,
+    geohash            TEXT               NOT NULL,
+    radius             DOUBLE PRECISION   NOT NULL CHECK (radius > 0),
+    capacity           INTEGER            NOT NULL CHECK (capacity > 0),
+    discount_percent   BIGINT             NOT NULL CHECK (discount_percent BETWEEN 0 AND 100),
+    created_at         TIMESTAMPTZ        NOT NULL
+);
+CREATE INDEX stations_geohash_idx ON stations(geohash text_pattern_ops);`,
+		Down: `DROP TABLE IF EXISTS stations;`,
+	},
+	{
+		Version: 20,
+		Name:    "telemetry",
+		Up: `ALTER TABLE scooters ADD COLUMN reported_at TIMESTAMPTZ;
+ALTER TABLE scooter_locations ADD COLUMN speed DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN battery INTEGER NOT NULL DEFAULT 0;`,
+		Down: `ALTER TABLE scooter_locations DROP COLUMN speed, DROP COLUMN battery;
+ALTER TABLE scooters DROP COLUMN reported_at;`,
+	},
+	{
+		Version: 21,
+		Name:    "commands",
+		Up: `CREATE TABLE commands
+(
+    id             TEXT               NOT NULL PRIMARY KEY,
+    scooter_id     TEXT               NOT NULL REFERENCES scooters(id),
+    kind           TEXT               NOT NULL CHECK (kind IN ('lock', 'unlock', 'beep', 'speed-limit', 'reboot')),
+    speed_limit    DOUBLE PRECISION   NOT NULL DEFAULT 0,
+    state          TEXT               NOT NULL CHECK (state IN ('queued', 'delivered', 'acked', 'failed')),
+    reason         TEXT               NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ        NOT NULL,
+    expires_at     TIMESTAMPTZ        NOT NULL,
+    delivered_at   TIMESTAMPTZ,
+    ended_at       TIMESTAMPTZ
+);
+CREATE INDEX commands_scooter_idx ON commands(scooter_id, created_at);
+CREATE INDEX commands_pending_idx ON commands(expires_at) WHERE state IN ('queued', 'delivered');`,
+		Down: `DROP TABLE IF EXISTS commands;`,
+	},
+}
@@ -0,0 +1,166 @@
+// Package geo has the geographic calculations and the geohash spatial index helpers.
+package geo
+
+import (
+	"math"
+	"scootin/models"
+	"strings"
+)
+
+const (
+	// earthRadius is the mean earth radius in meters
+	earthRadius = 6371008.8
+	// metersPerDegree is the length of a latitude degree in meters
+	metersPerDegree = earthRadius * math.Pi / 180
+
+	// HashPrecision is the geohash length stored for the scooters, a cell of about 4.8m x 4.8m
+	HashPrecision = 9
+	// ZonePrecision is the geohash length of the pricing zones, a cell of about 1.2km x 0.6km
+	ZonePrecision = 6
+
+	base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+)
+
+// Valid reports whether the location is within the latitude and longitude ranges
+func Valid(l models.Location) bool {
+	return l.Latitude >= -90 && l.Latitude <= 90 && l.Longitude >= -180 && l.Longitude <= 180
+}
+
+// Distance returns the great-circle distance in meters between two locations
+func Distance(a, b models.Location) float64 {
+	lat1, lat2 := radians(a.Latitude), radians(b.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(b.Longitude - a.Longitude)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// PathLength returns the length in meters of the path going through the locations in order
+func PathLength(path []models.Location) float64 {
+	length := 0.0
+	for i := 1; i < len(path); i++ {
+		length += Distance(path[i-1], path[i])
+	}
+	return length
+}
+
+// Move returns the location reached after going the distance in meters towards the bearing in degrees
+func Move(l models.Location, distance, bearing float64) models.Location {
+	lat1, lon1 := radians(l.Latitude), radians(l.Longitude)
+	d := distance / earthRadius
+	b := radians(bearing)
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(b))
+	lon2 := lon1 + math.Atan2(math.Sin(b)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return models.Location{Latitude: degrees(lat2), Longitude: normalizeLongitude(degrees(lon2))}
+}
+
+// Encode returns the geohash of the location with the given length
+func Encode(l models.Location, precision int) string {
+	var (
+		sb             strings.Builder
+		latMin, latMax = -90.0, 90.0
+		lonMin, lonMax = -180.0, 180.0
+		even           = true // the even bits are longitude ones
+		bit, ch        = 0, 0
+	)
+	for sb.Len() < precision {
+		if even {
+			if mid := (lonMin + lonMax) / 2; l.Longitude >= mid {
+				ch = ch<<1 | 1
+				lonMin = mid
+			} else {
+				ch <<= 1
+				lonMax = mid
+			}
+		} else {
+			if mid := (latMin + latMax) / 2; l.Latitude >= mid {
+				ch = ch<<1 | 1
+				latMin = mid
+			} else {
+				ch <<= 1
+				latMax = mid
+			}
+		}
+		even = !even
+		if bit++; bit == 5 {
+			sb.WriteByte(base32[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return sb.String()
+}
+
+// Zone returns the pricing zone of the location, the geohash cell of ZonePrecision containing it
+func Zone(l models.Location) string {
+	return Encode(l, ZonePrecision)
+}
+
+// cellSize returns a geohash cell height and width in degrees for the given length
+func cellSize(precision int) (float64, float64) {
+	bits := 5 * precision
+	latBits, lonBits := bits/2, bits-bits/2
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// CoveringHashes returns the geohash cells covering the circle of radius meters around the center.
+// The cells are the smallest ones still as large as the radius, so only a handful are returned,
+// a location within the circle has a geohash starting with one of them.
+func CoveringHashes(center models.Location, radius float64) []string {
+	dLat := radius / metersPerDegree
+	dLon := 360.0
+	if cos := math.Cos(radians(center.Latitude)); cos > 1e-9 {
+		dLon = math.Min(360, dLat/cos)
+	}
+
+	precision := 1
+	for p := HashPrecision; p > 1; p-- {
+		if height, width := cellSize(p); height >= dLat && width >= dLon {
+			precision = p
+			break
+		}
+	}
+	height, width := cellSize(precision)
+
+	minLat, maxLat := math.Max(-90, center.Latitude-dLat), math.Min(90, center.Latitude+dLat)
+	minLon, maxLon := center.Longitude-dLon, center.Longitude+dLon
+
+	seen := make(map[string]bool)
+	hashes := make([]string, 0)
+	// stepping by a cell size never skips a cell, the last step is clamped to the box edge
+	for lat := minLat; ; lat = math.Min(lat+height, maxLat) {
+		for lon := minLon; ; lon = math.Min(lon+width, maxLon) {
+			h := Encode(models.Location{Latitude: lat, Longitude: normalizeLongitude(lon)}, precision)
+			if !seen[h] {
+				seen[h] = true
+				hashes = append(hashes, h)
+			}
+			if lon >= maxLon {
+				break
+			}
+		}
+		if lat >= maxLat {
+			break
+		}
+	}
+	return hashes
+}
+
+func normalizeLongitude(lon float64) float64 {
+	for lon > 180 {
+		lon -= 360
+	}
+	for lon < -180 {
+		lon += 360
+	}
+	return lon
+}
+
+func radians(d float64) float64 {
+	return d * math.Pi / 180
+}
+
+func degrees(r float64) float64 {
+	return r * 180 / math.Pi
+}
@@ -0,0 +1,45 @@
+package geo
+
+import (
+	"scootin/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, "s00000000", Encode(models.Location{}, HashPrecision))
+	// the reference example of the geohash definition
+	assert.Equal(t, "u4pruydqqvj", Encode(models.Location{Latitude: 57.64911, Longitude: 10.40744}, 11))
+}
+
+func TestDistanceAndMove(t *testing.T) {
+	berlin := models.Location{Latitude: 52.520008, Longitude: 13.404954}
+	paris := models.Location{Latitude: 48.856613, Longitude: 2.352222}
+	assert.InDelta(t, 877500, Distance(berlin, paris), 1000)
+
+	moved := Move(berlin, 250, 45)
+	assert.InDelta(t, 250, Distance(berlin, moved), 0.01)
+
+	// going there and back
+	assert.InDelta(t, 500, PathLength([]models.Location{berlin, moved, berlin}), 0.01)
+	assert.Zero(t, PathLength([]models.Location{berlin}))
+}
+
+func TestCoveringHashes(t *testing.T) {
+	center := models.Location{Latitude: 52.520008, Longitude: 13.404954}
+	for _, radius := range []float64{10, 500, 5000, 50000} {
+		cells := CoveringHashes(center, radius)
+		assert.LessOrEqual(t, len(cells), 16)
+		// any location within the radius falls inside one of the cells
+		for bearing := 0.0; bearing < 360; bearing += 15 {
+			hash := Encode(Move(center, radius*0.99, bearing), HashPrecision)
+			covered := false
+			for _, cell := range cells {
+				covered = covered || strings.HasPrefix(hash, cell)
+			}
+			assert.True(t, covered, "radius %v bearing %v", radius, bearing)
+		}
+	}
+}
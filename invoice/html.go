@@ -0,0 +1,54 @@
+package invoice
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"scootin/models"
+)
+
+// page is the printable invoice
+var page = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money":   money,
+	"percent": func(bp int64) string { return fmt.Sprintf("%d.%02d%%", bp/100, bp%100) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Invoice {{.Number}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { border-bottom: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+td.amount, th.amount { text-align: right; }
+@media print { body { margin: 0; } }
+</style>
+</head>
+<body>
+<h1>Invoice {{.Number}}</h1>
+<p>{{.EntityName}}{{if .VATID}}<br>VAT ID {{.VATID}}{{end}}</p>
+<p>Customer {{.UserID}}<br>Period {{.Period}}<br>Issued {{.IssuedAt.Format "2006-01-02"}}</p>
+<table>
+<tr><th>Date</th><th>Item</th><th>City</th><th class="amount">VAT rate</th><th class="amount">Net</th><th class="amount">VAT</th><th class="amount">Total</th></tr>
+{{- range .Lines}}
+<tr><td>{{.At.Format "2006-01-02 15:04"}}</td><td>{{.Kind}} {{.Reference}}</td><td>{{.City}}</td><td class="amount">{{percent .VATRate}}</td><td class="amount">{{money .Net $.Currency}}</td><td class="amount">{{money .VAT $.Currency}}</td><td class="amount">{{money .Gross $.Currency}}</td></tr>
+{{- end}}
+<tr><th colspan="4">Total</th><th class="amount">{{money .Net .Currency}}</th><th class="amount">{{money .VAT .Currency}}</th><th class="amount">{{money .Total .Currency}}</th></tr>
+</table>
+</body>
+</html>
+`))
+
+// WriteHTML renders the invoice as a printable HTML page
+func WriteHTML(w io.Writer, inv *models.Invoice) error {
+	return page.Execute(w, inv)
+}
+
+// money formats the minor units amount of the currency, e.g. "12.50 EUR"
+func money(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, amount/100, amount%100, currency)
+}
@@ -0,0 +1,101 @@
+// Package invoice builds and renders the users' monthly invoices.
+package invoice
+
+import (
+	"fmt"
+	"math"
+	"scootin/models"
+	"sort"
+	"time"
+)
+
+// PeriodLayout formats the invoiced months
+const PeriodLayout = "2006-01"
+
+// Month returns the UTC bounds [from, to) of the "2006-01" month
+func Month(period string) (time.Time, time.Time, error) {
+	from, err := time.Parse(PeriodLayout, period)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return from, from.AddDate(0, 1, 0), nil
+}
+
+// Number returns the invoice number of the legal entity's sequence
+func Number(legalEntity string, sequence int64) string {
+	return fmt.Sprintf("%s-%06d", legalEntity, sequence)
+}
+
+// ValidRule reports whether the tax rule can be applied: a legal entity and its name, and a rate within 0-100%
+func ValidRule(rule *models.TaxRule) bool {
+	return rule != nil && len(rule.LegalEntity) > 0 && len(rule.EntityName) > 0 && rule.VATRate >= 0 && rule.VATRate <= 10000
+}
+
+// Rule returns the tax rule of the city, the rule without a city when the city has none
+func Rule(rules []models.TaxRule, city string) (*models.TaxRule, error) {
+	var fallback *models.TaxRule
+	for i := range rules {
+		switch rules[i].City {
+		case city:
+			return &rules[i], nil
+		case "":
+			fallback = &rules[i]
+		}
+	}
+	if fallback == nil {
+		return nil, models.Errorf(models.ErrTaxRuleNotFound, "city %q", city)
+	}
+	return fallback, nil
+}
+
+// Line returns the invoice line of the charge with the VAT the rate includes in it
+func Line(charge models.Charge, vatRate int64) models.InvoiceLine {
+	net := int64(math.Round(float64(charge.Amount) * 10000 / float64(10000+vatRate)))
+	return models.InvoiceLine{Kind: charge.Kind, Reference: charge.Reference, City: charge.City, At: charge.At,
+		VATRate: vatRate, Net: net, VAT: charge.Amount - net, Gross: charge.Amount}
+}
+
+// Build groups the period's charges in one invoice per user, legal entity and currency, ordered by user, entity and currency
+// and their lines by time, a refund is a credit line. The invoices aren't numbered yet, the charges of the cities
+// without a tax rule are returned unbilled.
+func Build(period string, charges []models.Charge, rules []models.TaxRule) ([]*models.Invoice, []models.Charge) {
+	type key struct{ user, entity, currency string }
+	invoices := make(map[key]*models.Invoice)
+	unbilled := make([]models.Charge, 0)
+	for _, c := range charges {
+		if c.Amount == 0 {
+			continue
+		}
+		rule, err := Rule(rules, c.City)
+		if err != nil {
+			unbilled = append(unbilled, c)
+			continue
+		}
+		k := key{c.UserID, rule.LegalEntity, c.Currency}
+		inv, ok := invoices[k]
+		if !ok {
+			inv = &models.Invoice{LegalEntity: rule.LegalEntity, EntityName: rule.EntityName, VATID: rule.VATID,
+				UserID: c.UserID, Period: period, Currency: c.Currency}
+			invoices[k] = inv
+		}
+		line := Line(c, rule.VATRate)
+		inv.Lines = append(inv.Lines, line)
+		inv.Net, inv.VAT, inv.Total = inv.Net+line.Net, inv.VAT+line.VAT, inv.Total+line.Gross
+	}
+
+	built := make([]*models.Invoice, 0, len(invoices))
+	for _, inv := range invoices {
+		sort.SliceStable(inv.Lines, func(i, j int) bool { return inv.Lines[i].At.Before(inv.Lines[j].At) })
+		built = append(built, inv)
+	}
+	sort.Slice(built, func(i, j int) bool {
+		a, b := built[i], built[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		} else if a.LegalEntity != b.LegalEntity {
+			return a.LegalEntity < b.LegalEntity
+		}
+		return a.Currency < b.Currency
+	})
+	return built, unbilled
+}
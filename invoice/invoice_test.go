@@ -0,0 +1,83 @@
+package invoice
+
+import (
+	"bytes"
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	rules := []models.TaxRule{
+		{LegalEntity: "DE01", EntityName: "Scootin GmbH", VATRate: 1900},
+		{City: "Zurich", LegalEntity: "CH01", EntityName: "Scootin AG", VATID: "CHE-123", VATRate: 770},
+	}
+	at := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	charges := []models.Charge{
+		{UserID: "u1", Kind: models.ChargeTrip, Reference: "t2", City: "Berlin", Amount: 238, Currency: "EUR", At: at.Add(time.Hour)},
+		{UserID: "u1", Kind: models.ChargeTrip, Reference: "t1", City: "Hamburg", Amount: 119, Currency: "EUR", At: at},
+		{UserID: "u1", Kind: models.ChargeTrip, Reference: "t3", City: "Zurich", Amount: 1077, Currency: "CHF", At: at},
+		{UserID: "u1", Kind: models.ChargeTrip, Reference: "t4", City: "Berlin", Currency: "EUR", At: at},
+		{UserID: "u0", Kind: models.ChargePass, Reference: "p1", Amount: 300, Currency: "EUR", At: at},
+		{UserID: "u1", Kind: models.ChargeRefund, Reference: "d1", City: "Zurich", Amount: -1077, Currency: "CHF", At: at.Add(time.Hour)},
+	}
+
+	invoices, unbilled := Build("2021-03", charges, rules)
+	assert.Empty(t, unbilled)
+	assert.Len(t, invoices, 3)
+	// the pass is billed by the entity of the cities without a rule
+	assert.Equal(t, "u0", invoices[0].UserID)
+	assert.Equal(t, int64(48), invoices[0].Lines[0].VAT)
+	// the prices include the VAT, the lines are ordered by time and the free trip isn't billed
+	berlin := invoices[2]
+	assert.Equal(t, "DE01", berlin.LegalEntity)
+	assert.Equal(t, []string{"t1", "t2"}, []string{berlin.Lines[0].Reference, berlin.Lines[1].Reference})
+	assert.Equal(t, int64(300), berlin.Net)
+	assert.Equal(t, int64(57), berlin.VAT)
+	assert.Equal(t, int64(357), berlin.Total)
+	// the refund is credited back
+	zurich := invoices[1]
+	assert.Equal(t, "CH01", zurich.LegalEntity)
+	assert.Equal(t, "CHF", zurich.Currency)
+	if assert.Len(t, zurich.Lines, 2) {
+		assert.Equal(t, int64(1000), zurich.Lines[0].Net)
+		assert.Equal(t, int64(-1000), zurich.Lines[1].Net)
+		assert.Equal(t, int64(-77), zurich.Lines[1].VAT)
+	}
+	assert.Zero(t, zurich.Total)
+
+	// the charges without a rule are left out of the invoices
+	invoices, unbilled = Build("2021-03", charges, rules[1:])
+	assert.Len(t, invoices, 1)
+	if assert.Len(t, unbilled, 3) {
+		assert.Equal(t, []string{"t2", "t1", "p1"}, []string{unbilled[0].Reference, unbilled[1].Reference, unbilled[2].Reference})
+	}
+}
+
+func TestMonth(t *testing.T) {
+	from, to, err := Month("2021-12")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC), from)
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), to)
+	_, _, err = Month("2021-13")
+	assert.Error(t, err)
+
+	assert.Equal(t, "DE01-000042", Number("DE01", 42))
+	assert.False(t, ValidRule(&models.TaxRule{LegalEntity: "DE01", EntityName: "Scootin GmbH", VATRate: 10001}))
+	assert.False(t, ValidRule(&models.TaxRule{EntityName: "Scootin GmbH"}))
+	assert.True(t, ValidRule(&models.TaxRule{LegalEntity: "DE01", EntityName: "Scootin GmbH"}))
+}
+
+func TestWriteHTML(t *testing.T) {
+	inv := &models.Invoice{Number: "DE01-000001", EntityName: "Scootin <GmbH>", UserID: "u1", Period: "2021-03", Currency: "EUR",
+		Lines: []models.InvoiceLine{{Kind: models.ChargeTrip, Reference: "t1", City: "Berlin", VATRate: 1900, Net: 100, VAT: 19, Gross: 119}},
+		Net:   100, VAT: 19, Total: 119}
+	var page bytes.Buffer
+	assert.NoError(t, WriteHTML(&page, inv))
+	assert.Contains(t, page.String(), "Invoice DE01-000001")
+	assert.Contains(t, page.String(), "19.00%")
+	assert.Contains(t, page.String(), "1.19")
+	assert.Contains(t, page.String(), "Scootin &lt;GmbH&gt;")
+}
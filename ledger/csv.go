@@ -0,0 +1,30 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"scootin/models"
+	"strconv"
+	"time"
+)
+
+// csvHeader names the exported columns, the amounts are in minor units
+var csvHeader = []string{"transaction_id", "created_at", "kind", "user_id", "reference", "currency", "account", "amount"}
+
+// WriteCSV exports the transactions' entries one per row, in the transactions' order
+func WriteCSV(w io.Writer, transactions []models.LedgerTransaction) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, tx := range transactions {
+		for _, e := range tx.Entries {
+			if err := cw.Write([]string{tx.ID, tx.CreatedAt.UTC().Format(time.RFC3339), string(tx.Kind), tx.UserID, tx.Reference,
+				tx.Currency, e.Account, strconv.FormatInt(e.Amount, 10)}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
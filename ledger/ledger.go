@@ -0,0 +1,104 @@
+// Package ledger builds the double-entry postings of the riders' money.
+package ledger
+
+import (
+	"scootin/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Fare charges the fare of the user's ended trip to the user's wallet, the revenue earns it
+func Fare(userID, tripID string, fare *models.Fare) *models.LedgerTransaction {
+	return post(models.LedgerFare, userID, tripID, fare.Currency,
+		models.WalletAccount(userID), -fare.Total, models.AccountRevenue)
+}
+
+// Payment credits the rider's wallet with the captured checkout
+func Payment(checkout *models.Checkout) *models.LedgerTransaction {
+	return post(models.LedgerPayment, checkout.UserID, checkout.ID, checkout.Currency,
+		models.WalletAccount(checkout.UserID), checkout.Amount, models.AccountPayments)
+}
+
+// TopUp credits the user's wallet with the money captured by the reference
+func TopUp(userID, reference string, money models.Money) *models.LedgerTransaction {
+	return post(models.LedgerTopUp, userID, reference, money.Currency,
+		models.WalletAccount(userID), money.Amount, models.AccountPayments)
+}
+
+// Refund gives the money of the referenced fare back from the revenue to the account,
+// the user's wallet or the payments account when it's refunded through the payment provider.
+func Refund(userID, reference string, money models.Money, account string) *models.LedgerTransaction {
+	return post(models.LedgerRefund, userID, reference, money.Currency, account, money.Amount, models.AccountRevenue)
+}
+
+// SplitRefund gives the money of the referenced fare back from the revenue when only the refunded part of it was paid
+// through the payment provider: that part goes to the payments and the rest, which the wallet paid, to the user's wallet.
+func SplitRefund(userID, reference string, money models.Money, refunded int64) *models.LedgerTransaction {
+	tx := Refund(userID, reference, money, models.AccountPayments)
+	tx.Entries = []models.LedgerEntry{
+		{Account: models.AccountPayments, Amount: refunded},
+		{Account: models.WalletAccount(userID), Amount: money.Amount - refunded},
+		{Account: models.AccountRevenue, Amount: -money.Amount},
+	}
+	return tx
+}
+
+// Promo credits the user's wallet with the referenced promotion
+func Promo(userID, reference string, money models.Money) *models.LedgerTransaction {
+	return post(models.LedgerPromo, userID, reference, money.Currency,
+		models.WalletAccount(userID), money.Amount, models.AccountPromotions)
+}
+
+// Pass earns the money of the user's pass paid through the payment provider
+func Pass(userID, passID string, money models.Money) *models.LedgerTransaction {
+	return post(models.LedgerPass, userID, passID, money.Currency, models.AccountRevenue, money.Amount, models.AccountPayments)
+}
+
+// post returns the transaction moving the amount into the account out of the counter account
+func post(kind models.LedgerKind, userID, reference, currency, account string, amount int64, counter string) *models.LedgerTransaction {
+	return &models.LedgerTransaction{
+		ID:        uuid.New().String(),
+		Kind:      kind,
+		UserID:    userID,
+		Currency:  currency,
+		Reference: reference,
+		Entries:   []models.LedgerEntry{{Account: account, Amount: amount}, {Account: counter, Amount: -amount}},
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Balanced reports whether the transaction can be posted: a user, a reference, a currency code
+// and at least two entries summing to zero.
+func Balanced(tx *models.LedgerTransaction) bool {
+	if tx == nil || len(tx.UserID) == 0 || len(tx.Reference) == 0 || len(tx.Currency) != 3 || len(tx.Entries) < 2 {
+		return false
+	}
+	var sum int64
+	for _, e := range tx.Entries {
+		if len(e.Account) == 0 {
+			return false
+		}
+		sum += e.Amount
+	}
+	return sum == 0
+}
+
+// Balances returns the account's balance per currency within the transactions, ordered by currency
+func Balances(transactions []models.LedgerTransaction, account string) []models.Money {
+	sums := make(map[string]int64)
+	for _, tx := range transactions {
+		for _, e := range tx.Entries {
+			if e.Account == account {
+				sums[tx.Currency] += e.Amount
+			}
+		}
+	}
+	balances := make([]models.Money, 0, len(sums))
+	for currency, amount := range sums {
+		balances = append(balances, models.Money{Amount: amount, Currency: currency})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].Currency < balances[j].Currency })
+	return balances
+}
@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"bytes"
+	"scootin/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostings(t *testing.T) {
+	checkout := &models.Checkout{ID: "c1", UserID: "u1", Amount: 200, Currency: "EUR"}
+	transactions := []models.LedgerTransaction{
+		*Fare("u1", "t1", &models.Fare{Currency: "EUR", Total: 300}),
+		*Payment(checkout),
+		*Promo("u1", "welcome", models.Money{Amount: 50, Currency: "EUR"}),
+		*TopUp("u1", "top-up-1", models.Money{Amount: 500, Currency: "CHF"}),
+		*Refund("u1", "t1", models.Money{Amount: 30, Currency: "EUR"}, models.WalletAccount("u1")),
+		*SplitRefund("u1", "d1", models.Money{Amount: 40, Currency: "EUR"}, 25),
+	}
+	for i := range transactions {
+		assert.True(t, Balanced(&transactions[i]))
+	}
+
+	// the wallet owes what's left of the fare in euros and has its top-up in francs
+	assert.Equal(t, []models.Money{{Amount: 500, Currency: "CHF"}, {Amount: -5, Currency: "EUR"}},
+		Balances(transactions, models.WalletAccount("u1")))
+	assert.Equal(t, []models.Money{{Amount: 230, Currency: "EUR"}}, Balances(transactions, models.AccountRevenue))
+	assert.Empty(t, Balances(transactions, models.WalletAccount("u2")))
+}
+
+func TestBalanced(t *testing.T) {
+	tx := TopUp("u1", "top-up-1", models.Money{Amount: 500, Currency: "EUR"})
+	tx.Entries[1].Amount = -400
+	assert.False(t, Balanced(tx))
+	assert.False(t, Balanced(&models.LedgerTransaction{UserID: "u1", Reference: "r1", Currency: "EUR",
+		Entries: []models.LedgerEntry{{Account: models.AccountRevenue}}}))
+	assert.False(t, Balanced(TopUp("u1", "", models.Money{Amount: 500, Currency: "EUR"})))
+	assert.False(t, Balanced(TopUp("u1", "top-up-1", models.Money{Amount: 500})))
+	assert.False(t, Balanced(nil))
+}
+
+func TestWriteCSV(t *testing.T) {
+	tx := TopUp("u1", "top-up-1", models.Money{Amount: 500, Currency: "EUR"})
+	tx.ID, tx.CreatedAt = "tx1", time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	var out bytes.Buffer
+	assert.NoError(t, WriteCSV(&out, []models.LedgerTransaction{*tx}))
+
+	rows := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, rows, 1+len(tx.Entries))
+	assert.Equal(t, "transaction_id,created_at,kind,user_id,reference,currency,account,amount", rows[0])
+	assert.True(t, strings.HasPrefix(rows[1], "tx1,2021-03-10T12:00:00Z,top_up,u1,top-up-1,EUR,"))
+}
@@ -1,24 +1,252 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"scootin/config"
 	"scootin/db"
 	"scootin/logger"
+	"scootin/payment"
+	"scootin/pricing"
 	"scootin/service"
+	"strconv"
+	"syscall"
+	"time"
 )
 
 const appName = "Scootin"
 
+const migrateUsage = "usage: scootin migrate up|down [steps]|status"
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	log := logger.NewLogger()
 	logger.InitLogger(log)
 	defer logger.Sync()
 
-	if err := db.InitiatePostgre(); err != nil {
+	if err := db.InitiateRepository(); err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	lc, err := config.IniatilizeLocationHistoryConfig()
+	if err != nil {
+		panic(err)
+	}
+	go service.MaintainLocationHistory(context.Background(), lc.MaintenanceInterval, lc.Retention)
+
+	rc, err := config.IniatilizeReservationConfig()
+	if err != nil {
+		panic(err)
+	}
+	service.SetReservationHold(time.Duration(rc.HoldMinutes) * time.Minute)
+	go service.ExpireReservations(context.Background(), rc.SweepInterval)
+
+	bc, err := config.IniatilizeBookingConfig()
+	if err != nil {
+		panic(err)
+	}
+	service.SetBookingDistance(bc.MaxDistance)
+
+	pc, err := config.IniatilizePaymentConfig()
+	if err != nil {
+		panic(err)
+	}
+	fakeMode, err := paymentFakeMode(pc.FakeMode)
+	if err != nil {
+		panic(err)
+	}
+	if len(fakeMode) > 0 {
+		service.SetPaymentProvider(payment.NewFake(fakeMode), pc.Timeout)
+	} else {
+		// no provider is shipped but the fake one, the bookings and payments are refused until it's opted into
+		logger.Warn("no payment provider is configured, the bookings and payments will be refused")
+		service.SetPaymentProvider(nil, pc.Timeout)
+	}
+	service.SetMerchantCode(pc.MerchantCode)
+	service.SetPaymentHold(pc.HoldAmount, pc.HoldCurrency)
+	go service.RecoverPaymentSagas(context.Background(), pc.RecoveryInterval, pc.RecoverAfter)
+
+	wc, err := config.IniatilizeWalletConfig()
+	if err != nil {
+		panic(err)
+	}
+	service.SetMinBalance(wc.MinBalance)
+
+	ac, err := config.IniatilizePassConfig()
+	if err != nil {
+		panic(err)
+	}
+	go service.RenewPasses(context.Background(), ac.RenewalInterval)
+
+	uc, err := config.IniatilizeSurgeConfig()
+	if err != nil {
+		panic(err)
+	}
+	windows, err := pricing.ParseRateWindows(uc.Windows)
+	if err != nil {
+		panic(err)
+	}
+	timeZone, err := time.LoadLocation(uc.TimeZone)
+	if err != nil {
+		panic(err)
+	}
+	service.SetSurgePolicy(pricing.SurgePolicy{Threshold: uc.Threshold, Sensitivity: uc.Sensitivity,
+		MaxMultiplier: uc.MaxMultiplier, Windows: windows, Location: timeZone})
+
+	cc, err := config.IniatilizeCommandConfig()
+	if err != nil {
+		panic(err)
+	}
+	service.SetCommandTTL(cc.TTL)
+	go service.ExpireCommands(context.Background(), cc.SweepInterval)
+
+	ic, err := config.IniatilizeInvoiceConfig()
+	if err != nil {
+		panic(err)
+	}
+	go service.RunInvoicing(context.Background(), ic.Interval)
+
+	sc, err := config.IniatilizeServiceConfig()
+	if err != nil {
 		panic(err)
 	}
+	service.SetAdminToken(sc.AdminToken)
+	service.SetTelemetryToken(sc.TelemetryToken)
+
+	tc, err := config.IniatilizeTelemetryConfig()
+	if err != nil {
+		panic(err)
+	}
+	// the service stops on SIGINT or SIGTERM, once the scooters' last positions are written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var positions *db.PositionBuffer
+	if tc.BufferCapacity > 0 {
+		positions = db.NewPositionBuffer(tc.BufferCapacity, tc.BatchSize)
+		service.SetPositionBuffer(positions)
+		go service.FlushPositions(ctx, tc.FlushInterval)
+	}
+	udpDone := make(chan struct{})
+	if len(tc.UDPAddr) > 0 && len(tc.UDPKey) > 0 {
+		conn, err := net.ListenPacket("udp", tc.UDPAddr)
+		if err != nil {
+			panic(err)
+		}
+		service.SetTelemetryKey(tc.UDPKey)
+		go func() {
+			service.ServeTelemetry(ctx, conn)
+			close(udpDone)
+		}()
+	} else {
+		close(udpDone)
+	}
+
 	//  create a new *router instance
-	router := service.NewRouter()
-	logger.Fatal(http.ListenAndServe(":8080", router))
+	srv := &http.Server{Addr: ":8080", Handler: service.NewRouter()}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), tc.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Errorf("couldn't shut the server down: %s", err)
+	}
+	<-udpDone
+	if positions != nil {
+		if err := positions.Close(shutdownCtx); err != nil {
+			logger.Errorf("the scooters' last positions were lost: %s", err)
+		}
+	}
+}
+
+// paymentFakeMode is how the fake payment provider answers, it's only used when PAYMENT_FAKE_MODE is set or when the
+// data is kept in memory, where it approves
+func paymentFakeMode(configured string) (payment.FakeMode, error) {
+	if len(configured) > 0 {
+		mode := payment.FakeMode(configured)
+		if !mode.Valid() {
+			return "", fmt.Errorf("unknown payment fake mode %q", configured)
+		}
+		return mode, nil
+	}
+	sc, err := config.IniatilizeStorageConfig()
+	if err != nil {
+		return "", err
+	}
+	if sc.Backend == db.MemoryBackend {
+		return payment.FakeApprove, nil
+	}
+	return "", nil
+}
+
+// migrate runs the "migrate" subcommand against the configured postgres database
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	conn, err := db.OpenPostgre()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator, err := db.NewMigrator(conn)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("invalid steps %q, %s", args[1], migrateUsage)
+			}
+		}
+		rolledBack, err := migrator.Down(ctx, steps)
+		for _, m := range rolledBack {
+			fmt.Printf("rolled back %d %s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			if s.Applied {
+				fmt.Printf("%d %s applied at %s\n", s.Version, s.Name, s.AppliedAt.Format("2006-01-02T15:04:05"))
+			} else {
+				fmt.Printf("%d %s pending\n", s.Version, s.Name)
+			}
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
 }
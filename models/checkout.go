@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// CheckoutStatus is the checkout's payment state, a pending checkout is being captured
+type CheckoutStatus string
+
+const (
+	CheckoutPending  CheckoutStatus = "pending"
+	CheckoutCaptured CheckoutStatus = "captured"
+	CheckoutDeclined CheckoutStatus = "declined"
+	CheckoutFailed   CheckoutStatus = "failed"
+)
+
+// CheckoutCreate asks to pay a finished trip, the amount and currency are the trip's fare
+// and the merchant code is the service's one unless they're given.
+type CheckoutCreate struct {
+	TripID       string
+	MerchantCode string
+	Amount       int64
+	Currency     string
+}
+
+// Checkout is the payment of a trip's fare, the amount is in the currency's minor units.
+type Checkout struct {
+	ID                string
+	TripID            string
+	UserID            string
+	MerchantCode      string
+	Amount            int64
+	Currency          string
+	Status            CheckoutStatus
+	ProviderReference string // the payment provider's reference of the captured payment
+	FailureCode       string // the error code of a declined or failed payment
+	CreatedAt         time.Time
+	CompletedAt       *time.Time
+}
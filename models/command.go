@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// CommandKind is what a remote command asks the scooter to do
+type CommandKind string
+
+const (
+	CommandLock       CommandKind = "lock"
+	CommandUnlock     CommandKind = "unlock"
+	CommandBeep       CommandKind = "beep"
+	CommandSpeedLimit CommandKind = "speed-limit"
+	CommandReboot     CommandKind = "reboot"
+)
+
+// Valid reports whether the kind is a known one
+func (k CommandKind) Valid() bool {
+	switch k {
+	case CommandLock, CommandUnlock, CommandBeep, CommandSpeedLimit, CommandReboot:
+		return true
+	}
+	return false
+}
+
+// CommandState is the command's delivery state: queued until the scooter polls it, delivered until the scooter
+// acknowledges it, then acked or failed. A command not acknowledged before it expires fails.
+type CommandState string
+
+const (
+	CommandQueued    CommandState = "queued"
+	CommandDelivered CommandState = "delivered"
+	CommandAcked     CommandState = "acked"
+	CommandFailed    CommandState = "failed"
+)
+
+// Command is an operator's remote command to a scooter
+type Command struct {
+	ID          string
+	ScooterID   string
+	Kind        CommandKind
+	SpeedLimit  float64 // km/h, for the speed-limit commands
+	State       CommandState
+	Reason      string // why the command failed
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+	DeliveredAt *time.Time // the last delivery, an unacknowledged command is delivered again
+	EndedAt     *time.Time // when it was acked or failed
+}
+
+// CommandCreate asks to send a command to a scooter, it expires after TTL seconds or the service's default
+type CommandCreate struct {
+	Kind       CommandKind
+	SpeedLimit float64
+	TTL        int
+}
+
+// CommandAck is the scooter's acknowledgment of a delivered command, acked or failed with a reason
+type CommandAck struct {
+	State  CommandState
+	Reason string
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// DisputeStatus is the dispute's workflow state, support reviews an open dispute then refunds or rejects it
+type DisputeStatus string
+
+const (
+	DisputeOpen        DisputeStatus = "open"
+	DisputeUnderReview DisputeStatus = "under_review"
+	DisputeRefunded    DisputeStatus = "refunded"
+	DisputeRejected    DisputeStatus = "rejected"
+)
+
+// disputeTransitions lists the states each state can move to, a decided dispute is final
+var disputeTransitions = map[DisputeStatus][]DisputeStatus{
+	DisputeOpen:        {DisputeUnderReview},
+	DisputeUnderReview: {DisputeRefunded, DisputeRejected},
+	DisputeRefunded:    {},
+	DisputeRejected:    {},
+}
+
+// CanTransitionTo reports whether the dispute may move from the status to the next one
+func (s DisputeStatus) CanTransitionTo(next DisputeStatus) bool {
+	for _, to := range disputeTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// the dispute events' actors
+const (
+	ActorRider   = "rider"
+	ActorSupport = "support"
+)
+
+// DisputeCreate opens a dispute of the rider's ended trip
+type DisputeCreate struct {
+	TripID string
+	Reason string
+}
+
+// Dispute is a rider's claim against a trip's fare, RefundAmount is the refunded part of the fare
+// in the Currency's minor units. Events is its audit trail, the oldest first.
+type Dispute struct {
+	ID              string
+	TripID          string
+	UserID          string
+	Reason          string
+	Status          DisputeStatus
+	RefundAmount    int64
+	Currency        string
+	RefundReference string // the payment provider's reference of the refund, empty when the fare is refunded to the wallet
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Events          []DisputeEvent
+}
+
+// DisputeEvent records a dispute entering a status, by whom and why
+type DisputeEvent struct {
+	Status DisputeStatus
+	Actor  string
+	Note   string
+	At     time.Time
+}
+
+// DisputeDecision closes a dispute under review, a refund of no amount refunds the whole fare
+type DisputeDecision struct {
+	Status DisputeStatus
+	Amount int64
+	Note   string
+}
+
+// DisputeReview is what support reviews a dispute with: the trip and its scooter's locations during it
+type DisputeReview struct {
+	Dispute Dispute
+	Trip    Trip
+	Track   []LocationSample
+}
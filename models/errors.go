@@ -0,0 +1,99 @@
+package models
+
+import "fmt"
+
+// Error is a domain error identified by a machine-readable code,
+// the service and the client map between them and the problem responses.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var (
+	ErrScooterOccupied         = &Error{Code: "scooter_occupied", Message: "scooter is already occupied"}
+	ErrScooterNotFound         = &Error{Code: "scooter_not_found", Message: "scooter not found"}
+	ErrUserNotFound            = &Error{Code: "user_not_found", Message: "user not found"}
+	ErrTripNotFound            = &Error{Code: "trip_not_found", Message: "trip not found"}
+	ErrNoActiveTrip            = &Error{Code: "no_active_trip", Message: "user has no active trip"}
+	ErrActiveTripExists        = &Error{Code: "active_trip_exists", Message: "user already has an active trip"}
+	ErrScooterUnavailable      = &Error{Code: "scooter_unavailable", Message: "scooter is not available"}
+	ErrInvalidTransition       = &Error{Code: "invalid_status_transition", Message: "scooter status can't change this way"}
+	ErrReservationNotFound     = &Error{Code: "reservation_not_found", Message: "reservation not found"}
+	ErrReservationNotActive    = &Error{Code: "reservation_not_active", Message: "reservation is no longer active"}
+	ErrActiveReservationExists = &Error{Code: "active_reservation_exists", Message: "user already has an active reservation"}
+	ErrPricePlanNotFound       = &Error{Code: "price_plan_not_found", Message: "no price plan applies"}
+	ErrCheckoutNotFound        = &Error{Code: "checkout_not_found", Message: "checkout not found"}
+	ErrTripNotFinished         = &Error{Code: "trip_not_finished", Message: "trip hasn't ended yet"}
+	ErrTripAlreadyPaid         = &Error{Code: "trip_already_paid", Message: "trip is already paid or being paid"}
+	ErrPaymentDeclined         = &Error{Code: "payment_declined", Message: "payment was declined"}
+	ErrPaymentTimeout          = &Error{Code: "payment_timeout", Message: "payment provider didn't answer in time"}
+	ErrPaymentFailed           = &Error{Code: "payment_failed", Message: "payment provider failed"}
+	ErrPaymentSagaNotFound     = &Error{Code: "payment_saga_not_found", Message: "payment saga not found"}
+	ErrInsufficientBalance     = &Error{Code: "insufficient_balance", Message: "wallet balance is below the allowed minimum"}
+	ErrDisputeNotFound         = &Error{Code: "dispute_not_found", Message: "dispute not found"}
+	ErrDisputeExists           = &Error{Code: "dispute_exists", Message: "trip is already disputed"}
+	ErrInvalidDisputeStatus    = &Error{Code: "invalid_dispute_transition", Message: "dispute status can't change this way"}
+	ErrPromoNotFound           = &Error{Code: "promo_not_found", Message: "promo code not found"}
+	ErrPromoExists             = &Error{Code: "promo_exists", Message: "promo code already exists"}
+	ErrPromoUnavailable        = &Error{Code: "promo_unavailable", Message: "promo code is expired or used up"}
+	ErrPromoAlreadyRedeemed    = &Error{Code: "promo_already_redeemed", Message: "promo code is already redeemed by the user"}
+	ErrPassPlanNotFound        = &Error{Code: "pass_plan_not_found", Message: "pass plan not found"}
+	ErrPassNotFound            = &Error{Code: "pass_not_found", Message: "pass not found"}
+	ErrTaxRuleNotFound         = &Error{Code: "tax_rule_not_found", Message: "no tax rule applies to the city"}
+	ErrInvoiceNotFound         = &Error{Code: "invoice_not_found", Message: "invoice not found"}
+	ErrInvoiceExists           = &Error{Code: "invoice_exists", Message: "invoice already issued"}
+	ErrZoneNotFound            = &Error{Code: "zone_not_found", Message: "zone not found"}
+	ErrOutsideOperatingArea    = &Error{Code: "outside_operating_area", Message: "the scooter is outside the operating area"}
+	ErrNoParkingZone           = &Error{Code: "no_parking_zone", Message: "the scooter is in a no-parking zone"}
+	ErrScooterTooFar           = &Error{Code: "scooter_too_far", Message: "the scooter is too far from the rider"}
+	ErrCommandNotFound         = &Error{Code: "command_not_found", Message: "command not found"}
+	ErrCommandNotDelivered     = &Error{Code: "command_not_delivered", Message: "the command isn't waiting for an acknowledgment"}
+	ErrForbidden               = &Error{Code: "forbidden", Message: "not allowed"}
+	ErrInvalidRequest          = &Error{Code: "invalid_request", Message: "invalid request"}
+	ErrMissingUserID           = &Error{Code: "missing_user_id", Message: "missing user-id header"}
+	ErrUnavailable             = &Error{Code: "unavailable", Message: "service temporarily unavailable"}
+	ErrInternal                = &Error{Code: "internal", Message: "internal error"}
+)
+
+// domainErrors indexes the domain errors by their code
+var domainErrors = map[string]*Error{}
+
+func init() {
+	for _, e := range []*Error{
+		ErrScooterOccupied, ErrScooterNotFound, ErrUserNotFound, ErrTripNotFound, ErrNoActiveTrip, ErrActiveTripExists,
+		ErrScooterUnavailable, ErrInvalidTransition, ErrForbidden,
+		ErrReservationNotFound, ErrReservationNotActive, ErrActiveReservationExists, ErrPricePlanNotFound,
+		ErrCheckoutNotFound, ErrTripNotFinished, ErrTripAlreadyPaid, ErrPaymentDeclined, ErrPaymentTimeout, ErrPaymentFailed,
+		ErrPaymentSagaNotFound, ErrInsufficientBalance, ErrDisputeNotFound, ErrDisputeExists, ErrInvalidDisputeStatus,
+		ErrPromoNotFound, ErrPromoExists, ErrPromoUnavailable, ErrPromoAlreadyRedeemed, ErrPassPlanNotFound,
+		ErrPassNotFound, ErrTaxRuleNotFound, ErrInvoiceNotFound, ErrInvoiceExists,
+		ErrZoneNotFound, ErrOutsideOperatingArea, ErrNoParkingZone, ErrScooterTooFar,
+		ErrCommandNotFound, ErrCommandNotDelivered,
+		ErrInvalidRequest, ErrMissingUserID, ErrUnavailable, ErrInternal,
+	} {
+		domainErrors[e.Code] = e
+	}
+}
+
+// ErrorByCode returns the domain error of the code, nil when it's unknown
+func ErrorByCode(code string) *Error {
+	return domainErrors[code]
+}
+
+// Errorf wraps the domain error with the details, the result still matches it with errors.Is
+func Errorf(e *Error, format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", e, fmt.Sprintf(format, a...))
+}
+
+// Problem is the RFC 7807 problem details body of the failed requests
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	Code   string `json:"code"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// TaxRule is the VAT of the rides in a city and the legal entity invoicing them,
+// the rule without a city applies to the cities without their own.
+type TaxRule struct {
+	City        string
+	LegalEntity string // the code the entity's invoices are numbered by, e.g. "SCOOTIN-DE"
+	EntityName  string
+	VATID       string
+	VATRate     int64 // in basis points, 1900 is 19%
+}
+
+// ChargeKind is what a user was charged for
+type ChargeKind string
+
+const (
+	ChargeTrip   ChargeKind = "trip"
+	ChargePass   ChargeKind = "pass"
+	ChargeRefund ChargeKind = "refund" // a fare given back by a dispute, credited with a negative amount
+)
+
+// Charge is an amount charged to a user, VAT included, the trips' charges and their refunds are made in the trip's city
+type Charge struct {
+	UserID    string
+	Kind      ChargeKind
+	Reference string // the trip's, the pass's or the refunded dispute's id
+	City      string
+	Amount    int64
+	Currency  string
+	At        time.Time
+}
+
+// InvoiceLine is an invoiced charge with its VAT
+type InvoiceLine struct {
+	Kind      ChargeKind
+	Reference string
+	City      string
+	At        time.Time
+	VATRate   int64 // in basis points
+	Net       int64
+	VAT       int64
+	Gross     int64
+}
+
+// InvoiceRun is the outcome of invoicing a month: the newly issued invoices and the charges left unbilled
+// as no tax rule applies to their city
+type InvoiceRun struct {
+	Issued   []Invoice
+	Unbilled []Charge
+}
+
+// Invoice is a user's monthly statement of the charges in a currency made by a legal entity,
+// its Number is sequential and gapless per legal entity.
+type Invoice struct {
+	ID          string
+	Number      string
+	Sequence    int64
+	LegalEntity string
+	EntityName  string
+	VATID       string
+	UserID      string
+	Period      string // the invoiced month, "2006-01"
+	Currency    string
+	Lines       []InvoiceLine
+	Net         int64
+	VAT         int64
+	Total       int64
+	IssuedAt    time.Time
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// LedgerKind is what a ledger transaction records
+type LedgerKind string
+
+const (
+	// LedgerFare charges an ended trip's fare to its rider's wallet
+	LedgerFare LedgerKind = "fare"
+	// LedgerPayment credits the rider's wallet with a captured checkout
+	LedgerPayment LedgerKind = "payment"
+	// LedgerTopUp credits the rider's wallet with funds paid ahead
+	LedgerTopUp LedgerKind = "top_up"
+	// LedgerRefund gives a fare back to its rider
+	LedgerRefund LedgerKind = "refund"
+	// LedgerPromo credits the rider's wallet with a promotion
+	LedgerPromo LedgerKind = "promo"
+	// LedgerPass earns a pass paid through the payment provider
+	LedgerPass LedgerKind = "pass"
+)
+
+// the ledger's accounts beside the riders' wallets
+const (
+	// AccountRevenue earns the fares
+	AccountRevenue = "revenue"
+	// AccountPayments is the money received from, or given back through, the payment provider
+	AccountPayments = "payments"
+	// AccountPromotions funds the promotions
+	AccountPromotions = "promotions"
+)
+
+// WalletAccount returns the ledger account of the user's wallet
+func WalletAccount(userID string) string {
+	return "wallet:" + userID
+}
+
+// LedgerEntry moves Amount minor units into the account, a negative amount moves them out of it
+type LedgerEntry struct {
+	Account string
+	Amount  int64
+}
+
+// LedgerTransaction is an immutable double-entry posting of the user's money, its entries sum to zero.
+// The reference is what it records, e.g. the trip of a fare or the checkout of a payment.
+type LedgerTransaction struct {
+	ID        string
+	Kind      LedgerKind
+	UserID    string
+	Currency  string
+	Reference string
+	Entries   []LedgerEntry
+	CreatedAt time.Time
+}
+
+// Money is an amount of the currency's minor units
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// Wallet is the user's balance per currency, a negative balance is owed by the user
+type Wallet struct {
+	UserID   string
+	Balances []Money
+}
@@ -1,14 +1,46 @@
 // Package models contains all the data representation for the service layer "REST-API".
 package models
 
-// NotOccupied is a constant for non-users to indicate the scooter is available
-var NotOccupied = "NOT_OCCUPIED"
+import "time"
+
+// Location is a geographic position in degrees
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Proximity is where a rider stands and how far in meters from them the scooter they take may be
+type Proximity struct {
+	Position    Location
+	MaxDistance float64
+}
 
 // ScooterInfo has the scooter details
 type ScooterInfo struct {
-	ID           string
-	Coordination int64 // represents the scooter location.
-	UserID       string
+	ID          string
+	Location    Location // represents the scooter location.
+	Status      ScooterStatus
+	UserID      string // the rider while the scooter is reserved or in a trip, empty otherwise
+	City        string // the city whose price plans apply
+	VehicleType string
+}
+
+// ScooterCreate has the details of a new scooter, all of them are optional
+type ScooterCreate struct {
+	Location
+	City        string
+	VehicleType string
+}
+
+// StatusChange is an operator's request to move a scooter to another operational status
+type StatusChange struct {
+	Status ScooterStatus
+}
+
+// NearbyScooter is a scooter found around a location, with its distance in meters
+type NearbyScooter struct {
+	ScooterInfo
+	Distance float64
 }
 
 // User represents the user details
@@ -22,3 +54,25 @@ type User struct {
 type UUIDResponse struct {
 	ID string
 }
+
+// Trip is a ride of a user on a scooter, it's open until EndedAt is set.
+type Trip struct {
+	ID            string
+	ScooterID     string
+	UserID        string
+	StartedAt     time.Time
+	EndedAt       *time.Time
+	StartLocation Location
+	EndLocation   *Location
+	Surge         float64 // the price multiplier locked in when the trip was booked
+	Fare          *Fare   // set once the trip has ended
+}
+
+// LocationSample is a reported scooter location at a point in time.
+type LocationSample struct {
+	ScooterID string
+	Time      time.Time
+	Location  Location
+	Speed     float64 // km/h, as reported by the scooter's telemetry
+	Battery   int     // percent, as reported by the scooter's telemetry
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// PassPeriod is how long a pass lasts from its start
+type PassPeriod string
+
+const (
+	PassDay   PassPeriod = "day"
+	PassMonth PassPeriod = "month"
+)
+
+// PassPlan is a ride pass sold to the riders for Price minor units of the Currency, every day of its period
+// it covers FreeUnlocks unlock fees and FreeMinutes ridden minutes.
+type PassPlan struct {
+	ID          string
+	Name        string
+	Period      PassPeriod
+	Price       int64
+	Currency    string
+	FreeUnlocks int
+	FreeMinutes int64
+	CreatedAt   time.Time
+}
+
+// PassPurchase buys a pass of the plan, an auto-renewed pass is bought again when it ends
+type PassPurchase struct {
+	PlanID    string
+	AutoRenew bool
+}
+
+// Pass is a rider's pass valid within [StartsAt, EndsAt), RenewalOf is the pass it renews.
+type Pass struct {
+	ID               string
+	PlanID           string
+	UserID           string
+	StartsAt         time.Time
+	EndsAt           time.Time
+	AutoRenew        bool
+	RenewalOf        string
+	PaymentReference string // the payment provider's reference of the pass's payment
+	CreatedAt        time.Time
+}
+
+// PassUsage counts what the pass covered on the day, a "2006-01-02" UTC date
+type PassUsage struct {
+	PassID  string
+	Day     string
+	Unlocks int
+	Minutes int64
+}
+
+// PassAllowance is a rider's pass with its plan and what's left of the day's allowances
+type PassAllowance struct {
+	Pass        Pass
+	Plan        PassPlan
+	Day         string
+	UnlocksLeft int
+	MinutesLeft int64
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// DefaultVehicleType is the vehicle type of the scooters created without one
+const DefaultVehicleType = "scooter"
+
+// PricePlan is a version of the rates charged in a city for a vehicle type, the amounts are in the currency's minor units.
+// A plan without a city applies to the cities which don't have their own plan.
+type PricePlan struct {
+	ID            string
+	City          string
+	VehicleType   string
+	Version       int // assigned when the plan is stored, one more than the city's and vehicle type's latest
+	Currency      string
+	UnlockFee     int64
+	PerMinute     int64
+	PerKilometer  int64
+	EffectiveFrom time.Time // the plan prices the trips started from then on, until a later version is effective
+}
+
+// Fare is the price breakdown of a ride in the plan currency's minor units
+type Fare struct {
+	PlanID         string
+	PlanVersion    int
+	City           string // where the ride ended
+	Currency       string
+	Minutes        int64   // the started minutes are billed
+	Distance       float64 // meters
+	Surge          float64 // the price multiplier the charges include
+	UnlockFee      int64
+	TimeCharge     int64
+	DistanceCharge int64
+	PassID         string // the pass which covered part of the charges
+	FreeUnlock     bool   // the pass covered the unlock fee
+	FreeMinutes    int64  // the minutes the pass covered
+	PassCredit     int64  // taken off the charges by the pass
+	Discount       int64  // taken off the charges by the promo code
+	PromoCode      string // the applied promo code
+	StationID      string // the station the trip ended at with a free slot
+	StationCredit  int64  // taken off the charges by the station
+	Total          int64
+}
@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// PromoKind is how a promo code benefits its riders
+type PromoKind string
+
+const (
+	// PromoPercentOff takes Percent off the Target part of the fares
+	PromoPercentOff PromoKind = "percent_off"
+	// PromoAmountOff takes Amount minor units off the fares
+	PromoAmountOff PromoKind = "amount_off"
+	// PromoVoucher is a prepaid gift voucher, its Amount is credited to the rider's wallet when it's redeemed
+	PromoVoucher PromoKind = "voucher"
+)
+
+// PromoTarget is the part of the fare a percentage is taken off
+type PromoTarget string
+
+const (
+	PromoTargetTotal  PromoTarget = "total"
+	PromoTargetUnlock PromoTarget = "unlock"
+)
+
+// PromoCode is a marketing campaign the riders redeem, e.g. the first ride free or half the unlock fee off in a city.
+// A discount applies to the rides of the City, every city when it's empty, and only to the rider's first ride
+// when FirstRideOnly. MaxRedemptions caps its riders, PerUserLimit the rides it discounts for each of them,
+// 0 meaning no cap. It's neither redeemed nor applied from ExpiresAt on.
+type PromoCode struct {
+	Code           string
+	Kind           PromoKind
+	Percent        int64
+	Amount         int64
+	Currency       string
+	Target         PromoTarget
+	City           string
+	FirstRideOnly  bool
+	MaxRedemptions int
+	PerUserLimit   int
+	ExpiresAt      *time.Time
+	Redemptions    int // the riders who redeemed it
+	CreatedAt      time.Time
+}
+
+// PromoRedeem redeems a promo code for the user
+type PromoRedeem struct {
+	Code string
+}
+
+// PromoRedemption attaches a promo code to the user, Uses counts the rides it discounted
+type PromoRedemption struct {
+	Code       string
+	UserID     string
+	Uses       int
+	RedeemedAt time.Time
+}
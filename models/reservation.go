@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ReservationStatus is the reservation's lifecycle state, only an active reservation holds its scooter
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"
+	ReservationConverted ReservationStatus = "converted"
+	ReservationCancelled ReservationStatus = "cancelled"
+	ReservationExpired   ReservationStatus = "expired"
+)
+
+// Reservation holds a scooter for a user until it's converted to a trip, cancelled or expired.
+type Reservation struct {
+	ID        string
+	ScooterID string
+	UserID    string
+	Status    ReservationStatus
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	EndedAt   *time.Time
+	TripID    string  // the trip the reservation was converted to
+	Surge     float64 // the price multiplier locked in for the trip
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// PaymentSagaStep is the payment saga's progress, each step is stored before it's acted upon
+// so a saga interrupted by a restart is resumed from it.
+type PaymentSagaStep string
+
+const (
+	// SagaAuthorizing is placing the hold, the booking isn't attempted yet
+	SagaAuthorizing PaymentSagaStep = "authorizing"
+	// SagaAuthorized holds the funds while the trip is started
+	SagaAuthorized PaymentSagaStep = "authorized"
+	// SagaBooked holds the funds while the trip goes on
+	SagaBooked PaymentSagaStep = "booked"
+	// SagaCapturing is charging the ended trip's fare from the hold
+	SagaCapturing PaymentSagaStep = "capturing"
+	// SagaCaptured has charged the fare
+	SagaCaptured PaymentSagaStep = "captured"
+	// SagaCancelling is releasing the hold of a booking which failed
+	SagaCancelling PaymentSagaStep = "cancelling"
+	// SagaCancelled has released the hold
+	SagaCancelled PaymentSagaStep = "cancelled"
+	// SagaFailed was declined or couldn't charge the fare, see its failure code
+	SagaFailed PaymentSagaStep = "failed"
+)
+
+// Done reports whether the saga has nothing left to do
+func (s PaymentSagaStep) Done() bool {
+	return s == SagaCaptured || s == SagaCancelled || s == SagaFailed
+}
+
+// PaymentSaga places a hold on the rider's funds before the trip starts and charges the fare from it once the trip ends,
+// the hold is released when the booking fails. The amount is in the currency's minor units.
+type PaymentSaga struct {
+	ID              string
+	UserID          string
+	ScooterID       string
+	TripID          string
+	CheckoutID      string // the checkout charging the fare
+	AuthorizationID string
+	HoldAmount      int64
+	Currency        string
+	Step            PaymentSagaStep
+	FailureCode     string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Station is a virtual parking station: the scooters within Radius meters of its Location are parked at it,
+// up to Capacity of them. A trip ended at it while a slot is free gets DiscountPercent off its fare.
+type Station struct {
+	ID              string
+	Name            string
+	City            string
+	Location        Location
+	Radius          float64
+	Capacity        int
+	DiscountPercent int64
+	CreatedAt       time.Time
+}
+
+// StationOccupancy is a station with the scooters parked at it, one over capacity needs rebalancing
+type StationOccupancy struct {
+	Station
+	Occupancy int
+	FreeSlots int
+	Rebalance bool
+}
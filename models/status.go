@@ -0,0 +1,55 @@
+package models
+
+// ScooterStatus is the scooter's lifecycle state
+type ScooterStatus string
+
+const (
+	StatusAvailable   ScooterStatus = "available"
+	StatusReserved    ScooterStatus = "reserved"
+	StatusInTrip      ScooterStatus = "in_trip"
+	StatusMaintenance ScooterStatus = "maintenance"
+	StatusLowBattery  ScooterStatus = "low_battery"
+	StatusOffline     ScooterStatus = "offline"
+	StatusRetired     ScooterStatus = "retired"
+)
+
+// statusTransitions lists the states each state can move to, a retired scooter never comes back
+var statusTransitions = map[ScooterStatus][]ScooterStatus{
+	StatusAvailable:   {StatusReserved, StatusInTrip, StatusMaintenance, StatusLowBattery, StatusOffline, StatusRetired},
+	StatusReserved:    {StatusAvailable, StatusInTrip, StatusMaintenance, StatusOffline},
+	StatusInTrip:      {StatusAvailable, StatusLowBattery},
+	StatusMaintenance: {StatusAvailable, StatusOffline, StatusRetired},
+	StatusLowBattery:  {StatusAvailable, StatusMaintenance, StatusOffline, StatusRetired},
+	StatusOffline:     {StatusAvailable, StatusMaintenance, StatusRetired},
+	StatusRetired:     {},
+}
+
+// Valid reports whether the status is a known one
+func (s ScooterStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo reports whether the scooter may move from the status to the next one
+func (s ScooterStatus) CanTransitionTo(next ScooterStatus) bool {
+	for _, to := range statusTransitions[s] {
+		if to == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Operational reports whether the status is set by the operators rather than by the riders
+func (s ScooterStatus) Operational() bool {
+	switch s {
+	case StatusAvailable, StatusMaintenance, StatusLowBattery, StatusOffline, StatusRetired:
+		return true
+	}
+	return false
+}
+
+// Ridden reports whether the status holds the scooter for a rider
+func (s ScooterStatus) Ridden() bool {
+	return s == StatusReserved || s == StatusInTrip
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// TelemetrySample is a scooter's report at a point in time, Speed in km/h and Battery in percent
+type TelemetrySample struct {
+	Time     time.Time
+	Location Location
+	Speed    float64
+	Battery  int
+}
+
+// TelemetryBatch is the samples a scooter reports at once, the oldest first
+type TelemetryBatch struct {
+	Samples []TelemetrySample
+}
+
+// TelemetryReceipt tells how many of a batch's samples were recorded, the ones not newer
+// than the scooter's last report are duplicates and left out.
+type TelemetryReceipt struct {
+	Accepted   int
+	Duplicates int
+}
+
+// PositionBufferStats are the counters of the telemetry's write-behind buffer since the service started
+type PositionBufferStats struct {
+	Pending       int   // the scooters waiting for the next flush
+	Capacity      int   // the most scooters pending, the samples wait for a flush past it
+	Received      int64 // the samples added
+	Coalesced     int64 // the samples whose scooter was already pending, it's moved once by the flush
+	Written       int64 // the samples recorded by the flushes
+	Batches       int64
+	FailedBatches int64
+	Stalls        int64   // the samples which waited for a flush, the buffer being full
+	StalledFor    float64 // the seconds the stalled samples waited
+	LastFlushAt   *time.Time
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ZoneTag is what a zone's polygon means to the rides
+type ZoneTag string
+
+const (
+	// ZoneOperatingArea is where the trips may end, anywhere when no zone has the tag
+	ZoneOperatingArea ZoneTag = "operating-area"
+	// ZoneNoParking is where the trips may not end
+	ZoneNoParking ZoneTag = "no-parking"
+	// ZoneNoRiding is where the scooters may not go, entering it raises an alert
+	ZoneNoRiding ZoneTag = "no-riding"
+	// ZoneSlow is where the scooters go at most the zone's speed limit
+	ZoneSlow ZoneTag = "slow-zone"
+)
+
+// Geometry is a GeoJSON Polygon or MultiPolygon, its positions are [longitude, latitude]
+type Geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// Zone is an area of the map with its tags, SpeedLimit is in km/h for the slow zones
+type Zone struct {
+	ID         string
+	Name       string
+	Tags       []ZoneTag
+	Geometry   Geometry
+	SpeedLimit int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Has reports whether the zone is tagged with the tag
+func (z *Zone) Has(tag ZoneTag) bool {
+	for _, t := range z.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ZoneAlert records a scooter entering a zone it shouldn't, with its rider if it was in a trip
+type ZoneAlert struct {
+	ID        string
+	ScooterID string
+	UserID    string
+	ZoneID    string
+	Tag       ZoneTag
+	Location  Location
+	At        time.Time
+}
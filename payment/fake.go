@@ -0,0 +1,257 @@
+package payment
+
+import (
+	"context"
+	"scootin/models"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FakeMode tells the fake provider how to answer
+type FakeMode string
+
+const (
+	// FakeApprove captures and authorizes every payment
+	FakeApprove FakeMode = "approve"
+	// FakeDecline declines every payment
+	FakeDecline FakeMode = "decline"
+	// FakeTimeout never answers, the payments wait until their ctx is done
+	FakeTimeout FakeMode = "timeout"
+)
+
+// Valid reports whether the mode is a known one
+func (m FakeMode) Valid() bool {
+	return m == FakeApprove || m == FakeDecline || m == FakeTimeout
+}
+
+// authorization is a hold placed by the fake provider
+type authorization struct {
+	req       Request
+	cancelled bool
+	captured  string // the capture's reference
+}
+
+// Fake is a local provider for the development and the tests, it's told to approve, decline or time out.
+type Fake struct {
+	mu             sync.Mutex
+	mode           FakeMode
+	captures       map[string]string // the captured requests' references by the requests' ids
+	authorizations map[string]*authorization
+	authorized     map[string]string // the authorizations' ids by their requests' ids
+	refunds        map[string]string // the refunds' references by their requests' ids
+	refunded       map[string]int64  // the refunded amounts by the payments' references
+	charged        map[string]int64  // the captured amounts by the payers' ids
+}
+
+// NewFake returns a fake provider answering in the mode
+func NewFake(mode FakeMode) *Fake {
+	return &Fake{
+		mode:           mode,
+		captures:       make(map[string]string),
+		authorizations: make(map[string]*authorization),
+		authorized:     make(map[string]string),
+		refunds:        make(map[string]string),
+		refunded:       make(map[string]int64),
+		charged:        make(map[string]int64),
+	}
+}
+
+// SetMode changes how the next payments are answered
+func (f *Fake) SetMode(mode FakeMode) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mode = mode
+}
+
+// Capture ...
+func (f *Fake) Capture(ctx context.Context, req Request) (string, error) {
+	f.mu.Lock()
+	ref, captured := f.captures[req.ID]
+	f.mu.Unlock()
+	if captured {
+		return ref, nil
+	}
+	if err := f.answer(ctx, "payment %s of %d %s", req.ID, req.Amount, req.Currency); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ref, captured = f.captures[req.ID]; captured {
+		return ref, nil
+	}
+	ref = "fake-" + uuid.New().String()
+	f.captures[req.ID] = ref
+	f.charged[req.PayerID] += req.Amount
+	return ref, nil
+}
+
+// Authorize ...
+func (f *Fake) Authorize(ctx context.Context, req Request) (string, error) {
+	f.mu.Lock()
+	id, authorized := f.authorized[req.ID]
+	f.mu.Unlock()
+	if authorized {
+		return id, nil
+	}
+	if err := f.answer(ctx, "hold %s of %d %s", req.ID, req.Amount, req.Currency); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id = "fake-auth-" + uuid.New().String()
+	f.authorizations[id] = &authorization{req: req}
+	f.authorized[req.ID] = id
+	return id, nil
+}
+
+// CancelAuthorization ...
+func (f *Fake) CancelAuthorization(ctx context.Context, authorizationID string) error {
+	f.mu.Lock()
+	mode := f.mode
+	f.mu.Unlock()
+	// a hold is always released unless the provider doesn't answer
+	if mode == FakeTimeout {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	auth, ok := f.authorizations[authorizationID]
+	if !ok {
+		return models.Errorf(models.ErrPaymentFailed, "unknown authorization %s", authorizationID)
+	} else if len(auth.captured) > 0 {
+		return models.Errorf(models.ErrPaymentFailed, "authorization %s is captured", authorizationID)
+	}
+	auth.cancelled = true
+	return nil
+}
+
+// CaptureAuthorization ... the amount can't exceed the hold's
+func (f *Fake) CaptureAuthorization(ctx context.Context, authorizationID string, amount int64) (string, error) {
+	f.mu.Lock()
+	auth, ok := f.authorizations[authorizationID]
+	var ref string
+	if ok {
+		ref = auth.captured
+	}
+	f.mu.Unlock()
+	switch {
+	case !ok:
+		return "", models.Errorf(models.ErrPaymentFailed, "unknown authorization %s", authorizationID)
+	case len(ref) > 0:
+		return ref, nil
+	case amount > auth.req.Amount:
+		return "", models.Errorf(models.ErrPaymentFailed, "capture of %d exceeds the %d %s held by %s",
+			amount, auth.req.Amount, auth.req.Currency, authorizationID)
+	}
+	if err := f.answer(ctx, "capture of %d from hold %s", amount, authorizationID); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if auth.cancelled {
+		return "", models.Errorf(models.ErrPaymentFailed, "authorization %s is cancelled", authorizationID)
+	} else if len(auth.captured) > 0 {
+		return auth.captured, nil
+	}
+	auth.captured = "fake-" + uuid.New().String()
+	f.charged[auth.req.PayerID] += amount
+	return auth.captured, nil
+}
+
+// Refund ...
+func (f *Fake) Refund(ctx context.Context, paymentReference string, req Request) (string, error) {
+	f.mu.Lock()
+	ref, refunded := f.refunds[req.ID]
+	known := f.paid(paymentReference)
+	f.mu.Unlock()
+	switch {
+	case refunded:
+		return ref, nil
+	case !known:
+		return "", models.Errorf(models.ErrPaymentFailed, "unknown payment %s", paymentReference)
+	}
+	if err := f.answer(ctx, "refund %s of %d %s", req.ID, req.Amount, req.Currency); err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ref, refunded = f.refunds[req.ID]; refunded {
+		return ref, nil
+	}
+	ref = "fake-refund-" + uuid.New().String()
+	f.refunds[req.ID] = ref
+	f.refunded[paymentReference] += req.Amount
+	return ref, nil
+}
+
+// paid reports whether the reference is a captured payment's one
+func (f *Fake) paid(reference string) bool {
+	for _, ref := range f.captures {
+		if ref == reference {
+			return true
+		}
+	}
+	for _, auth := range f.authorizations {
+		if auth.captured == reference {
+			return true
+		}
+	}
+	return false
+}
+
+// Refunded returns the amount refunded of the referenced payment
+func (f *Fake) Refunded(paymentReference string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.refunded[paymentReference]
+}
+
+// Charged returns the amount captured from the payer, in any currency
+func (f *Fake) Charged(payerID string) int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.charged[payerID]
+}
+
+// Captured reports whether the request was captured
+func (f *Fake) Captured(requestID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.captures[requestID]
+	return ok
+}
+
+// OpenHolds returns the number of authorizations neither captured nor cancelled
+func (f *Fake) OpenHolds() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	open := 0
+	for _, auth := range f.authorizations {
+		if !auth.cancelled && len(auth.captured) == 0 {
+			open++
+		}
+	}
+	return open
+}
+
+// answer fails the call as told by the mode, the description details a declined call
+func (f *Fake) answer(ctx context.Context, format string, a ...interface{}) error {
+	f.mu.Lock()
+	mode := f.mode
+	f.mu.Unlock()
+	switch mode {
+	case FakeDecline:
+		return models.Errorf(models.ErrPaymentDeclined, format, a...)
+	case FakeTimeout:
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+// Package payment has the pluggable payment providers charging the riders.
+package payment
+
+import "context"
+
+// Request is a payment of the amount in the currency's minor units by the payer to the merchant.
+// Its ID is the idempotency key, a request retried with the same ID is charged or authorized once.
+type Request struct {
+	ID           string
+	PayerID      string
+	MerchantCode string
+	Amount       int64
+	Currency     string
+}
+
+// Provider is the payment service the checkouts are captured and the bookings' holds are placed through.
+// A declined payment is reported with models.ErrPaymentDeclined, a provider not answering before ctx is done
+// returns the ctx error.
+type Provider interface {
+	// Capture charges the request, returns the provider's reference of the payment
+	Capture(ctx context.Context, req Request) (string, error)
+
+	// Authorize places a hold of the request's amount on the payer's funds, returns the authorization id
+	Authorize(ctx context.Context, req Request) (string, error)
+
+	// CancelAuthorization releases the hold, cancelling a cancelled authorization does nothing
+	CancelAuthorization(ctx context.Context, authorizationID string) error
+
+	// CaptureAuthorization charges the amount from the hold, returns the provider's reference of the payment.
+	// Capturing a captured authorization returns the first capture's reference.
+	CaptureAuthorization(ctx context.Context, authorizationID string, amount int64) (string, error)
+
+	// Refund gives the request's amount of the referenced payment back to the payer, returns the refund's reference
+	Refund(ctx context.Context, paymentReference string, req Request) (string, error)
+}
@@ -0,0 +1,73 @@
+package pricing
+
+import (
+	"scootin/models"
+	"time"
+)
+
+// PassDayLayout formats the UTC days the passes' allowances are counted by
+const PassDayLayout = "2006-01-02"
+
+// ValidPassPlan reports whether the pass plan can be sold: a name, a known period, a currency code,
+// no negative price and at least one free unlock or minute a day.
+func ValidPassPlan(plan *models.PassPlan) bool {
+	return plan != nil && len(plan.Name) > 0 && (plan.Period == models.PassDay || plan.Period == models.PassMonth) &&
+		len(plan.Currency) == 3 && plan.Price >= 0 && plan.FreeUnlocks >= 0 && plan.FreeMinutes >= 0 &&
+		(plan.FreeUnlocks > 0 || plan.FreeMinutes > 0)
+}
+
+// PassEnd returns when a pass of the period started at the time ends
+func PassEnd(period models.PassPeriod, start time.Time) time.Time {
+	if period == models.PassMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// PassDay returns the day of the time the passes' allowances are counted by
+func PassDay(at time.Time) string {
+	return at.UTC().Format(PassDayLayout)
+}
+
+// Allowance returns what's left of the plan's allowances on a day the pass was used as much as the usage
+func Allowance(plan *models.PassPlan, usage models.PassUsage) (unlocks int, minutes int64) {
+	unlocks, minutes = plan.FreeUnlocks-usage.Unlocks, plan.FreeMinutes-usage.Minutes
+	if unlocks < 0 {
+		unlocks = 0
+	}
+	if minutes < 0 {
+		minutes = 0
+	}
+	return unlocks, minutes
+}
+
+// ApplyPass takes what's left of the pass's allowances for the day off the fare, the free minutes are credited
+// at the fare's per minute rate. Returns the usage counting the covered ride, or false when the pass covers nothing.
+func ApplyPass(fare *models.Fare, pass *models.Pass, plan *models.PassPlan, usage models.PassUsage) (models.PassUsage, bool) {
+	if plan.Currency != fare.Currency {
+		return usage, false
+	}
+	unlocks, minutes := Allowance(plan, usage)
+	freeUnlock := unlocks > 0 && fare.UnlockFee > 0
+	if minutes > fare.Minutes {
+		minutes = fare.Minutes
+	}
+	if !freeUnlock && (minutes == 0 || fare.TimeCharge == 0) {
+		return usage, false
+	}
+
+	var credit int64
+	if freeUnlock {
+		credit = fare.UnlockFee
+		usage.Unlocks++
+	}
+	if minutes > 0 && fare.TimeCharge > 0 {
+		credit += fare.TimeCharge * minutes / fare.Minutes
+		usage.Minutes += minutes
+	} else {
+		minutes = 0
+	}
+	fare.PassID, fare.FreeUnlock, fare.FreeMinutes, fare.PassCredit = pass.ID, freeUnlock, minutes, credit
+	fare.Total = fare.UnlockFee + fare.TimeCharge + fare.DistanceCharge - credit
+	return usage, true
+}
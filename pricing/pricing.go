@@ -0,0 +1,53 @@
+// Package pricing computes the rides' fares from the price plans.
+package pricing
+
+import (
+	"math"
+	"scootin/models"
+	"time"
+)
+
+// Calculate returns the fare of a ride of the duration and distance in meters priced by the plan.
+// Every started minute is billed and the distance charge is rounded to the nearest minor unit.
+func Calculate(plan *models.PricePlan, duration time.Duration, distance float64) *models.Fare {
+	minutes := int64(math.Ceil(duration.Minutes()))
+	if minutes < 0 {
+		minutes = 0
+	}
+	distance = math.Max(0, distance)
+
+	fare := &models.Fare{
+		PlanID:         plan.ID,
+		PlanVersion:    plan.Version,
+		Currency:       plan.Currency,
+		Minutes:        minutes,
+		Distance:       distance,
+		Surge:          1,
+		UnlockFee:      plan.UnlockFee,
+		TimeCharge:     minutes * plan.PerMinute,
+		DistanceCharge: int64(math.Round(distance / 1000 * float64(plan.PerKilometer))),
+	}
+	fare.Total = fare.UnlockFee + fare.TimeCharge + fare.DistanceCharge
+	return fare
+}
+
+// ValidPlan reports whether the plan can price rides: a vehicle type, a currency code and no negative rate
+func ValidPlan(plan *models.PricePlan) bool {
+	return plan != nil && len(plan.VehicleType) > 0 && len(plan.Currency) == 3 &&
+		plan.UnlockFee >= 0 && plan.PerMinute >= 0 && plan.PerKilometer >= 0
+}
+
+// Applies reports whether the plan prices the rides of the city and vehicle type started at the time,
+// the plans without a city apply to every city.
+func Applies(plan *models.PricePlan, city, vehicleType string, at time.Time) bool {
+	return plan.VehicleType == vehicleType && (plan.City == city || len(plan.City) == 0) && !plan.EffectiveFrom.After(at)
+}
+
+// Preferred reports whether the plan a is chosen over b when both apply:
+// the city's own plans come before the plans without a city, then the latest version first.
+func Preferred(a, b *models.PricePlan) bool {
+	if (len(a.City) > 0) != (len(b.City) > 0) {
+		return len(a.City) > 0
+	}
+	return a.Version > b.Version
+}
@@ -0,0 +1,157 @@
+package pricing
+
+import (
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculate(t *testing.T) {
+	plan := &models.PricePlan{ID: "p1", Version: 2, Currency: "EUR", UnlockFee: 100, PerMinute: 25, PerKilometer: 50}
+
+	// the started minutes are billed
+	fare := Calculate(plan, 4*time.Minute+time.Second, 1500)
+	assert.Equal(t, int64(5), fare.Minutes)
+	assert.Equal(t, int64(125), fare.TimeCharge)
+	assert.Equal(t, int64(75), fare.DistanceCharge)
+	assert.Equal(t, int64(300), fare.Total)
+	assert.Equal(t, "EUR", fare.Currency)
+	assert.Equal(t, 2, fare.PlanVersion)
+
+	fare = Calculate(plan, 0, 0)
+	assert.Equal(t, plan.UnlockFee, fare.Total)
+}
+
+func TestPlanChoice(t *testing.T) {
+	now := time.Now()
+	fallback := &models.PricePlan{VehicleType: "scooter", Version: 3}
+	berlin := &models.PricePlan{City: "berlin", VehicleType: "scooter", Version: 1}
+	future := &models.PricePlan{City: "berlin", VehicleType: "scooter", Version: 2, EffectiveFrom: now.Add(time.Hour)}
+
+	assert.True(t, Applies(fallback, "berlin", "scooter", now))
+	assert.True(t, Applies(berlin, "berlin", "scooter", now))
+	assert.False(t, Applies(berlin, "paris", "scooter", now))
+	assert.False(t, Applies(berlin, "berlin", "bike", now))
+	assert.False(t, Applies(future, "berlin", "scooter", now))
+
+	// the city's own plan wins over a later fallback version
+	assert.True(t, Preferred(berlin, fallback))
+	assert.True(t, Preferred(future, berlin))
+}
+
+func TestPromoDiscount(t *testing.T) {
+	now := time.Now()
+	expiresAt := now.Add(-time.Minute)
+	fare := &models.Fare{Currency: "EUR", UnlockFee: 100, TimeCharge: 250, DistanceCharge: 50, Total: 400}
+	firstFree := models.PromoCode{Code: "FIRST", Kind: models.PromoPercentOff, Percent: 100, Target: models.PromoTargetTotal, FirstRideOnly: true}
+	halfUnlock := models.PromoCode{Code: "HALF", Kind: models.PromoPercentOff, Percent: 50, Target: models.PromoTargetUnlock, City: "berlin"}
+	twoEuros := models.PromoCode{Code: "TWO", Kind: models.PromoAmountOff, Amount: 200, Currency: "EUR"}
+	expired := models.PromoCode{Code: "OLD", Kind: models.PromoAmountOff, Amount: 300, Currency: "EUR", ExpiresAt: &expiresAt}
+	for _, p := range []models.PromoCode{firstFree, halfUnlock, twoEuros, expired} {
+		assert.True(t, ValidPromo(&p))
+	}
+	assert.False(t, ValidPromo(&models.PromoCode{Code: "BAD", Kind: models.PromoPercentOff, Percent: 120, Target: models.PromoTargetTotal}))
+	assert.False(t, ValidPromo(&models.PromoCode{Code: "BAD", Kind: models.PromoVoucher, Amount: 500}))
+
+	assert.Equal(t, int64(400), Discount(&firstFree, fare, "berlin", true, now))
+	assert.Zero(t, Discount(&firstFree, fare, "berlin", false, now))
+	assert.Equal(t, int64(50), Discount(&halfUnlock, fare, "berlin", false, now))
+	assert.Zero(t, Discount(&halfUnlock, fare, "paris", false, now))
+	assert.Zero(t, Discount(&expired, fare, "berlin", false, now))
+	assert.False(t, Redeemable(&expired, now))
+
+	// the largest discount is applied and shown on the fare
+	applied := ApplyBestPromo(fare, []models.PromoCode{halfUnlock, twoEuros, expired}, "berlin", false, now)
+	assert.Equal(t, "TWO", applied.Code)
+	assert.Equal(t, int64(200), fare.Discount)
+	assert.Equal(t, "TWO", fare.PromoCode)
+	assert.Equal(t, int64(200), fare.Total)
+	assert.Nil(t, ApplyBestPromo(fare, []models.PromoCode{firstFree}, "berlin", false, now))
+}
+
+func TestApplyPass(t *testing.T) {
+	plan := &models.PassPlan{Name: "day", Period: models.PassDay, Currency: "EUR", Price: 500, FreeUnlocks: 1, FreeMinutes: 10}
+	pass := &models.Pass{ID: "pass"}
+	assert.True(t, ValidPassPlan(plan))
+	assert.False(t, ValidPassPlan(&models.PassPlan{Name: "none", Period: models.PassDay, Currency: "EUR"}))
+	start := time.Date(2021, 1, 31, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC), PassEnd(models.PassMonth, start))
+	assert.Equal(t, start.Add(24*time.Hour), PassEnd(models.PassDay, start))
+
+	// the unlock and the first 10 of the 12 minutes are covered
+	fare := &models.Fare{Currency: "EUR", Minutes: 12, UnlockFee: 100, TimeCharge: 240, DistanceCharge: 50, Total: 390}
+	usage, ok := ApplyPass(fare, pass, plan, models.PassUsage{PassID: pass.ID, Day: PassDay(start)})
+	assert.True(t, ok)
+	assert.Equal(t, models.PassUsage{PassID: pass.ID, Day: "2021-01-31", Unlocks: 1, Minutes: 10}, usage)
+	assert.True(t, fare.FreeUnlock)
+	assert.Equal(t, int64(10), fare.FreeMinutes)
+	assert.Equal(t, int64(300), fare.PassCredit)
+	assert.Equal(t, int64(90), fare.Total)
+
+	// the day's allowances are used up, the promo codes only discount what's left
+	_, ok = ApplyPass(&models.Fare{Currency: "EUR", Minutes: 3, UnlockFee: 100, TimeCharge: 60}, pass, plan, usage)
+	assert.False(t, ok)
+	unlock := models.PromoCode{Code: "UNLOCK", Kind: models.PromoPercentOff, Percent: 100, Target: models.PromoTargetUnlock}
+	assert.Zero(t, Discount(&unlock, fare, "berlin", false, start))
+	total := models.PromoCode{Code: "ALL", Kind: models.PromoPercentOff, Percent: 100, Target: models.PromoTargetTotal}
+	ApplyBestPromo(fare, []models.PromoCode{total}, "berlin", false, start)
+	assert.Equal(t, int64(90), fare.Discount)
+	assert.Zero(t, fare.Total)
+}
+
+func TestSurge(t *testing.T) {
+	windows, err := ParseRateWindows("07:00-10:00=1.25, 23:00-05:00=0.8")
+	assert.NoError(t, err)
+	assert.Equal(t, []RateWindow{{From: 7 * time.Hour, To: 10 * time.Hour, Rate: 1.25}, {From: 23 * time.Hour, To: 5 * time.Hour, Rate: 0.8}}, windows)
+	for _, spec := range []string{"07:00=1.2", "07:00-25:00=1.2", "07:00-10:00=0", "07:00-07:00=2"} {
+		_, err = ParseRateWindows(spec)
+		assert.Error(t, err, spec)
+	}
+
+	policy := &SurgePolicy{Threshold: 1, Sensitivity: 0.5, MaxMultiplier: 2, Windows: windows, Location: time.UTC}
+	noon, rush, night := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2021, 6, 1, 8, 0, 0, 0, time.UTC),
+		time.Date(2021, 6, 1, 2, 0, 0, 0, time.UTC)
+	assert.Equal(t, 1.0, policy.Multiplier(2, 4, noon))
+	assert.Equal(t, 1.5, policy.Multiplier(4, 2, noon))
+	assert.Equal(t, 2.0, policy.Multiplier(30, 0, noon))
+	assert.Equal(t, 1.88, policy.Multiplier(4, 2, rush))
+	assert.Equal(t, 0.8, policy.Multiplier(0, 5, night))
+	assert.Equal(t, 1.0, (&SurgePolicy{}).Multiplier(10, 0, rush))
+
+	fare := &models.Fare{UnlockFee: 100, TimeCharge: 125, DistanceCharge: 75, Total: 300}
+	ApplySurge(fare, 1.5)
+	assert.Equal(t, 1.5, fare.Surge)
+	assert.Equal(t, int64(150), fare.UnlockFee)
+	assert.Equal(t, int64(188), fare.TimeCharge)
+	assert.Equal(t, int64(451), fare.Total)
+}
+
+func TestStation(t *testing.T) {
+	center := models.Location{Latitude: 52.52, Longitude: 13.405}
+	stations := []models.Station{
+		{ID: "far", Location: center, Radius: 150, Capacity: 2, DiscountPercent: 10},
+		{ID: "near", Location: models.Location{Latitude: 52.5205, Longitude: 13.405}, Radius: 30, Capacity: 1, DiscountPercent: 20},
+	}
+	assert.True(t, ValidStation(&stations[0]))
+	assert.False(t, ValidStation(&models.Station{Location: center, Radius: 500, Capacity: 1}))
+	assert.False(t, ValidStation(&models.Station{Location: center, Radius: 50}))
+	assert.False(t, ValidStation(&models.Station{Location: center, Radius: 50, Capacity: 1, DiscountPercent: 101}))
+
+	// the nearest station containing the location is the one parked at
+	assert.Equal(t, "near", NearestStation(stations, models.Location{Latitude: 52.5204, Longitude: 13.405}).ID)
+	assert.Equal(t, "far", NearestStation(stations, models.Location{Latitude: 52.5195, Longitude: 13.405}).ID)
+	assert.Nil(t, NearestStation(stations, models.Location{Latitude: 52.53, Longitude: 13.405}))
+
+	// the discount needs a free slot
+	fare := &models.Fare{UnlockFee: 100, TimeCharge: 200, Discount: 50, Total: 250}
+	assert.False(t, ApplyStation(fare, &stations[1], 1))
+	assert.Equal(t, int64(250), fare.Total)
+	assert.True(t, ApplyStation(fare, &stations[1], 0))
+	assert.Equal(t, int64(50), fare.StationCredit)
+	assert.Equal(t, int64(200), fare.Total)
+
+	assert.Equal(t, models.StationOccupancy{Station: stations[0], Occupancy: 3, Rebalance: true}, Occupancy(stations[0], 3))
+	assert.Equal(t, 1, Occupancy(stations[0], 1).FreeSlots)
+}
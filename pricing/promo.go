@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"scootin/models"
+	"time"
+)
+
+// ValidPromo reports whether the promo code can be offered: a code, a percentage of a known fare part,
+// or a positive amount of a currency, and no negative cap.
+func ValidPromo(promo *models.PromoCode) bool {
+	if promo == nil || len(promo.Code) == 0 || promo.MaxRedemptions < 0 || promo.PerUserLimit < 0 {
+		return false
+	}
+	switch promo.Kind {
+	case models.PromoPercentOff:
+		return promo.Percent > 0 && promo.Percent <= 100 &&
+			(promo.Target == models.PromoTargetTotal || promo.Target == models.PromoTargetUnlock)
+	case models.PromoAmountOff, models.PromoVoucher:
+		return promo.Amount > 0 && len(promo.Currency) == 3
+	}
+	return false
+}
+
+// Redeemable reports whether the promo code can still be redeemed at the time
+func Redeemable(promo *models.PromoCode, at time.Time) bool {
+	return !expired(promo, at) && (promo.MaxRedemptions == 0 || promo.Redemptions < promo.MaxRedemptions)
+}
+
+// Usable reports whether the user's redemption of the promo code can discount another ride
+func Usable(promo *models.PromoCode, redemption *models.PromoRedemption) bool {
+	return promo.PerUserLimit == 0 || redemption.Uses < promo.PerUserLimit
+}
+
+// Discount returns what the promo code takes off the fare of a ride in the city ended at the time,
+// 0 when it doesn't apply. A first ride is the rider's first ended one.
+func Discount(promo *models.PromoCode, fare *models.Fare, city string, firstRide bool, at time.Time) int64 {
+	if expired(promo, at) || (len(promo.City) > 0 && promo.City != city) || (promo.FirstRideOnly && !firstRide) {
+		return 0
+	}
+	// what a pass covered isn't discounted again
+	charges := fare.UnlockFee + fare.TimeCharge + fare.DistanceCharge - fare.PassCredit
+	switch promo.Kind {
+	case models.PromoPercentOff:
+		base := charges
+		if promo.Target == models.PromoTargetUnlock {
+			base = fare.UnlockFee
+			if fare.FreeUnlock {
+				base = 0
+			}
+		}
+		return base * promo.Percent / 100
+	case models.PromoAmountOff:
+		if promo.Currency != fare.Currency {
+			return 0
+		}
+		if promo.Amount < charges {
+			return promo.Amount
+		}
+		return charges
+	}
+	return 0
+}
+
+// ApplyBestPromo takes the largest discount of the promo codes off the fare, returns the applied code or nil
+// when none applies.
+func ApplyBestPromo(fare *models.Fare, promos []models.PromoCode, city string, firstRide bool, at time.Time) *models.PromoCode {
+	var (
+		best     *models.PromoCode
+		discount int64
+	)
+	for i := range promos {
+		if d := Discount(&promos[i], fare, city, firstRide, at); d > discount {
+			best, discount = &promos[i], d
+		}
+	}
+	if best != nil {
+		fare.Discount, fare.PromoCode = discount, best.Code
+		fare.Total = fare.UnlockFee + fare.TimeCharge + fare.DistanceCharge - fare.PassCredit - discount
+	}
+	return best
+}
+
+func expired(promo *models.PromoCode, at time.Time) bool {
+	return promo.ExpiresAt != nil && !at.Before(*promo.ExpiresAt)
+}
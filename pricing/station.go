@@ -0,0 +1,51 @@
+package pricing
+
+import (
+	"scootin/geo"
+	"scootin/models"
+)
+
+// MaxStationRadius is the largest station radius in meters
+const MaxStationRadius = 200.0
+
+// ValidStation reports whether the station can be opened: a valid location, a radius up to MaxStationRadius,
+// a capacity and a discount within 0-100%.
+func ValidStation(station *models.Station) bool {
+	return station != nil && geo.Valid(station.Location) && station.Radius > 0 && station.Radius <= MaxStationRadius &&
+		station.Capacity > 0 && station.DiscountPercent >= 0 && station.DiscountPercent <= 100
+}
+
+// NearestStation returns the nearest of the stations whose radius contains the location, nil when there's none
+func NearestStation(stations []models.Station, l models.Location) *models.Station {
+	var (
+		nearest  *models.Station
+		distance float64
+	)
+	for i := range stations {
+		if d := geo.Distance(stations[i].Location, l); d <= stations[i].Radius && (nearest == nil || d < distance) {
+			nearest, distance = &stations[i], d
+		}
+	}
+	return nearest
+}
+
+// Occupancy returns the station with its parked scooters
+func Occupancy(station models.Station, parked int) models.StationOccupancy {
+	free := station.Capacity - parked
+	if free < 0 {
+		free = 0
+	}
+	return models.StationOccupancy{Station: station, Occupancy: parked, FreeSlots: free, Rebalance: parked > station.Capacity}
+}
+
+// ApplyStation takes the discount of the station the trip ended at off what's left to pay of the fare,
+// the station needs a free slot for the scooter.
+func ApplyStation(fare *models.Fare, station *models.Station, parked int) bool {
+	if parked >= station.Capacity {
+		return false
+	}
+	credit := fare.Total * station.DiscountPercent / 100
+	fare.StationID, fare.StationCredit = station.ID, credit
+	fare.Total -= credit
+	return true
+}
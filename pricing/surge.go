@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"scootin/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateWindow multiplies the prices by Rate within [From, To) of the day, a window ending before it starts
+// goes past midnight.
+type RateWindow struct {
+	From time.Duration // since midnight
+	To   time.Duration // since midnight
+	Rate float64
+}
+
+// SurgePolicy computes the price multiplier of a zone from its demand and supply and the time of the day.
+// The zero policy never surges.
+type SurgePolicy struct {
+	// Threshold is the demand to supply ratio above which the zone surges
+	Threshold float64
+	// Sensitivity is how much the multiplier grows for every unit of ratio above the Threshold
+	Sensitivity float64
+	// MaxMultiplier caps the zone's surge, 0 is no cap
+	MaxMultiplier float64
+	// Windows are the time-of-day rates in the Location's time, the first window containing the time applies
+	Windows  []RateWindow
+	Location *time.Location
+}
+
+// Multiplier returns the price multiplier of a zone with the demand and supply at the time, rounded to hundredths.
+// A zone without supply has the demand as its ratio.
+func (p *SurgePolicy) Multiplier(demand, supply int, at time.Time) float64 {
+	surge := 1.0
+	ratio := float64(demand) / math.Max(1, float64(supply))
+	if ratio > p.Threshold && p.Sensitivity > 0 {
+		surge += p.Sensitivity * (ratio - p.Threshold)
+		if p.MaxMultiplier > 0 {
+			surge = math.Min(surge, p.MaxMultiplier)
+		}
+	}
+	return math.Round(surge*p.rate(at)*100) / 100
+}
+
+// rate returns the rate of the first window containing the time, 1 out of the windows
+func (p *SurgePolicy) rate(at time.Time) float64 {
+	if p.Location != nil {
+		at = at.In(p.Location)
+	}
+	midnight := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	offset := at.Sub(midnight)
+	for _, w := range p.Windows {
+		if w.From <= w.To && offset >= w.From && offset < w.To {
+			return w.Rate
+		} else if w.From > w.To && (offset >= w.From || offset < w.To) {
+			return w.Rate
+		}
+	}
+	return 1
+}
+
+// ParseRateWindows parses the comma separated "HH:MM-HH:MM=rate" windows, e.g. "07:00-10:00=1.25,23:00-05:00=0.8"
+func ParseRateWindows(spec string) ([]RateWindow, error) {
+	windows := make([]RateWindow, 0)
+	for _, w := range strings.Split(spec, ",") {
+		if w = strings.TrimSpace(w); len(w) == 0 {
+			continue
+		}
+		span, rate, ok := cut(w, "=")
+		from, to, ok2 := cut(span, "-")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid rate window %q", w)
+		}
+		window := RateWindow{}
+		var err error
+		if window.From, err = timeOfDay(from); err != nil {
+			return nil, fmt.Errorf("invalid rate window %q: %s", w, err)
+		}
+		if window.To, err = timeOfDay(to); err != nil {
+			return nil, fmt.Errorf("invalid rate window %q: %s", w, err)
+		}
+		if window.Rate, err = strconv.ParseFloat(rate, 64); err != nil || window.Rate <= 0 || window.From == window.To {
+			return nil, fmt.Errorf("invalid rate window %q", w)
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// ApplySurge multiplies the fare's charges by the multiplier, each rounded to the nearest minor unit
+func ApplySurge(fare *models.Fare, multiplier float64) {
+	if multiplier <= 0 {
+		return
+	}
+	surge := func(charge int64) int64 { return int64(math.Round(float64(charge) * multiplier)) }
+	fare.Surge = multiplier
+	fare.UnlockFee, fare.TimeCharge, fare.DistanceCharge = surge(fare.UnlockFee), surge(fare.TimeCharge), surge(fare.DistanceCharge)
+	fare.Total = fare.UnlockFee + fare.TimeCharge + fare.DistanceCharge
+}
+
+// timeOfDay parses a "HH:MM" time of the day, "24:00" is the end of the day
+func timeOfDay(s string) (time.Duration, error) {
+	hours, minutes, ok := cut(s, ":")
+	h, err := strconv.Atoi(hours)
+	if !ok || err != nil || h < 0 || h > 24 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || (h == 24 && m > 0) {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// cut slices s around the first separator, strings.Cut isn't in go 1.17
+func cut(s, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+len(sep):]), true
+	}
+	return s, "", false
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// adminToken is the bearer token of the operators, the admin endpoints are closed while it's empty
+var adminToken string
+
+// SetAdminToken sets the operators' bearer token
+func SetAdminToken(token string) {
+	adminToken = token
+}
+
+// adminOnly lets only the requests carrying the operators' bearer token through
+func adminOnly(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if !isAdmin(r) {
+			writeError(w, r, models.Errorf(models.ErrForbidden, "%s %s is for operators only", r.Method, r.URL.Path))
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
+// isAdmin reports whether the request carries the operators' bearer token
+func isAdmin(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return len(adminToken) > 0 && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// SetScooterStatus moves a scooter to another operational status, returns the updated scooter
+func SetScooterStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var change models.StatusChange
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &change); err != nil || !change.Status.Valid() {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid status change %s", body))
+		return
+	}
+
+	sc, err := db.SetScooterStatus(r.Context(), ps.ByName("id"), change.Status)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, sc)
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"scootin/payment"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// paymentProvider captures the checkouts, they fail while it's not set
+	paymentProvider payment.Provider
+	// paymentTimeout is how long the payment provider is waited for
+	paymentTimeout = 10 * time.Second
+	// merchantCode is the merchant paid by the checkouts which don't name one
+	merchantCode = "SCOOTIN"
+)
+
+// SetPaymentProvider sets the provider capturing the checkouts and how long it's waited for
+func SetPaymentProvider(provider payment.Provider, timeout time.Duration) {
+	paymentProvider, paymentTimeout = provider, timeout
+}
+
+// SetMerchantCode sets the merchant paid by default
+func SetMerchantCode(code string) {
+	merchantCode = code
+}
+
+// CreateCheckout pays the requesting user's finished trip, returns the captured checkout
+func CreateCheckout(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var create *models.CheckoutCreate
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &create); err != nil || create == nil || len(create.TripID) == 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid checkout %s", body))
+		return
+	}
+
+	trip, err := db.GetTrip(r.Context(), create.TripID)
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case trip.UserID != userID:
+		writeError(w, r, models.Errorf(models.ErrForbidden, "trip %s isn't user %s's", trip.ID, userID))
+		return
+	case trip.EndedAt == nil || trip.Fare == nil:
+		writeError(w, r, models.Errorf(models.ErrTripNotFinished, "trip %s", trip.ID))
+		return
+	}
+	// the wallet pays what it can of the fare, the caller may state what it expects to pay of the rest
+	due, err := fareDue(r.Context(), trip)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	} else if due == 0 {
+		writeError(w, r, models.Errorf(models.ErrTripAlreadyPaid, "trip %s is paid by the wallet", trip.ID))
+		return
+	}
+	if (create.Amount != 0 && create.Amount != due) || (len(create.Currency) > 0 && create.Currency != trip.Fare.Currency) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "%d %s of the trip %s fare is due", due, trip.Fare.Currency, trip.ID))
+		return
+	}
+	if len(create.MerchantCode) == 0 {
+		create.MerchantCode = merchantCode
+	}
+
+	checkout := &models.Checkout{
+		ID:           uuid.New().String(),
+		TripID:       trip.ID,
+		UserID:       userID,
+		MerchantCode: create.MerchantCode,
+		Amount:       due,
+		Currency:     trip.Fare.Currency,
+		Status:       models.CheckoutPending,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := db.CreateCheckout(r.Context(), checkout); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := capture(checkout); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, checkout)
+}
+
+// GetCheckout returns the checkout by its id to its user or the operators
+func GetCheckout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	checkout, err := db.GetCheckout(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !isAdmin(r) && r.Header.Get("user-id") != checkout.UserID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "checkout %s", checkout.ID))
+		return
+	}
+
+	writeJSON(w, checkout)
+}
+
+// capture charges the pending checkout through the payment provider and stores the outcome,
+// it's not bound to the request so the outcome is stored even when the caller has gone.
+func capture(checkout *models.Checkout) error {
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+
+	var (
+		ref string
+		err = errNoPaymentProvider
+	)
+	if paymentProvider != nil {
+		ref, err = paymentProvider.Capture(ctx, payment.Request{ID: checkout.ID, PayerID: checkout.UserID,
+			MerchantCode: checkout.MerchantCode, Amount: checkout.Amount, Currency: checkout.Currency})
+	}
+	return completeCheckout(checkout, ref, paymentError(err, checkout.ID))
+}
+
+// completeCheckout stores the outcome of the checkout's capture, returns the capture's error
+func completeCheckout(checkout *models.Checkout, ref string, err error) error {
+	if err == nil {
+		checkout.Status, checkout.ProviderReference = models.CheckoutCaptured, ref
+	} else if errors.Is(err, models.ErrPaymentDeclined) {
+		checkout.Status = models.CheckoutDeclined
+	} else {
+		checkout.Status = models.CheckoutFailed
+	}
+	checkout.FailureCode = errorCode(err)
+	completedAt := time.Now().UTC()
+	checkout.CompletedAt = &completedAt
+
+	if uerr := db.UpdateCheckout(context.Background(), checkout); uerr != nil {
+		return uerr
+	}
+	return err
+}
+
+// errNoPaymentProvider fails the payments while there's no provider
+var errNoPaymentProvider = models.Errorf(models.ErrPaymentFailed, "no payment provider")
+
+// paymentError translates the payment provider's error of the payment id to its domain error,
+// a provider not answering in time is a timeout and the other failures are ErrPaymentFailed.
+func paymentError(err error, id string) error {
+	var domainErr *models.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return models.Errorf(models.ErrPaymentTimeout, "payment %s", id)
+	case errors.As(err, &domainErr) && (domainErr == models.ErrPaymentDeclined || domainErr == models.ErrPaymentFailed):
+		return err
+	}
+	return models.Errorf(models.ErrPaymentFailed, "payment %s: %s", id, err)
+}
+
+// errorCode returns the code of the domain error the error wraps, empty without one
+func errorCode(err error) string {
+	var domainErr *models.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return ""
+}
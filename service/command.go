@@ -0,0 +1,110 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxCommandTTL is the longest a command may wait for its scooter
+const maxCommandTTL = 24 * time.Hour
+
+// commandTTL is how long a command waits for its scooter unless it's issued with its own TTL
+var commandTTL = 5 * time.Minute
+
+// SetCommandTTL sets how long a command waits for its scooter by default
+func SetCommandTTL(ttl time.Duration) {
+	commandTTL = ttl
+}
+
+// IssueCommand queues an operator's command to a scooter, returns the queued command
+func IssueCommand(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var create models.CommandCreate
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &create); err != nil || !create.Kind.Valid() || create.TTL < 0 ||
+		time.Duration(create.TTL)*time.Second > maxCommandTTL ||
+		(create.Kind == models.CommandSpeedLimit) != (create.SpeedLimit > 0) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid command %s", body))
+		return
+	}
+	ttl := commandTTL
+	if create.TTL > 0 {
+		ttl = time.Duration(create.TTL) * time.Second
+	}
+
+	now := time.Now().UTC()
+	command := &models.Command{ID: uuid.New().String(), ScooterID: ps.ByName("id"), Kind: create.Kind,
+		SpeedLimit: create.SpeedLimit, State: models.CommandQueued, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+	if err := db.CreateCommand(r.Context(), command); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, command)
+}
+
+// GetCommand returns a command with its delivery state
+func GetCommand(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	command, err := db.GetCommand(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, command)
+}
+
+// ListCommands lists a scooter's commands in their creation order
+func ListCommands(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	commands, err := db.ListCommands(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, commands)
+}
+
+// PollCommands delivers the scooter its commands waiting for an acknowledgment, the oldest first. A command
+// not acknowledged is delivered again by the next polls until it expires.
+func PollCommands(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	commands, err := db.PollCommands(r.Context(), ps.ByName("id"), time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, commands)
+}
+
+// AckCommand ends a delivered command as the scooter acknowledged it, acked or failed
+func AckCommand(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var ack models.CommandAck
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &ack); err != nil || (ack.State != models.CommandAcked && ack.State != models.CommandFailed) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid acknowledgment %s", body))
+		return
+	}
+
+	command, err := db.AckCommand(r.Context(), ps.ByName("id"), ps.ByName("command"), ack, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, command)
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/ledger"
+	"scootin/models"
+	"scootin/payment"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateDispute opens a dispute of the requesting user's ended trip, returns the open dispute
+func CreateDispute(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var create *models.DisputeCreate
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &create); err != nil || create == nil || len(create.TripID) == 0 || len(create.Reason) == 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid dispute %s", body))
+		return
+	}
+
+	trip, err := db.GetTrip(r.Context(), create.TripID)
+	switch {
+	case err != nil:
+		writeError(w, r, err)
+		return
+	case trip.UserID != userID:
+		writeError(w, r, models.Errorf(models.ErrForbidden, "trip %s isn't user %s's", trip.ID, userID))
+		return
+	case trip.EndedAt == nil:
+		writeError(w, r, models.Errorf(models.ErrTripNotFinished, "trip %s", trip.ID))
+		return
+	}
+
+	now := time.Now().UTC()
+	dispute := &models.Dispute{
+		ID:        uuid.New().String(),
+		TripID:    trip.ID,
+		UserID:    userID,
+		Reason:    create.Reason,
+		Status:    models.DisputeOpen,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Events:    []models.DisputeEvent{{Status: models.DisputeOpen, Actor: models.ActorRider, Note: create.Reason, At: now}},
+	}
+	if err := db.CreateDispute(r.Context(), dispute); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, dispute)
+}
+
+// GetDispute returns the dispute with its audit trail to its user or the operators
+func GetDispute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	dispute, err := db.GetDispute(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !isAdmin(r) && r.Header.Get("user-id") != dispute.UserID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "dispute %s", dispute.ID))
+		return
+	}
+
+	writeJSON(w, dispute)
+}
+
+// ListDisputes lists the disputes in the "status" query, all of them by default, the oldest first
+func ListDisputes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	disputes, err := db.ListDisputes(r.Context(), models.DisputeStatus(r.URL.Query().Get("status")))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, disputes)
+}
+
+// GetDisputeReview returns the dispute with its trip and the trip's location history
+func GetDisputeReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	dispute, err := db.GetDispute(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	review, err := disputeReview(r.Context(), dispute)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, review)
+}
+
+// ReviewDispute takes the open dispute under review, returns it with its trip and the trip's location history
+func ReviewDispute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	dispute, err := db.GetDispute(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	event := models.DisputeEvent{Status: models.DisputeUnderReview, Actor: models.ActorSupport, At: time.Now().UTC()}
+	if err := db.UpdateDispute(r.Context(), dispute, event, nil); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	review, err := disputeReview(r.Context(), dispute)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, review)
+}
+
+// DecideDispute refunds or rejects the dispute under review, returns the decided dispute
+func DecideDispute(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var decision *models.DisputeDecision
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &decision); err != nil || decision == nil ||
+		(decision.Status != models.DisputeRefunded && decision.Status != models.DisputeRejected) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid decision %s", body))
+		return
+	}
+	dispute, err := db.GetDispute(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// nothing is refunded unless the dispute can be decided
+	if !dispute.Status.CanTransitionTo(decision.Status) {
+		writeError(w, r, models.Errorf(models.ErrInvalidDisputeStatus, "dispute %s is %s", dispute.ID, dispute.Status))
+		return
+	}
+
+	var refund *models.LedgerTransaction
+	if decision.Status == models.DisputeRefunded {
+		if refund, err = refundFare(r.Context(), dispute, decision.Amount); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	event := models.DisputeEvent{Status: decision.Status, Actor: models.ActorSupport, Note: decision.Note, At: time.Now().UTC()}
+	if err := db.UpdateDispute(r.Context(), dispute, event, refund); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, dispute)
+}
+
+// refundFare gives the amount of the disputed trip's fare back, the whole fare when it's 0, and returns its ledger posting.
+// A paid fare is refunded through the payment provider, the dispute's id being the idempotency key, up to the captured
+// amount; the part the wallet paid, or an unpaid fare, is credited to the rider's wallet.
+func refundFare(ctx context.Context, dispute *models.Dispute, amount int64) (*models.LedgerTransaction, error) {
+	trip, err := db.GetTrip(ctx, dispute.TripID)
+	if err != nil {
+		return nil, err
+	} else if trip.Fare == nil {
+		return nil, models.Errorf(models.ErrTripNotFinished, "trip %s", trip.ID)
+	}
+	if amount == 0 {
+		amount = trip.Fare.Total
+	}
+	if amount < 0 || amount > trip.Fare.Total {
+		return nil, models.Errorf(models.ErrInvalidRequest, "the trip %s fare is %d %s", trip.ID, trip.Fare.Total, trip.Fare.Currency)
+	}
+	money := models.Money{Amount: amount, Currency: trip.Fare.Currency}
+	dispute.RefundAmount, dispute.Currency = money.Amount, money.Currency
+	if amount == 0 {
+		return nil, nil
+	}
+
+	checkout, err := db.CapturedCheckout(ctx, trip.ID)
+	if errors.Is(err, models.ErrCheckoutNotFound) {
+		return ledger.Refund(dispute.UserID, dispute.ID, money, models.WalletAccount(dispute.UserID)), nil
+	} else if err != nil {
+		return nil, err
+	}
+	if paymentProvider == nil {
+		return nil, errNoPaymentProvider
+	}
+	refunded := amount
+	if refunded > checkout.Amount {
+		refunded = checkout.Amount
+	}
+	pctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+	ref, err := paymentProvider.Refund(pctx, checkout.ProviderReference, payment.Request{ID: dispute.ID, PayerID: dispute.UserID,
+		MerchantCode: checkout.MerchantCode, Amount: refunded, Currency: money.Currency})
+	if err != nil {
+		return nil, paymentError(err, dispute.ID)
+	}
+	dispute.RefundReference = ref
+	if refunded < amount {
+		return ledger.SplitRefund(dispute.UserID, dispute.ID, money, refunded), nil
+	}
+	return ledger.Refund(dispute.UserID, dispute.ID, money, models.AccountPayments), nil
+}
+
+// disputeReview returns the dispute with its trip and the scooter's locations during the trip
+func disputeReview(ctx context.Context, dispute *models.Dispute) (*models.DisputeReview, error) {
+	trip, err := db.GetTrip(ctx, dispute.TripID)
+	if err != nil {
+		return nil, err
+	}
+	end := time.Now().UTC()
+	if trip.EndedAt != nil {
+		end = *trip.EndedAt
+	}
+	track, err := db.ScooterTrack(ctx, trip.ScooterID, trip.StartedAt, end)
+	if err != nil {
+		return nil, err
+	}
+	return &models.DisputeReview{Dispute: *dispute, Trip: *trip, Track: track}, nil
+}
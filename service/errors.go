@@ -0,0 +1,113 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"scootin/db"
+	"scootin/logger"
+	"scootin/models"
+)
+
+// problemContentType is the RFC 7807 media type of the error responses
+const problemContentType = "application/problem+json"
+
+// errorStatus maps the domain errors to their HTTP status
+var errorStatus = map[*models.Error]int{
+	models.ErrScooterOccupied:         http.StatusConflict,
+	models.ErrScooterNotFound:         http.StatusNotFound,
+	models.ErrUserNotFound:            http.StatusNotFound,
+	models.ErrTripNotFound:            http.StatusNotFound,
+	models.ErrNoActiveTrip:            http.StatusConflict,
+	models.ErrActiveTripExists:        http.StatusConflict,
+	models.ErrScooterUnavailable:      http.StatusConflict,
+	models.ErrInvalidTransition:       http.StatusConflict,
+	models.ErrReservationNotFound:     http.StatusNotFound,
+	models.ErrReservationNotActive:    http.StatusConflict,
+	models.ErrActiveReservationExists: http.StatusConflict,
+	models.ErrPricePlanNotFound:       http.StatusNotFound,
+	models.ErrCheckoutNotFound:        http.StatusNotFound,
+	models.ErrTripNotFinished:         http.StatusConflict,
+	models.ErrTripAlreadyPaid:         http.StatusConflict,
+	models.ErrPaymentDeclined:         http.StatusPaymentRequired,
+	models.ErrPaymentTimeout:          http.StatusGatewayTimeout,
+	models.ErrPaymentFailed:           http.StatusBadGateway,
+	models.ErrPaymentSagaNotFound:     http.StatusNotFound,
+	models.ErrInsufficientBalance:     http.StatusPaymentRequired,
+	models.ErrDisputeNotFound:         http.StatusNotFound,
+	models.ErrDisputeExists:           http.StatusConflict,
+	models.ErrInvalidDisputeStatus:    http.StatusConflict,
+	models.ErrPromoNotFound:           http.StatusNotFound,
+	models.ErrPromoExists:             http.StatusConflict,
+	models.ErrPromoUnavailable:        http.StatusConflict,
+	models.ErrPromoAlreadyRedeemed:    http.StatusConflict,
+	models.ErrPassPlanNotFound:        http.StatusNotFound,
+	models.ErrPassNotFound:            http.StatusNotFound,
+	models.ErrTaxRuleNotFound:         http.StatusNotFound,
+	models.ErrInvoiceNotFound:         http.StatusNotFound,
+	models.ErrInvoiceExists:           http.StatusConflict,
+	models.ErrZoneNotFound:            http.StatusNotFound,
+	models.ErrOutsideOperatingArea:    http.StatusConflict,
+	models.ErrNoParkingZone:           http.StatusConflict,
+	models.ErrScooterTooFar:           http.StatusConflict,
+	models.ErrCommandNotFound:         http.StatusNotFound,
+	models.ErrCommandNotDelivered:     http.StatusConflict,
+	models.ErrForbidden:               http.StatusForbidden,
+	models.ErrInvalidRequest:          http.StatusBadRequest,
+	models.ErrMissingUserID:           http.StatusBadRequest,
+	models.ErrUnavailable:             http.StatusServiceUnavailable,
+	models.ErrInternal:                http.StatusInternalServerError,
+}
+
+// writeError writes the error as a problem response, the status is chosen by the domain error it wraps.
+// The errors which aren't domain ones are internal unless the database is unavailable.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var domainErr *models.Error
+	if !errors.As(err, &domainErr) {
+		if db.IsUnavailable(err) {
+			domainErr = models.ErrUnavailable
+		} else {
+			domainErr = models.ErrInternal
+		}
+	}
+	status, ok := errorStatus[domainErr]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	problem := models.Problem{
+		Type:   "urn:scootin:problem:" + domainErr.Code,
+		Title:  domainErr.Message,
+		Status: status,
+		Detail: err.Error(),
+		Code:   domainErr.Code,
+	}
+	if status >= http.StatusInternalServerError {
+		logger.Errorf("%s %s failed: %s", r.Method, r.URL.Path, err)
+		// the internal details aren't for the callers
+		problem.Detail = ""
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(problem); err != nil {
+		logger.Error(err)
+	}
+}
+
+// writeJSON writes the value as the JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Error(err)
+	}
+}
+
+// requestUserID returns the requesting user's id from the "user-id" header
+func requestUserID(r *http.Request) (string, error) {
+	id := r.Header.Get("user-id")
+	if len(id) == 0 {
+		return "", models.ErrMissingUserID
+	}
+	return id, nil
+}
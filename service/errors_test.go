@@ -0,0 +1,40 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"scootin/logger"
+	"scootin/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{models.Errorf(models.ErrScooterOccupied, "scooter sc1"), http.StatusConflict, "scooter_occupied"},
+		{models.Errorf(models.ErrScooterNotFound, "scooter sc1"), http.StatusNotFound, "scooter_not_found"},
+		{models.ErrMissingUserID, http.StatusBadRequest, "missing_user_id"},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, http.StatusServiceUnavailable, "unavailable"},
+		{errors.New("boom"), http.StatusInternalServerError, "internal"},
+	} {
+		w := httptest.NewRecorder()
+		writeError(w, httptest.NewRequest(http.MethodGet, "/", nil), tc.err)
+
+		var problem models.Problem
+		assert.Equal(t, tc.status, w.Code)
+		assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		assert.Equal(t, tc.code, problem.Code)
+		assert.Equal(t, tc.status, problem.Status)
+	}
+}
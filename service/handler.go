@@ -1,18 +1,30 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"scootin/db"
+	"scootin/geo"
 	"scootin/logger"
 	"scootin/models"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 )
 
+// bookingDistance is how far in meters a rider may be from the scooter they book, 0 turns the check off
+var bookingDistance = 100.0
+
+// SetBookingDistance sets how far in meters a rider may be from the scooter they book
+func SetBookingDistance(meters float64) {
+	bookingDistance = meters
+}
+
 func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	fmt.Fprintf(w, "Hello, welcome to the Scootin")
 }
@@ -20,35 +32,81 @@ func Index(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 func BookScooter(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	scooterID := ps.ByName("id")
 	if len(scooterID) == 0 {
-		logger.Errorf("Empty scooterID")
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "empty scooter id"))
+		return
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	near, err := checkProximity(r, scooterID)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-	userID := r.Header.Get("user-id")
-	if err := db.BookScooter(r.Context(), scooterID, userID); err != nil {
-		logger.Errorf("couldn't book scooter %s for user %s: %s", scooterID, userID, err)
-		http.Error(w, err.Error(), 500)
+	// the zone's surge is locked in for the trip
+	surge, err := scooterSurge(r.Context(), scooterID, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	trip, err := startTripWithHold(r.Context(), userID, scooterID, func(ctx context.Context) (*models.Trip, error) {
+		return db.BookScooter(ctx, scooterID, userID, surge, near)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+
+	// returns the started trip
+	writeJSON(w, trip)
 }
 
 func ReleaseScooter(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	userID := r.Header.Get("user-id")
-	if err := db.ReleaseScooter(r.Context(), userID); err != nil {
-		logger.Errorf("couldn't release scooter for user %s: %s", userID, err)
-		http.Error(w, err.Error(), 500)
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	trip, err := db.ReleaseScooter(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
+	// the trip has ended anyway, a fare the hold couldn't pay is left to a checkout
+	if err := chargeTripFare(r.Context(), trip); err != nil {
+		logger.Errorf("couldn't charge the fare of trip %s: %s", trip.ID, err)
+	}
+
+	// returns the ended trip with its fare
+	writeJSON(w, trip)
 }
 
+// ListAvailableScooter lists the available scooters, when "lat" and "lon" are queried
+// only the ones within "radius" meters are listed, up to "limit" of them and the nearest first.
 func ListAvailableScooter(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	sc, err := db.ListAvailableScooter(r.Context())
+	var (
+		sc  interface{}
+		err error
+	)
+	query := r.URL.Query()
+	if query.Has("lat") || query.Has("lon") {
+		center, radius, limit, qerr := nearbyQuery(query)
+		if qerr != nil {
+			writeError(w, r, qerr)
+			return
+		}
+		sc, err = db.ListAvailableScooterNear(r.Context(), center, radius, limit)
+	} else {
+		sc, err = db.ListAvailableScooter(r.Context())
+	}
 	if err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
+		writeError(w, r, err)
+		return
 	}
 
-	if err = json.NewEncoder(w).Encode(sc); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
-	}
+	writeJSON(w, sc)
 }
 
 // CreateUser creates a new user, returns the user UUID
@@ -60,44 +118,137 @@ func CreateUser(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	)
 	body, err = ioutil.ReadAll(r.Body)
 	if err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 400)
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
 	}
-	if err = json.Unmarshal(body, &user); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 400)
+	if err = json.Unmarshal(body, &user); err != nil || user == nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid user %s", body))
+		return
 	}
 
 	// assign user uuid
 	user.ID = uuid.New().String()
 
 	if err := db.CreateUser(r.Context(), user); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
+		writeError(w, r, err)
+		return
 	}
 
 	// returns the user UUID
-	if err = json.NewEncoder(w).Encode(&models.UUIDResponse{ID: user.ID}); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
-	}
+	writeJSON(w, &models.UUIDResponse{ID: user.ID})
 }
 
 // CreateScooter creates a new scooter, returns its UUID
 func CreateScooter(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var (
-		err error
+		body    []byte
+		err     error
+		scooter models.ScooterCreate
 	)
+	body, err = ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	// the scooter's initial location, city and vehicle type are optional
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &scooter); err != nil || !geo.Valid(scooter.Location) {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid scooter %s", body))
+			return
+		}
+	}
 
 	scotterID := uuid.New().String()
-	if err := db.CreateScooter(r.Context(), scotterID); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
+	if err := db.CreateScooter(r.Context(), &models.ScooterInfo{ID: scotterID, Location: scooter.Location,
+		City: scooter.City, VehicleType: scooter.VehicleType}); err != nil {
+		writeError(w, r, err)
+		return
 	}
 
 	// returns the scooter UUID
-	if err = json.NewEncoder(w).Encode(models.UUIDResponse{ID: scotterID}); err != nil {
-		logger.Error(err)
-		http.Error(w, err.Error(), 500)
+	writeJSON(w, models.UUIDResponse{ID: scotterID})
+}
+
+// GetTrip returns the trip by its id
+func GetTrip(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	trip, err := db.GetTrip(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, trip)
+}
+
+// ListUserTrips returns the user's trips, the oldest first
+func ListUserTrips(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	trips, err := db.ListUserTrips(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, trips)
+}
+
+// ScooterTrack returns the scooter's location history within the "from" and "to" RFC3339 query times,
+// the last hour by default.
+func ScooterTrack(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var (
+		err       error
+		scooterID = ps.ByName("id")
+		to        = time.Now().UTC()
+		from      time.Time
+	)
+	query := r.URL.Query()
+	if v := query.Get("to"); len(v) > 0 {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid to time: %s", err))
+			return
+		}
+	}
+	from = to.Add(-time.Hour)
+	if v := query.Get("from"); len(v) > 0 {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid from time: %s", err))
+			return
+		}
+	}
+	if !from.Before(to) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "from must be before to"))
+		return
+	}
+
+	track, err := db.ScooterTrack(r.Context(), scooterID, from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, track)
+}
+
+// checkProximity checks the rider at the "lat" and "lon" queried is close enough to the scooter's last reported location,
+// returns the proximity the repository checks again when the trip starts or the scooter is reserved.
+// The operators take the scooters from anywhere, they and a zero booking distance get a nil proximity.
+func checkProximity(r *http.Request, scooterID string) (*models.Proximity, error) {
+	if bookingDistance <= 0 || isAdmin(r) {
+		return nil, nil
+	}
+	query := r.URL.Query()
+	if !query.Has("lat") || !query.Has("lon") {
+		return nil, models.Errorf(models.ErrInvalidRequest, "the rider's position is required to take a scooter")
+	}
+	position, err := locationQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := db.GetScooter(r.Context(), scooterID)
+	if err != nil {
+		return nil, err
+	}
+	if d := geo.Distance(position, sc.Location); d > bookingDistance {
+		return nil, models.Errorf(models.ErrScooterTooFar, "scooter %s is %.0fm away", scooterID, d)
 	}
+	return &models.Proximity{Position: position, MaxDistance: bookingDistance}, nil
 }
@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/invoice"
+	"scootin/ledger"
+	"scootin/logger"
+	"scootin/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// SetTaxRule stores the VAT rate and the legal entity of a city, the rule without a city applies to the cities
+// without their own. Returns the stored rule.
+func SetTaxRule(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var rule *models.TaxRule
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &rule); err != nil || !invoice.ValidRule(rule) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid tax rule %s", body))
+		return
+	}
+
+	if err := db.SetTaxRule(r.Context(), rule); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, rule)
+}
+
+// ListTaxRules lists the tax rules ordered by city
+func ListTaxRules(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	rules, err := db.ListTaxRules(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, rules)
+}
+
+// IssueInvoices issues the invoices of the ended "month", "2006-01", returns the newly issued ones
+// and the charges left unbilled
+func IssueInvoices(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	run, err := issueInvoices(r.Context(), r.URL.Query().Get("month"), time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, run)
+}
+
+// GetInvoice returns the invoice by its id to its user or the operators, as a printable page with "format=html"
+func GetInvoice(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	inv, err := db.GetInvoice(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !isAdmin(r) && r.Header.Get("user-id") != inv.UserID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "invoice %s", inv.ID))
+		return
+	}
+
+	if r.URL.Query().Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := invoice.WriteHTML(w, inv); err != nil {
+			logger.Error(err)
+		}
+		return
+	}
+	writeJSON(w, inv)
+}
+
+// ListUserInvoices lists the user's invoices to the user or the operators, the oldest first
+func ListUserInvoices(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if !isAdmin(r) && r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "user %s's invoices", userID))
+		return
+	}
+	invoices, err := db.ListUserInvoices(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, invoices)
+}
+
+// ExportLedger exports the ledger entries posted within the "from" and "to" RFC3339 query times as CSV,
+// the current month by default.
+func ExportLedger(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var (
+		err  error
+		to   = time.Now().UTC()
+		from = time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	)
+	query := r.URL.Query()
+	if v := query.Get("from"); len(v) > 0 {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid from time: %s", err))
+			return
+		}
+	}
+	if v := query.Get("to"); len(v) > 0 {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid to time: %s", err))
+			return
+		}
+	}
+	if !from.Before(to) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "from must be before to"))
+		return
+	}
+	transactions, err := db.ListLedgerTransactionsBetween(r.Context(), from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="ledger.csv"`)
+	if err := ledger.WriteCSV(w, transactions); err != nil {
+		logger.Error(err)
+	}
+}
+
+// RunInvoicing issues the previous month's invoices every interval, the already issued ones are kept.
+// It returns when ctx is done.
+func RunInvoicing(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		now := time.Now().UTC()
+		lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+		if _, err := issueInvoices(ctx, lastMonth.Format(invoice.PeriodLayout), now); err != nil {
+			logger.Errorf("couldn't issue the invoices of %s: %s", lastMonth.Format(invoice.PeriodLayout), err)
+		}
+	})
+}
+
+// issueInvoices issues one invoice per user, legal entity and currency charged within the period, a month over by now.
+// Returns the newly issued invoices, the ones issued before are skipped, and the charges no tax rule applies to,
+// which are left unbilled rather than holding the other invoices up.
+func issueInvoices(ctx context.Context, period string, now time.Time) (*models.InvoiceRun, error) {
+	from, to, err := invoice.Month(period)
+	if err != nil {
+		return nil, models.Errorf(models.ErrInvalidRequest, "invalid month %q", period)
+	} else if to.After(now) {
+		return nil, models.Errorf(models.ErrInvalidRequest, "month %s isn't over", period)
+	}
+	charges, err := db.ListCharges(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := db.ListTaxRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	built, unbilled := invoice.Build(period, charges, rules)
+	for _, c := range unbilled {
+		logger.Warnf("the %s %s of user %s in %q isn't invoiced for %s, there's no tax rule", c.Kind, c.Reference, c.UserID, c.City, period)
+	}
+
+	run := &models.InvoiceRun{Issued: make([]models.Invoice, 0, len(built)), Unbilled: unbilled}
+	for _, inv := range built {
+		inv.ID, inv.IssuedAt = uuid.New().String(), now
+		err := db.CreateInvoice(ctx, inv)
+		switch {
+		case errors.Is(err, models.ErrInvoiceExists):
+			continue
+		case err != nil:
+			return run, err
+		}
+		run.Issued = append(run.Issued, *inv)
+	}
+	return run, nil
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"scootin/db"
+	"scootin/invoice"
+	"scootin/ledger"
+	"scootin/logger"
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIssueInvoices(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1", City: "Berlin"}))
+	_, err := db.BookScooter(ctx, "sc1", "u1", 1, nil)
+	assert.NoError(t, err)
+	trip, err := db.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	now := time.Now().UTC()
+	period := now.Format(invoice.PeriodLayout)
+	_, monthEnd, _ := invoice.Month(period)
+
+	// a refund of the trip is credited back
+	dispute := &models.Dispute{ID: "d1", TripID: trip.ID, UserID: "u1", Status: models.DisputeOpen, CreatedAt: now, UpdatedAt: now}
+	assert.NoError(t, db.CreateDispute(ctx, dispute))
+	assert.NoError(t, db.UpdateDispute(ctx, dispute, models.DisputeEvent{Status: models.DisputeUnderReview, At: now}, nil))
+	refund := models.Money{Amount: 1, Currency: trip.Fare.Currency}
+	dispute.RefundAmount, dispute.Currency = refund.Amount, refund.Currency
+	assert.NoError(t, db.UpdateDispute(ctx, dispute, models.DisputeEvent{Status: models.DisputeRefunded, At: now},
+		ledger.Refund("u1", dispute.ID, refund, models.WalletAccount("u1"))))
+
+	// a month is invoiced once it's over, the charges in the cities without a tax rule are left out
+	_, err = issueInvoices(ctx, period, now)
+	assert.ErrorIs(t, err, models.ErrInvalidRequest)
+	run, err := issueInvoices(ctx, period, monthEnd)
+	assert.NoError(t, err)
+	assert.Empty(t, run.Issued)
+	assert.Len(t, run.Unbilled, 2)
+	assert.NoError(t, db.SetTaxRule(ctx, &models.TaxRule{LegalEntity: "DE01", EntityName: "Scootin GmbH", VATRate: 1900}))
+	run, err = issueInvoices(ctx, period, monthEnd)
+	assert.NoError(t, err)
+	assert.Empty(t, run.Unbilled)
+	if assert.Len(t, run.Issued, 1) {
+		issued := run.Issued[0]
+		assert.Equal(t, "DE01-000001", issued.Number)
+		assert.Equal(t, trip.Fare.Total-1, issued.Total)
+		assert.Equal(t, issued.Total, issued.Net+issued.VAT)
+		if assert.Len(t, issued.Lines, 2) {
+			assert.Equal(t, models.ChargeRefund, issued.Lines[1].Kind)
+			assert.Equal(t, int64(-1), issued.Lines[1].Gross)
+		}
+	}
+
+	// issuing the month again keeps its invoices
+	run, err = issueInvoices(ctx, period, monthEnd)
+	assert.NoError(t, err)
+	assert.Empty(t, run.Issued)
+	invoices, err := db.ListUserInvoices(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, invoices, 1)
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"scootin/db"
+	"scootin/logger"
+	"time"
+)
+
+// MaintainLocationHistory keeps the scooters' location history within its retention until ctx is done
+func MaintainLocationHistory(ctx context.Context, interval, retention time.Duration) {
+	every(ctx, interval, func() {
+		if err := db.MaintainLocationHistory(ctx, time.Now().UTC(), retention); err != nil {
+			logger.Errorf("couldn't maintain the location history: %s", err)
+		}
+	})
+}
+
+// ExpireReservations returns the elapsed reservations' scooters to the available ones until ctx is done
+func ExpireReservations(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		n, err := db.ExpireReservations(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorf("couldn't expire the reservations: %s", err)
+		} else if n > 0 {
+			logger.Infof("expired %d reservations", n)
+		}
+	})
+}
+
+// ExpireCommands fails the commands their scooters didn't acknowledge in time until ctx is done
+func ExpireCommands(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		n, err := db.ExpireCommands(ctx, time.Now().UTC())
+		if err != nil {
+			logger.Errorf("couldn't expire the commands: %s", err)
+		} else if n > 0 {
+			logger.Infof("expired %d commands", n)
+		}
+	})
+}
+
+// FlushPositions writes the buffered positions every interval and whenever a flush is due, until ctx is done
+func FlushPositions(ctx context.Context, interval time.Duration) {
+	buffer := positions
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-buffer.Due():
+		}
+		if err := buffer.Flush(ctx); err != nil {
+			logger.Errorf("couldn't flush the scooters' positions: %s", err)
+		}
+	}
+}
+
+// every runs the job right away then at each interval until ctx is done
+func every(ctx context.Context, interval time.Duration, job func()) {
+	job()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			job()
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/ledger"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/payment"
+	"scootin/pricing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreatePassPlan stores a new pass plan, returns it
+func CreatePassPlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var plan *models.PassPlan
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &plan); err != nil || !pricing.ValidPassPlan(plan) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid pass plan %s", body))
+		return
+	}
+	plan.ID, plan.CreatedAt = uuid.New().String(), time.Now().UTC()
+
+	if err := db.CreatePassPlan(r.Context(), plan); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, plan)
+}
+
+// ListPassPlans lists the pass plans on sale, the oldest first
+func ListPassPlans(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	plans, err := db.ListPassPlans(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, plans)
+}
+
+// BuyPass sells a pass of the plan to the requesting user, it's paid through the payment provider
+// and valid from now on. Returns the pass.
+func BuyPass(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var purchase *models.PassPurchase
+	userID := ps.ByName("id")
+	if r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "only user %s buys its passes", userID))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &purchase); err != nil || purchase == nil || len(purchase.PlanID) == 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid pass purchase %s", body))
+		return
+	}
+	plan, err := db.GetPassPlan(r.Context(), purchase.PlanID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	pass := &models.Pass{ID: uuid.New().String(), PlanID: plan.ID, UserID: userID, StartsAt: now,
+		EndsAt: pricing.PassEnd(plan.Period, now), AutoRenew: purchase.AutoRenew, CreatedAt: now}
+	if err := sellPass(pass, plan); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, pass)
+}
+
+// ListUserPasses lists the user's valid passes with what's left of their allowances today, to the user or the operators
+func ListUserPasses(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if !isAdmin(r) && r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "user %s's passes", userID))
+		return
+	}
+	allowances, err := db.ListPassAllowances(r.Context(), userID, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, allowances)
+}
+
+// RenewPasses renews the auto-renewed passes every interval, the passes ending before the next run are renewed
+// so they don't lapse. It returns when ctx is done.
+func RenewPasses(ctx context.Context, interval time.Duration) {
+	every(ctx, interval, func() {
+		if err := renewPasses(ctx, time.Now().UTC().Add(interval)); err != nil {
+			logger.Errorf("couldn't renew the passes: %s", err)
+		}
+	})
+}
+
+// renewPasses buys the auto-renewed passes ending before the time again, a renewal starts when its pass ends.
+// A declined renewal stops renewing the pass, the other failures are retried on the next run.
+func renewPasses(ctx context.Context, before time.Time) error {
+	passes, err := db.ListRenewablePasses(ctx, before)
+	if err != nil {
+		return err
+	}
+	for i := range passes {
+		pass := &passes[i]
+		plan, err := db.GetPassPlan(ctx, pass.PlanID)
+		if err != nil {
+			return err
+		}
+		// the renewal's id is derived from its pass so a retried renewal is paid once
+		renewal := &models.Pass{ID: uuid.NewSHA1(uuid.NameSpaceOID, []byte(pass.ID)).String(), PlanID: plan.ID, UserID: pass.UserID,
+			StartsAt: pass.EndsAt, EndsAt: pricing.PassEnd(plan.Period, pass.EndsAt), AutoRenew: true, RenewalOf: pass.ID,
+			CreatedAt: time.Now().UTC()}
+		err = sellPass(renewal, plan)
+		switch {
+		case errors.Is(err, models.ErrPaymentDeclined):
+			logger.Infof("the renewal of pass %s was declined, it's not renewed anymore", pass.ID)
+			if err := db.StopPassRenewal(ctx, pass.ID); err != nil {
+				return err
+			}
+		case err != nil:
+			logger.Errorf("couldn't renew pass %s: %s", pass.ID, err)
+		}
+	}
+	return nil
+}
+
+// sellPass captures the plan's price of the pass through the payment provider and stores the pass,
+// the pass's id is the payment's idempotency key.
+func sellPass(pass *models.Pass, plan *models.PassPlan) error {
+	var tx *models.LedgerTransaction
+	if plan.Price > 0 {
+		if paymentProvider == nil {
+			return errNoPaymentProvider
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+		defer cancel()
+		ref, err := paymentProvider.Capture(ctx, payment.Request{ID: pass.ID, PayerID: pass.UserID, MerchantCode: merchantCode,
+			Amount: plan.Price, Currency: plan.Currency})
+		if err != nil {
+			return paymentError(err, pass.ID)
+		}
+		pass.PaymentReference = ref
+		tx = ledger.Pass(pass.UserID, pass.ID, models.Money{Amount: plan.Price, Currency: plan.Currency})
+	}
+	if err := db.CreatePass(context.Background(), pass, tx); err != nil {
+		logger.Errorf("couldn't store the paid pass %s of user %s: %s", pass.ID, pass.UserID, err)
+		return err
+	}
+	return nil
+}
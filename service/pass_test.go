@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"scootin/db"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/payment"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenewPasses(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	payments := payment.NewFake(payment.FakeApprove)
+	SetPaymentProvider(payments, 100*time.Millisecond)
+	defer SetPaymentProvider(nil, 0)
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u2"}))
+	plan := &models.PassPlan{ID: "month", Name: "Month", Period: models.PassMonth, Price: 2000, Currency: "EUR", FreeUnlocks: 2}
+	assert.NoError(t, db.CreatePassPlan(ctx, plan))
+	now := time.Now().UTC()
+	ending := &models.Pass{ID: "p1", PlanID: plan.ID, UserID: "u1", StartsAt: now.AddDate(0, -1, 0), EndsAt: now, AutoRenew: true}
+	assert.NoError(t, db.CreatePass(ctx, ending, nil))
+
+	// the renewal starts when the pass ends and is paid once however often it runs
+	assert.NoError(t, renewPasses(ctx, now.Add(time.Minute)))
+	assert.NoError(t, renewPasses(ctx, now.Add(time.Minute)))
+	allowances, err := db.ListPassAllowances(ctx, "u1", now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Len(t, allowances, 1)
+	renewal := allowances[0].Pass
+	assert.Equal(t, ending.ID, renewal.RenewalOf)
+	assert.Equal(t, now.AddDate(0, 1, 0), renewal.EndsAt)
+	assert.True(t, payments.Captured(renewal.ID))
+	wallet, err := db.ListLedgerTransactions(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Len(t, wallet, 1)
+
+	// a declined renewal stops renewing the pass
+	assert.NoError(t, db.CreatePass(ctx, &models.Pass{ID: "p2", PlanID: plan.ID, UserID: "u2", StartsAt: now.AddDate(0, -1, 0),
+		EndsAt: now, AutoRenew: true}, nil))
+	payments.SetMode(payment.FakeDecline)
+	assert.NoError(t, renewPasses(ctx, now.Add(time.Minute)))
+	renewable, err := db.ListRenewablePasses(ctx, now.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, renewable)
+	allowances, err = db.ListPassAllowances(ctx, "u2", now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Empty(t, allowances)
+}
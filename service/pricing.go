@@ -0,0 +1,106 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"scootin/pricing"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// FareEstimate quotes a ride of "minutes" and "distance" meters in the "city" with the "vehicle_type",
+// priced by the plan in effect now. The ride is surged like one booked now at "lat" and "lon" when they're queried,
+// by the time of the day's rate otherwise.
+func FareEstimate(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	minutes, err := strconv.ParseFloat(query.Get("minutes"), 64)
+	if err != nil || minutes < 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid minutes %q", query.Get("minutes")))
+		return
+	}
+	distance := 0.0
+	if v := query.Get("distance"); len(v) > 0 {
+		if distance, err = strconv.ParseFloat(v, 64); err != nil || distance < 0 {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid distance %q", v))
+			return
+		}
+	}
+	now := time.Now().UTC()
+	surge := surgePolicy.Multiplier(0, 0, now)
+	if query.Has("lat") || query.Has("lon") {
+		l, qerr := locationQuery(query)
+		if qerr != nil {
+			writeError(w, r, qerr)
+			return
+		}
+		if surge, err = locationSurge(r.Context(), l, now); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	plan, err := db.PricePlanAt(r.Context(), query.Get("city"), vehicleTypeOrDefault(query.Get("vehicle_type")), now)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	fare := pricing.Calculate(plan, time.Duration(minutes*float64(time.Minute)), distance)
+	pricing.ApplySurge(fare, surge)
+	writeJSON(w, fare)
+}
+
+// CreatePricePlan stores the next version of a city's and vehicle type's prices, returns the stored plan
+func CreatePricePlan(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var plan *models.PricePlan
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &plan); err != nil || plan == nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid price plan %s", body))
+		return
+	}
+	plan.VehicleType = vehicleTypeOrDefault(plan.VehicleType)
+	if !pricing.ValidPlan(plan) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid price plan %s", body))
+		return
+	}
+
+	// the plan is effective right away unless it's scheduled
+	plan.ID = uuid.New().String()
+	if plan.EffectiveFrom.IsZero() {
+		plan.EffectiveFrom = time.Now().UTC()
+	}
+	if err := db.CreatePricePlan(r.Context(), plan); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, plan)
+}
+
+// ListPricePlans lists all the price plans' versions
+func ListPricePlans(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	plans, err := db.ListPricePlans(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, plans)
+}
+
+// vehicleTypeOrDefault returns the vehicle type, the default one when it's empty
+func vehicleTypeOrDefault(t string) string {
+	if len(t) == 0 {
+		return models.DefaultVehicleType
+	}
+	return t
+}
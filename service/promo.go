@@ -0,0 +1,101 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"scootin/pricing"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreatePromoCode stores a new promo code, returns it
+func CreatePromoCode(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var promo *models.PromoCode
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &promo); err != nil || promo == nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid promo code %s", body))
+		return
+	}
+	promo.Code = promoCode(promo.Code)
+	if !pricing.ValidPromo(promo) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid promo code %s", body))
+		return
+	}
+	promo.Redemptions, promo.CreatedAt = 0, time.Now().UTC()
+
+	if err := db.CreatePromoCode(r.Context(), promo); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, promo)
+}
+
+// ListPromoCodes lists the promo codes, the oldest first
+func ListPromoCodes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	promos, err := db.ListPromoCodes(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, promos)
+}
+
+// RedeemPromoCode attaches the promo code to the requesting user, its discounts apply to the user's next fares
+// and a voucher is credited to the user's wallet. Returns the redemption.
+func RedeemPromoCode(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var redeem *models.PromoRedeem
+	userID := ps.ByName("id")
+	if r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "only user %s redeems its promo codes", userID))
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &redeem); err != nil || redeem == nil || len(redeem.Code) == 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid promo code %s", body))
+		return
+	}
+
+	redemption, err := db.RedeemPromoCode(r.Context(), promoCode(redeem.Code), userID, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, redemption)
+}
+
+// ListPromoRedemptions lists the promo codes the user redeemed to the user or the operators
+func ListPromoRedemptions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if !isAdmin(r) && r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "promo codes of user %s", userID))
+		return
+	}
+	redemptions, err := db.ListPromoRedemptions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, redemptions)
+}
+
+// promoCode returns the code the way it's stored, the riders type it in any case
+func promoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net/url"
+	"scootin/geo"
+	"scootin/models"
+	"strconv"
+)
+
+const (
+	defaultNearbyRadius = 1000  // meters
+	maxNearbyRadius     = 50000 // meters
+	defaultNearbyLimit  = 20
+	maxNearbyLimit      = 100
+)
+
+// nearbyQuery parses the "lat", "lon", "radius" and "limit" query parameters of a nearby search
+func nearbyQuery(query url.Values) (models.Location, float64, int, error) {
+	var (
+		center models.Location
+		radius = float64(defaultNearbyRadius)
+		limit  = defaultNearbyLimit
+		err    error
+	)
+	if center, err = locationQuery(query); err != nil {
+		return center, 0, 0, err
+	}
+	if v := query.Get("radius"); len(v) > 0 {
+		if radius, err = strconv.ParseFloat(v, 64); err != nil || radius <= 0 || radius > maxNearbyRadius {
+			return center, 0, 0, models.Errorf(models.ErrInvalidRequest, "radius must be a number of meters up to %d", maxNearbyRadius)
+		}
+	}
+	if v := query.Get("limit"); len(v) > 0 {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > maxNearbyLimit {
+			return center, 0, 0, models.Errorf(models.ErrInvalidRequest, "limit must be a number up to %d", maxNearbyLimit)
+		}
+	}
+	return center, radius, limit, nil
+}
+
+// locationQuery parses the "lat" and "lon" query parameters
+func locationQuery(query url.Values) (models.Location, error) {
+	var (
+		l   models.Location
+		err error
+	)
+	if l.Latitude, err = strconv.ParseFloat(query.Get("lat"), 64); err != nil {
+		return l, models.Errorf(models.ErrInvalidRequest, "invalid latitude %q", query.Get("lat"))
+	}
+	if l.Longitude, err = strconv.ParseFloat(query.Get("lon"), 64); err != nil {
+		return l, models.Errorf(models.ErrInvalidRequest, "invalid longitude %q", query.Get("lon"))
+	}
+	if !geo.Valid(l) {
+		return l, models.Errorf(models.ErrInvalidRequest, "location %v,%v is out of range", l.Latitude, l.Longitude)
+	}
+	return l, nil
+}
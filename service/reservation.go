@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// reservationHold is how long a reserved scooter is held for its rider
+var reservationHold = 10 * time.Minute
+
+// SetReservationHold sets how long a reserved scooter is held
+func SetReservationHold(hold time.Duration) {
+	reservationHold = hold
+}
+
+// ReserveScooter holds a scooter for the requesting user, returns the active reservation
+func ReserveScooter(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	scooterID := ps.ByName("id")
+	if len(scooterID) == 0 {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "empty scooter id"))
+		return
+	}
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// the zone's surge is locked in for the trip the reservation is converted to
+	surge, err := scooterSurge(r.Context(), scooterID, time.Now().UTC())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reservation, err := db.ReserveScooter(r.Context(), scooterID, userID, reservationHold, surge)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, reservation)
+}
+
+// GetReservation returns the reservation by its id
+func GetReservation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	reservation, err := db.GetReservation(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, reservation)
+}
+
+// ConvertReservation starts the trip on the requesting user's reserved scooter, returns the started trip
+func ConvertReservation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reservation, err := db.GetReservation(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	// the rider must still be at the scooter to ride it off
+	near, err := checkProximity(r, reservation.ScooterID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	trip, err := startTripWithHold(r.Context(), userID, reservation.ScooterID, func(ctx context.Context) (*models.Trip, error) {
+		return db.ConvertReservation(ctx, reservation.ID, userID, near)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, trip)
+}
+
+// CancelReservation cancels the requesting user's reservation, returns the cancelled reservation
+func CancelReservation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, err := requestUserID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	reservation, err := db.CancelReservation(r.Context(), ps.ByName("id"), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, reservation)
+}
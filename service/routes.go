@@ -38,9 +38,269 @@ var routes = Routes{
 		"/v0.1/scooter/book/:id",
 		BookScooter,
 	},
+	Route{
+		"PUT",
+		"/v0.1/scooter/reserve/:id",
+		ReserveScooter,
+	},
+	Route{
+		"GET",
+		"/v0.1/reservations/:id",
+		GetReservation,
+	},
+	Route{
+		"PUT",
+		"/v0.1/reservations/:id/convert",
+		ConvertReservation,
+	},
+	Route{
+		"PUT",
+		"/v0.1/reservations/:id/cancel",
+		CancelReservation,
+	},
 	Route{
 		"PUT",
 		"/v0.1/scooter/release/",
 		ReleaseScooter,
 	},
+	Route{
+		"GET",
+		"/v0.1/trips/:id",
+		GetTrip,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/trips",
+		ListUserTrips,
+	},
+	Route{
+		"GET",
+		"/v0.1/scooters/:id/track",
+		ScooterTrack,
+	},
+	Route{
+		"PUT",
+		"/v0.1/admin/scooters/:id/status",
+		adminOnly(SetScooterStatus),
+	},
+	Route{
+		"GET",
+		"/v0.1/fare-estimate",
+		FareEstimate,
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/price-plans",
+		adminOnly(CreatePricePlan),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/price-plans",
+		adminOnly(ListPricePlans),
+	},
+	Route{
+		"POST",
+		"/v0.1/checkouts",
+		CreateCheckout,
+	},
+	Route{
+		"GET",
+		"/v0.1/checkouts/:id",
+		GetCheckout,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/wallet",
+		GetWallet,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/wallet/transactions",
+		ListWalletTransactions,
+	},
+	Route{
+		"POST",
+		"/v0.1/users/:id/wallet/top-ups",
+		TopUpWallet,
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/users/:id/promo-credits",
+		adminOnly(CreditPromo),
+	},
+	Route{
+		"POST",
+		"/v0.1/disputes",
+		CreateDispute,
+	},
+	Route{
+		"GET",
+		"/v0.1/disputes/:id",
+		GetDispute,
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/disputes",
+		adminOnly(ListDisputes),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/disputes/:id/review",
+		adminOnly(GetDisputeReview),
+	},
+	Route{
+		"PUT",
+		"/v0.1/admin/disputes/:id/review",
+		adminOnly(ReviewDispute),
+	},
+	Route{
+		"PUT",
+		"/v0.1/admin/disputes/:id/decision",
+		adminOnly(DecideDispute),
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/promo-codes",
+		adminOnly(CreatePromoCode),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/promo-codes",
+		adminOnly(ListPromoCodes),
+	},
+	Route{
+		"POST",
+		"/v0.1/users/:id/promo-codes",
+		RedeemPromoCode,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/promo-codes",
+		ListPromoRedemptions,
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/pass-plans",
+		adminOnly(CreatePassPlan),
+	},
+	Route{
+		"GET",
+		"/v0.1/pass-plans",
+		ListPassPlans,
+	},
+	Route{
+		"POST",
+		"/v0.1/users/:id/passes",
+		BuyPass,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/passes",
+		ListUserPasses,
+	},
+	Route{
+		"PUT",
+		"/v0.1/admin/tax-rules",
+		adminOnly(SetTaxRule),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/tax-rules",
+		adminOnly(ListTaxRules),
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/invoices",
+		adminOnly(IssueInvoices),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/ledger.csv",
+		adminOnly(ExportLedger),
+	},
+	Route{
+		"GET",
+		"/v0.1/invoices/:id",
+		GetInvoice,
+	},
+	Route{
+		"GET",
+		"/v0.1/users/:id/invoices",
+		ListUserInvoices,
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/zones",
+		adminOnly(CreateZone),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/zones",
+		adminOnly(ListZones),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/zones/:id",
+		adminOnly(GetZone),
+	},
+	Route{
+		"PUT",
+		"/v0.1/admin/zones/:id",
+		adminOnly(UpdateZone),
+	},
+	Route{
+		"DELETE",
+		"/v0.1/admin/zones/:id",
+		adminOnly(DeleteZone),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/zone-alerts",
+		adminOnly(ListZoneAlerts),
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/stations",
+		adminOnly(CreateStation),
+	},
+	Route{
+		"GET",
+		"/v0.1/stations",
+		ListStations,
+	},
+	Route{
+		"POST",
+		"/v0.1/scooters/:id/telemetry",
+		scooterOnly(ReportTelemetry),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/telemetry/buffer",
+		adminOnly(GetPositionBufferStats),
+	},
+	Route{
+		"POST",
+		"/v0.1/admin/scooters/:id/commands",
+		adminOnly(IssueCommand),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/scooters/:id/commands",
+		adminOnly(ListCommands),
+	},
+	Route{
+		"GET",
+		"/v0.1/admin/commands/:id",
+		adminOnly(GetCommand),
+	},
+	Route{
+		"GET",
+		"/v0.1/scooters/:id/commands",
+		scooterOnly(PollCommands),
+	},
+	Route{
+		"POST",
+		"/v0.1/scooters/:id/commands/:command/ack",
+		scooterOnly(AckCommand),
+	},
 }
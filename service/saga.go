@@ -0,0 +1,281 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"scootin/db"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/payment"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// holdAmount is placed on the rider's funds before a trip starts, in the holdCurrency minor units
+	holdAmount   int64 = 1000
+	holdCurrency       = "EUR"
+)
+
+// SetPaymentHold sets the hold placed on the riders' funds before their trips start
+func SetPaymentHold(amount int64, currency string) {
+	holdAmount, holdCurrency = amount, currency
+}
+
+// startTripWithHold starts the user's trip on the scooter with start once a hold is placed on the user's funds,
+// the hold is released when the trip doesn't start. The trips are refused while there's no payment provider to
+// place the hold, or while the user's wallet is below the minimum balance.
+func startTripWithHold(ctx context.Context, userID, scooterID string, start func(ctx context.Context) (*models.Trip, error)) (*models.Trip, error) {
+	if paymentProvider == nil {
+		return nil, errNoPaymentProvider
+	}
+	if err := checkBalance(ctx, userID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	saga := &models.PaymentSaga{ID: uuid.New().String(), UserID: userID, ScooterID: scooterID, HoldAmount: holdAmount,
+		Currency: holdCurrency, Step: models.SagaAuthorizing, CreatedAt: now, UpdatedAt: now}
+	if err := db.CreatePaymentSaga(ctx, saga); err != nil {
+		return nil, err
+	}
+
+	authorizationID, err := authorize(saga)
+	if errors.Is(err, models.ErrPaymentDeclined) {
+		saga.FailureCode = errorCode(err)
+		if uerr := advance(saga, models.SagaFailed); uerr != nil {
+			logger.Errorf("couldn't store the declined payment saga %s: %s", saga.ID, uerr)
+		}
+		return nil, err
+	} else if err != nil {
+		// the recovery releases the hold the provider may have placed
+		return nil, err
+	}
+	saga.AuthorizationID = authorizationID
+	if err := advance(saga, models.SagaAuthorized); err != nil {
+		return nil, err
+	}
+
+	trip, err := start(ctx)
+	if err != nil {
+		if cerr := cancelHold(saga); cerr != nil {
+			logger.Errorf("couldn't release the hold of the payment saga %s: %s", saga.ID, cerr)
+		}
+		return nil, err
+	}
+	saga.TripID = trip.ID
+	if err := advance(saga, models.SagaBooked); err != nil {
+		// the recovery finds the started trip
+		logger.Errorf("couldn't store the payment saga %s of trip %s: %s", saga.ID, trip.ID, err)
+	}
+	return trip, nil
+}
+
+// chargeTripFare charges the ended trip's fare from the hold placed when it started,
+// the trips started without a hold are paid with a checkout.
+func chargeTripFare(ctx context.Context, trip *models.Trip) error {
+	saga, err := db.PaymentSagaByTrip(ctx, trip.ID)
+	if errors.Is(err, models.ErrPaymentSagaNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if saga.Step != models.SagaBooked {
+		return nil
+	}
+	return captureHold(saga, trip)
+}
+
+// captureHold creates the checkout of the part of the trip's fare the rider's wallet doesn't pay and charges it
+// from the saga's hold
+func captureHold(saga *models.PaymentSaga, trip *models.Trip) error {
+	// a free ride releases the hold, which only pays a fare of its currency
+	if trip.Fare.Total == 0 {
+		return cancelHold(saga)
+	} else if trip.Fare.Currency != saga.Currency {
+		saga.FailureCode = models.ErrPaymentFailed.Code
+		return cancelHold(saga)
+	}
+	due, err := fareDue(context.Background(), trip)
+	if err != nil {
+		return err
+	} else if due == 0 {
+		// the wallet paid the whole fare
+		return cancelHold(saga)
+	}
+
+	checkout := &models.Checkout{
+		ID:           uuid.New().String(),
+		TripID:       trip.ID,
+		UserID:       trip.UserID,
+		MerchantCode: merchantCode,
+		Amount:       due,
+		Currency:     trip.Fare.Currency,
+		Status:       models.CheckoutPending,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := db.CreateCheckout(context.Background(), checkout); err != nil {
+		return err
+	}
+	saga.CheckoutID = checkout.ID
+	if err := advance(saga, models.SagaCapturing); err != nil {
+		return err
+	}
+	return settleHold(saga, checkout)
+}
+
+// settleHold charges the saga's checkout from its hold, a hold which can't pay the fare is released
+// and the fare is left to be paid with another checkout.
+func settleHold(saga *models.PaymentSaga, checkout *models.Checkout) error {
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+
+	ref, err := paymentProvider.CaptureAuthorization(ctx, saga.AuthorizationID, checkout.Amount)
+	err = paymentError(err, checkout.ID)
+	if errors.Is(err, models.ErrPaymentTimeout) {
+		// the capture may have happened, the recovery asks again
+		return err
+	}
+	if cerr := completeCheckout(checkout, ref, err); cerr != nil && err == nil {
+		return cerr
+	}
+	if err == nil {
+		return advance(saga, models.SagaCaptured)
+	}
+
+	saga.FailureCode = errorCode(err)
+	if cerr := cancelHold(saga); cerr != nil {
+		logger.Errorf("couldn't release the hold of the payment saga %s: %s", saga.ID, cerr)
+	}
+	return err
+}
+
+// cancelHold releases the saga's hold, the saga is left cancelling for the recovery when the provider fails
+func cancelHold(saga *models.PaymentSaga) error {
+	if err := advance(saga, models.SagaCancelling); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+	if err := paymentProvider.CancelAuthorization(ctx, saga.AuthorizationID); err != nil {
+		return paymentError(err, saga.ID)
+	}
+	// a saga whose capture failed is failed rather than cancelled
+	if len(saga.FailureCode) > 0 {
+		return advance(saga, models.SagaFailed)
+	}
+	return advance(saga, models.SagaCancelled)
+}
+
+// authorize places the saga's hold, the saga's id is the idempotency key so asking again returns the same hold
+func authorize(saga *models.PaymentSaga) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+	authorizationID, err := paymentProvider.Authorize(ctx, payment.Request{ID: saga.ID, PayerID: saga.UserID,
+		MerchantCode: merchantCode, Amount: saga.HoldAmount, Currency: saga.Currency})
+	return authorizationID, paymentError(err, saga.ID)
+}
+
+// advance stores the saga at its next step, it's not bound to any request so the progress is kept when the caller has gone
+func advance(saga *models.PaymentSaga, step models.PaymentSagaStep) error {
+	saga.Step, saga.UpdatedAt = step, time.Now().UTC()
+	return db.UpdatePaymentSaga(context.Background(), saga)
+}
+
+// RecoverPaymentSagas resumes the payment sagas left unfinished for longer than after, e.g. by a restart, until ctx is done
+func RecoverPaymentSagas(ctx context.Context, interval, after time.Duration) {
+	every(ctx, interval, func() {
+		if err := recoverPaymentSagas(ctx, time.Now().UTC().Add(-after)); err != nil {
+			logger.Errorf("couldn't recover the payment sagas: %s", err)
+		}
+	})
+}
+
+// recoverPaymentSagas resumes the unfinished payment sagas not updated since before
+func recoverPaymentSagas(ctx context.Context, before time.Time) error {
+	if paymentProvider == nil {
+		return nil
+	}
+	sagas, err := db.ListStalePaymentSagas(ctx, before)
+	if err != nil {
+		return err
+	}
+	for i := range sagas {
+		if err := resumeSaga(ctx, &sagas[i]); err != nil {
+			logger.Errorf("couldn't resume the %s payment saga %s: %s", sagas[i].Step, sagas[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// resumeSaga finishes what the saga was doing when it was interrupted
+func resumeSaga(ctx context.Context, saga *models.PaymentSaga) error {
+	switch saga.Step {
+	case models.SagaAuthorizing:
+		// the hold may have been placed, it's released as the trip was never started
+		authorizationID, err := authorize(saga)
+		if errors.Is(err, models.ErrPaymentDeclined) {
+			saga.FailureCode = errorCode(err)
+			return advance(saga, models.SagaFailed)
+		} else if err != nil {
+			return err
+		}
+		saga.AuthorizationID = authorizationID
+		return cancelHold(saga)
+	case models.SagaAuthorized:
+		// the trip may have started without the saga being stored
+		trip, err := startedTrip(ctx, saga)
+		if err != nil {
+			return err
+		} else if trip == nil {
+			return cancelHold(saga)
+		}
+		saga.TripID = trip.ID
+		if err := advance(saga, models.SagaBooked); err != nil {
+			return err
+		}
+		return resumeBooked(ctx, saga)
+	case models.SagaBooked:
+		return resumeBooked(ctx, saga)
+	case models.SagaCapturing:
+		checkout, err := db.GetCheckout(ctx, saga.CheckoutID)
+		if err != nil {
+			return err
+		}
+		return settleHold(saga, checkout)
+	case models.SagaCancelling:
+		return cancelHold(saga)
+	}
+	return nil
+}
+
+// resumeBooked charges the fare of the saga's trip once it has ended
+func resumeBooked(ctx context.Context, saga *models.PaymentSaga) error {
+	trip, err := db.GetTrip(ctx, saga.TripID)
+	if err != nil {
+		return err
+	} else if trip.EndedAt == nil || trip.Fare == nil {
+		return nil
+	}
+	return captureHold(saga, trip)
+}
+
+// startedTrip returns the trip the saga's user started on its scooter since the saga was created, nil when there's none
+func startedTrip(ctx context.Context, saga *models.PaymentSaga) (*models.Trip, error) {
+	trips, err := db.ListUserTrips(ctx, saga.UserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range trips {
+		if trips[i].ScooterID != saga.ScooterID || trips[i].StartedAt.Before(saga.CreatedAt) {
+			continue
+		}
+		// the trip isn't another saga's
+		if _, err := db.PaymentSagaByTrip(ctx, trips[i].ID); errors.Is(err, models.ErrPaymentSagaNotFound) {
+			return &trips[i], nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"scootin/db"
+	"scootin/ledger"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/payment"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPaymentSagas(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	payments := payment.NewFake(payment.FakeApprove)
+	SetPaymentProvider(payments, 100*time.Millisecond)
+	defer SetPaymentProvider(nil, 0)
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc2"}))
+
+	// the sagas were interrupted a while ago
+	then := time.Now().UTC().Add(-time.Hour)
+	saga := func(id, scooterID string, step models.PaymentSagaStep) *models.PaymentSaga {
+		s := &models.PaymentSaga{ID: id, UserID: "u1", ScooterID: scooterID, HoldAmount: 1000, Currency: "EUR",
+			Step: step, CreatedAt: then, UpdatedAt: then}
+		if step != models.SagaAuthorizing {
+			var err error
+			s.AuthorizationID, err = payments.Authorize(ctx, payment.Request{ID: id, PayerID: "u1", Amount: 1000, Currency: "EUR"})
+			assert.NoError(t, err)
+		}
+		return s
+	}
+
+	// a hold whose answer was lost and a hold whose trip never started are released
+	unanswered, unbooked := saga("s1", "sc1", models.SagaAuthorizing), saga("s2", "sc1", models.SagaAuthorized)
+	assert.NoError(t, db.CreatePaymentSaga(ctx, unanswered))
+	assert.NoError(t, db.CreatePaymentSaga(ctx, unbooked))
+
+	// the fare of an ended trip is charged from its hold
+	trip, err := db.BookScooter(ctx, "sc2", "u1", 1, nil)
+	assert.NoError(t, err)
+	booked := saga("s3", "sc2", models.SagaBooked)
+	booked.TripID = trip.ID
+	assert.NoError(t, db.CreatePaymentSaga(ctx, booked))
+	trip, err = db.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+
+	assert.NoError(t, recoverPaymentSagas(ctx, time.Now().UTC()))
+	assert.Equal(t, 0, payments.OpenHolds())
+	stale, err := db.ListStalePaymentSagas(ctx, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Empty(t, stale)
+
+	captured, err := db.PaymentSagaByTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SagaCaptured, captured.Step)
+	checkout, err := db.GetCheckout(ctx, captured.CheckoutID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CheckoutCaptured, checkout.Status)
+	assert.Equal(t, trip.Fare.Total, checkout.Amount)
+}
+
+func TestStartTripWithoutProvider(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	SetPaymentProvider(nil, 0)
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	_, err := startTripWithHold(ctx, "u1", "sc1", func(ctx context.Context) (*models.Trip, error) {
+		return db.BookScooter(ctx, "sc1", "u1", 1, nil)
+	})
+	assert.ErrorIs(t, err, models.ErrPaymentFailed)
+	sc, err := db.GetScooter(ctx, "sc1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusAvailable, sc.Status)
+}
+
+func TestCaptureHoldAboveHold(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	payments := payment.NewFake(payment.FakeApprove)
+	SetPaymentProvider(payments, 100*time.Millisecond)
+	defer SetPaymentProvider(nil, 0)
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+
+	// the hold is smaller than the fare
+	SetPaymentHold(1, "EUR")
+	defer SetPaymentHold(1000, "EUR")
+	trip, err := startTripWithHold(ctx, "u1", "sc1", func(ctx context.Context) (*models.Trip, error) {
+		return db.BookScooter(ctx, "sc1", "u1", 1, nil)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, payments.OpenHolds())
+	trip, err = db.ReleaseScooter(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Greater(t, trip.Fare.Total, int64(1))
+
+	// the hold is released and the fare is left to another checkout
+	assert.ErrorIs(t, chargeTripFare(ctx, trip), models.ErrPaymentFailed)
+	assert.Equal(t, 0, payments.OpenHolds())
+	saga, err := db.PaymentSagaByTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SagaFailed, saga.Step)
+	assert.Equal(t, models.ErrPaymentFailed.Code, saga.FailureCode)
+	checkout, err := db.GetCheckout(ctx, saga.CheckoutID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CheckoutFailed, checkout.Status)
+	assert.Empty(t, checkout.ProviderReference)
+}
+
+func TestCaptureHoldSpendsWallet(t *testing.T) {
+	logger.InitLogger(logger.NewLogger())
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	payments := payment.NewFake(payment.FakeApprove)
+	SetPaymentProvider(payments, 100*time.Millisecond)
+	defer SetPaymentProvider(nil, 0)
+
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.NoError(t, db.CreateScooter(ctx, &models.ScooterInfo{ID: "sc1"}))
+	ride := func() *models.Trip {
+		trip, err := startTripWithHold(ctx, "u1", "sc1", func(ctx context.Context) (*models.Trip, error) {
+			return db.BookScooter(ctx, "sc1", "u1", 1, nil)
+		})
+		assert.NoError(t, err)
+		trip, err = db.ReleaseScooter(ctx, "u1")
+		assert.NoError(t, err)
+		assert.NoError(t, chargeTripFare(ctx, trip))
+		return trip
+	}
+
+	// the topped up wallet pays what it holds of the fare, the hold the rest
+	assert.NoError(t, db.PostLedgerTransaction(ctx, ledger.TopUp("u1", "top-up-1", models.Money{Amount: 10, Currency: "EUR"})))
+	trip := ride()
+	saga, err := db.PaymentSagaByTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SagaCaptured, saga.Step)
+	checkout, err := db.GetCheckout(ctx, saga.CheckoutID)
+	assert.NoError(t, err)
+	assert.Equal(t, trip.Fare.Total-10, checkout.Amount)
+	wallet, err := db.GetWallet(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 0, Currency: "EUR"}}, wallet.Balances)
+
+	// a wallet holding the whole fare pays it and the hold is released
+	assert.NoError(t, db.PostLedgerTransaction(ctx, ledger.TopUp("u1", "top-up-2", models.Money{Amount: 10000, Currency: "EUR"})))
+	trip = ride()
+	saga, err = db.PaymentSagaByTrip(ctx, trip.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.SagaCancelled, saga.Step)
+	assert.Empty(t, saga.CheckoutID)
+	assert.Equal(t, 0, payments.OpenHolds())
+	wallet, err = db.GetWallet(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Money{{Amount: 10000 - trip.Fare.Total, Currency: "EUR"}}, wallet.Balances)
+}
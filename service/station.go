@@ -0,0 +1,58 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"scootin/pricing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateStation opens a virtual parking station, returns the stored station
+func CreateStation(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var station *models.Station
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &station); err != nil || !pricing.ValidStation(station) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid station %s", body))
+		return
+	}
+	station.ID, station.CreatedAt = uuid.New().String(), time.Now().UTC()
+
+	if err := db.CreateStation(r.Context(), station); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, station)
+}
+
+// ListStations lists the stations of the "city" query, every station by default, with their free slots.
+// With "rebalance=true" only the stations over capacity are listed.
+func ListStations(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	query := r.URL.Query()
+	stations, err := db.ListStations(r.Context(), query.Get("city"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if query.Get("rebalance") == "true" {
+		overCapacity := make([]models.StationOccupancy, 0)
+		for _, s := range stations {
+			if s.Rebalance {
+				overCapacity = append(overCapacity, s)
+			}
+		}
+		stations = overCapacity
+	}
+
+	writeJSON(w, stations)
+}
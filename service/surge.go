@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"scootin/db"
+	"scootin/geo"
+	"scootin/models"
+	"scootin/pricing"
+	"time"
+)
+
+// surgePolicy prices the zones by their demand and the time of the day, the zero policy never surges
+var surgePolicy pricing.SurgePolicy
+
+// SetSurgePolicy sets how the prices follow the zones' demand and the time of the day
+func SetSurgePolicy(policy pricing.SurgePolicy) {
+	surgePolicy = policy
+}
+
+// scooterSurge returns the price multiplier of the scooter's zone at the time, a scooter which isn't available
+// gets the time of the day's rate.
+func scooterSurge(ctx context.Context, scooterID string, at time.Time) (float64, error) {
+	sc, err := db.GetScooter(ctx, scooterID)
+	if errors.Is(err, models.ErrScooterNotFound) {
+		return surgePolicy.Multiplier(0, 0, at), nil
+	} else if err != nil {
+		return 0, err
+	}
+	if sc.Status != models.StatusAvailable {
+		return surgePolicy.Multiplier(0, 0, at), nil
+	}
+	return zoneSurge(ctx, geo.Zone(sc.Location), at)
+}
+
+// locationSurge returns the price multiplier of the location's zone at the time
+func locationSurge(ctx context.Context, l models.Location, at time.Time) (float64, error) {
+	return zoneSurge(ctx, geo.Zone(l), at)
+}
+
+// zoneSurge returns the price multiplier of the zone at the time, its demand is the active reservations
+// of its scooters and its supply the available scooters within it.
+func zoneSurge(ctx context.Context, zone string, at time.Time) (float64, error) {
+	available, err := db.ListAvailableScooterInZone(ctx, zone)
+	if err != nil {
+		return 0, err
+	}
+	demand, err := db.CountActiveReservations(ctx, zone)
+	if err != nil {
+		return 0, err
+	}
+	return surgePolicy.Multiplier(demand, len(available), at), nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/geo"
+	"scootin/models"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// maxTelemetrySamples is the most samples a telemetry batch holds
+	maxTelemetrySamples = 500
+	// maxTelemetrySpeed is the fastest speed a sample may report, in km/h
+	maxTelemetrySpeed = 100
+	// telemetryClockSkew is how far ahead of the service's clock a sample may be
+	telemetryClockSkew = 30 * time.Second
+)
+
+// telemetryToken is the bearer token of the scooters' fleet, the telemetry is refused while it's empty
+var telemetryToken string
+
+// SetTelemetryToken sets the fleet's bearer token
+func SetTelemetryToken(token string) {
+	telemetryToken = token
+}
+
+// scooterOnly lets only the requests carrying the fleet's bearer token through
+func scooterOnly(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if len(telemetryToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(telemetryToken)) != 1 {
+			writeError(w, r, models.Errorf(models.ErrForbidden, "%s %s is for scooters only", r.Method, r.URL.Path))
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
+// ReportTelemetry records a scooter's batch of samples, returns how many were accepted and how many
+// were already recorded, so a scooter may send a batch again when it doesn't know it went through.
+func ReportTelemetry(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var batch models.TelemetryBatch
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err))
+		return
+	}
+	if err = json.Unmarshal(body, &batch); err != nil {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid telemetry batch %s", body))
+		return
+	}
+	receipt, err := ingestTelemetry(r.Context(), ps.ByName("id"), batch.Samples)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, receipt)
+}
+
+// ingestTelemetry records the scooter's samples once they're checked, whether they came over HTTP or UDP
+func ingestTelemetry(ctx context.Context, scooterID string, samples []models.TelemetrySample) (*models.TelemetryReceipt, error) {
+	if err := validTelemetry(samples, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return db.RecordTelemetry(ctx, scooterID, samples)
+}
+
+// validTelemetry checks the samples are within range, not in the future and strictly ordered by their time
+func validTelemetry(samples []models.TelemetrySample, now time.Time) error {
+	if len(samples) == 0 || len(samples) > maxTelemetrySamples {
+		return models.Errorf(models.ErrInvalidRequest, "a telemetry batch holds 1 to %d samples", maxTelemetrySamples)
+	}
+	for i, s := range samples {
+		switch {
+		case s.Time.IsZero() || s.Time.After(now.Add(telemetryClockSkew)):
+			return models.Errorf(models.ErrInvalidRequest, "sample %d has an invalid time %s", i, s.Time)
+		case i > 0 && !s.Time.After(samples[i-1].Time):
+			return models.Errorf(models.ErrInvalidRequest, "sample %d isn't newer than the previous one", i)
+		case !geo.Valid(s.Location):
+			return models.Errorf(models.ErrInvalidRequest, "sample %d has an invalid location %v", i, s.Location)
+		case s.Speed < 0 || s.Speed > maxTelemetrySpeed:
+			return models.Errorf(models.ErrInvalidRequest, "sample %d has an invalid speed %v", i, s.Speed)
+		case s.Battery < 0 || s.Battery > 100:
+			return models.Errorf(models.ErrInvalidRequest, "sample %d has an invalid battery %d", i, s.Battery)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"scootin/db"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/telemetry"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// telemetryKey is the fleet's key signing the UDP telemetry frames
+var telemetryKey []byte
+
+// positions is the write-behind buffer of the UDP telemetry, the frames are recorded one by one without it
+var positions *db.PositionBuffer
+
+// SetPositionBuffer sets the write-behind buffer of the UDP telemetry
+func SetPositionBuffer(b *db.PositionBuffer) {
+	positions = b
+}
+
+// SetTelemetryKey sets the fleet's key of the UDP telemetry frames
+func SetTelemetryKey(key string) {
+	telemetryKey = []byte(key)
+}
+
+// ServeTelemetry reads the UDP telemetry frames until the context is done, their samples are recorded
+// as the HTTP telemetry's. The frames not signed with the fleet's key are dropped.
+// It returns once the last frame read is handed over.
+func ServeTelemetry(ctx context.Context, conn net.PacketConn) {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	// a longer datagram is read past the frame's size, so it's refused
+	buf := make([]byte, telemetry.FrameSize+1)
+	last := make(map[string]telemetry.Frame)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Errorf("couldn't read the telemetry: %s", err)
+			continue
+		}
+		f, err := telemetry.Decode(buf[:n], telemetryKey)
+		if err != nil {
+			logger.Errorf("dropped a telemetry frame from %s: %s", addr, err)
+			continue
+		}
+		// the duplicated and late datagrams are dropped before reaching the repository, a lower sequence
+		// with a newer time is a restarted scooter
+		if prev, ok := last[f.ScooterID]; ok && f.Seq <= prev.Seq && !f.Sample.Time.After(prev.Sample.Time) {
+			continue
+		}
+		last[f.ScooterID] = f
+
+		if err := ingestFrame(ctx, f); err != nil {
+			logger.Errorf("couldn't record the scooter %s telemetry frame %d: %s", f.ScooterID, f.Seq, err)
+		}
+	}
+}
+
+// ingestFrame records the frame's sample, through the write-behind buffer when there's one
+func ingestFrame(ctx context.Context, f telemetry.Frame) error {
+	if positions == nil {
+		_, err := ingestTelemetry(ctx, f.ScooterID, []models.TelemetrySample{f.Sample})
+		return err
+	}
+	if err := validTelemetry([]models.TelemetrySample{f.Sample}, time.Now().UTC()); err != nil {
+		return err
+	}
+	return positions.Add(ctx, models.LocationSample{ScooterID: f.ScooterID, Time: f.Sample.Time, Location: f.Sample.Location,
+		Speed: f.Sample.Speed, Battery: f.Sample.Battery})
+}
+
+// GetPositionBufferStats returns the counters of the UDP telemetry's write-behind buffer, all zero without it
+func GetPositionBufferStats(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if positions == nil {
+		writeJSON(w, models.PositionBufferStats{})
+		return
+	}
+	writeJSON(w, positions.Stats())
+}
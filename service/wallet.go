@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/ledger"
+	"scootin/logger"
+	"scootin/models"
+	"scootin/payment"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// minBalance is the lowest wallet balance, in every currency, a user starts a trip with
+var minBalance int64
+
+// SetMinBalance sets the lowest wallet balance a user starts a trip with
+func SetMinBalance(amount int64) {
+	minBalance = amount
+}
+
+// checkBalance refuses the trips of a user whose wallet is below the minimum balance,
+// a wallet without any balance in the hold's currency has 0 of it
+func checkBalance(ctx context.Context, userID string) error {
+	wallet, err := db.GetWallet(ctx, userID)
+	if err != nil {
+		return err
+	}
+	held := false
+	for _, b := range wallet.Balances {
+		if b.Amount < minBalance {
+			return models.Errorf(models.ErrInsufficientBalance, "user %s has %d %s", userID, b.Amount, b.Currency)
+		}
+		held = held || b.Currency == holdCurrency
+	}
+	if !held && minBalance > 0 {
+		return models.Errorf(models.ErrInsufficientBalance, "user %s has no %s", userID, holdCurrency)
+	}
+	return nil
+}
+
+// fareDue returns the part of the ended trip's fare its rider's wallet doesn't pay, which is left to the card.
+// The wallet is charged the whole fare when the trip ends, so it pays what it held in the fare's currency before,
+// and the captured rest credits it back.
+func fareDue(ctx context.Context, trip *models.Trip) (int64, error) {
+	wallet, err := db.GetWallet(ctx, trip.UserID)
+	if err != nil {
+		return 0, err
+	}
+	var balance int64
+	for _, b := range wallet.Balances {
+		if b.Currency == trip.Fare.Currency {
+			balance = b.Amount
+		}
+	}
+	switch {
+	case balance >= 0:
+		return 0, nil
+	case -balance > trip.Fare.Total:
+		// the rest is owed by other trips
+		return trip.Fare.Total, nil
+	}
+	return -balance, nil
+}
+
+// GetWallet returns the user's wallet balances to the user or the operators
+func GetWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if !isAdmin(r) && r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "wallet of user %s", userID))
+		return
+	}
+	wallet, err := db.GetWallet(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, wallet)
+}
+
+// ListWalletTransactions returns the user's ledger transactions to the user or the operators, the oldest first
+func ListWalletTransactions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if !isAdmin(r) && r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "transactions of user %s", userID))
+		return
+	}
+	transactions, err := db.ListLedgerTransactions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, transactions)
+}
+
+// TopUpWallet captures the money through the payment provider and credits the user's wallet with it,
+// returns the posted transaction
+func TopUpWallet(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("id")
+	if r.Header.Get("user-id") != userID {
+		writeError(w, r, models.Errorf(models.ErrForbidden, "only user %s tops up the wallet", userID))
+		return
+	}
+	money, err := readMoney(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if paymentProvider == nil {
+		writeError(w, r, errNoPaymentProvider)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentTimeout)
+	defer cancel()
+	topUpID := uuid.New().String()
+	if _, err := paymentProvider.Capture(ctx, payment.Request{ID: topUpID, PayerID: userID, MerchantCode: merchantCode,
+		Amount: money.Amount, Currency: money.Currency}); err != nil {
+		writeError(w, r, paymentError(err, topUpID))
+		return
+	}
+	tx := ledger.TopUp(userID, topUpID, *money)
+	if err := db.PostLedgerTransaction(context.Background(), tx); err != nil {
+		logger.Errorf("couldn't post the captured top-up %s of user %s: %s", topUpID, userID, err)
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, tx)
+}
+
+// CreditPromo credits the user's wallet with a promotion, returns the posted transaction
+func CreditPromo(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	money, err := readMoney(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tx := ledger.Promo(ps.ByName("id"), uuid.New().String(), *money)
+	if err := db.PostLedgerTransaction(r.Context(), tx); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, tx)
+}
+
+// readMoney reads a positive amount of a currency from the request's body
+func readMoney(r *http.Request) (*models.Money, error) {
+	var money *models.Money
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err)
+	}
+	if err = json.Unmarshal(body, &money); err != nil || money == nil || money.Amount <= 0 || len(money.Currency) != 3 {
+		return nil, models.Errorf(models.ErrInvalidRequest, "invalid amount %s", body)
+	}
+	return money, nil
+}
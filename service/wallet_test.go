@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"scootin/db"
+	"scootin/ledger"
+	"scootin/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBalance(t *testing.T) {
+	ctx := context.Background()
+	db.SetRepository(db.NewInMemory())
+	SetMinBalance(100)
+	defer SetMinBalance(0)
+
+	// an empty wallet has nothing in the hold's currency
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u1"}))
+	assert.ErrorIs(t, checkBalance(ctx, "u1"), models.ErrInsufficientBalance)
+
+	// nor has a wallet only holding other currencies
+	assert.NoError(t, db.PostLedgerTransaction(ctx, ledger.Promo("u1", "promo1", models.Money{Amount: 500, Currency: "USD"})))
+	assert.ErrorIs(t, checkBalance(ctx, "u1"), models.ErrInsufficientBalance)
+
+	assert.NoError(t, db.PostLedgerTransaction(ctx, ledger.Promo("u1", "promo2", models.Money{Amount: 100, Currency: holdCurrency})))
+	assert.NoError(t, checkBalance(ctx, "u1"))
+
+	// no balance is required by default
+	SetMinBalance(0)
+	assert.NoError(t, db.CreateUser(ctx, &models.User{ID: "u2"}))
+	assert.NoError(t, checkBalance(ctx, "u2"))
+}
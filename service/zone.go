@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"scootin/db"
+	"scootin/models"
+	"scootin/zone"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CreateZone stores a zone with its GeoJSON Polygon or MultiPolygon geometry, returns the stored zone
+func CreateZone(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	z, err := readZone(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	z.ID = uuid.New().String()
+	z.CreatedAt = time.Now().UTC()
+	z.UpdatedAt = z.CreatedAt
+
+	if err := db.CreateZone(r.Context(), z); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, z)
+}
+
+// UpdateZone replaces the zone's name, tags, geometry and speed limit, returns the updated zone
+func UpdateZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	z, err := readZone(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	z.ID = ps.ByName("id")
+	z.UpdatedAt = time.Now().UTC()
+
+	if err := db.UpdateZone(r.Context(), z); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	updated, err := db.GetZone(r.Context(), z.ID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, updated)
+}
+
+// DeleteZone deletes the zone, returns its id
+func DeleteZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zoneID := ps.ByName("id")
+	if err := db.DeleteZone(r.Context(), zoneID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, models.UUIDResponse{ID: zoneID})
+}
+
+// GetZone returns the zone by its id
+func GetZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	z, err := db.GetZone(r.Context(), ps.ByName("id"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, z)
+}
+
+// ListZones lists the zones in their creation order
+func ListZones(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	zones, err := db.ListZones(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, zones)
+}
+
+// ListZoneAlerts lists the alerts raised within the "from" and "to" RFC3339 query times, the last hour by default
+func ListZoneAlerts(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var (
+		err  error
+		to   = time.Now().UTC()
+		from time.Time
+	)
+	query := r.URL.Query()
+	if v := query.Get("to"); len(v) > 0 {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid to time: %s", err))
+			return
+		}
+	}
+	from = to.Add(-time.Hour)
+	if v := query.Get("from"); len(v) > 0 {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			writeError(w, r, models.Errorf(models.ErrInvalidRequest, "invalid from time: %s", err))
+			return
+		}
+	}
+	if !from.Before(to) {
+		writeError(w, r, models.Errorf(models.ErrInvalidRequest, "from must be before to"))
+		return
+	}
+
+	alerts, err := db.ListZoneAlerts(r.Context(), from, to)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	writeJSON(w, alerts)
+}
+
+// readZone reads the request's zone and checks it can be indexed
+func readZone(r *http.Request) (*models.Zone, error) {
+	var z *models.Zone
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, models.Errorf(models.ErrInvalidRequest, "couldn't read the body: %s", err)
+	}
+	if err = json.Unmarshal(body, &z); err != nil {
+		return nil, models.Errorf(models.ErrInvalidRequest, "invalid zone %s", body)
+	}
+	if err = zone.Valid(z); err != nil {
+		return nil, models.Errorf(models.ErrInvalidRequest, "invalid zone: %s", err)
+	}
+	return z, nil
+}
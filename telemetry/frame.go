@@ -0,0 +1,121 @@
+// Package telemetry encodes the scooters' telemetry samples into compact signed frames, sent over UDP
+// by the fleets too large for the JSON telemetry endpoint.
+//
+// A frame is FrameSize bytes, the integers big-endian:
+//
+//	offset  size  field
+//	0       1     version, 1
+//	1       16    scooter id, the bytes of its UUID
+//	17      4     sequence number, incremented by the scooter for every frame
+//	21      8     time, unix milliseconds
+//	29      4     latitude, signed, 1e-7 degrees
+//	33      4     longitude, signed, 1e-7 degrees
+//	37      2     speed, 0.1 km/h
+//	39      1     battery, percent
+//	40      16    signature, the first 16 bytes of the HMAC-SHA256 of the bytes 0 to 39 with the fleet's key
+package telemetry
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"scootin/geo"
+	"scootin/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// Version is the frames' layout version
+	Version = 1
+	// FrameSize is the length of a frame in bytes
+	FrameSize = payloadSize + signatureSize
+
+	payloadSize   = 40
+	signatureSize = 16
+	// degreeUnit is the frames' position resolution, about 1cm
+	degreeUnit = 1e-7
+	// speedUnit is the frames' speed resolution in km/h
+	speedUnit = 0.1
+)
+
+var (
+	// ErrMalformed is returned for the frames of another size or version
+	ErrMalformed = errors.New("malformed telemetry frame")
+	// ErrSignature is returned for the frames not signed with the fleet's key
+	ErrSignature = errors.New("invalid telemetry frame signature")
+)
+
+// Frame is a scooter's sample with its sequence number
+type Frame struct {
+	ScooterID string
+	Seq       uint32
+	Sample    models.TelemetrySample
+}
+
+// Encode returns the frame signed with the key, the scooter id must be a UUID and the sample within the frame's ranges
+func Encode(f Frame, key []byte) ([]byte, error) {
+	id, err := uuid.Parse(f.ScooterID)
+	if err != nil {
+		return nil, fmt.Errorf("the scooter id %q isn't a UUID", f.ScooterID)
+	}
+	s := f.Sample
+	if !geo.Valid(s.Location) {
+		return nil, fmt.Errorf("invalid location %v", s.Location)
+	}
+	if s.Speed < 0 || s.Speed/speedUnit > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid speed %v", s.Speed)
+	}
+	if s.Battery < 0 || s.Battery > math.MaxUint8 {
+		return nil, fmt.Errorf("invalid battery %d", s.Battery)
+	}
+
+	b := make([]byte, FrameSize)
+	b[0] = Version
+	copy(b[1:17], id[:])
+	binary.BigEndian.PutUint32(b[17:21], f.Seq)
+	binary.BigEndian.PutUint64(b[21:29], uint64(s.Time.UnixNano()/int64(time.Millisecond)))
+	binary.BigEndian.PutUint32(b[29:33], uint32(int32(math.Round(s.Location.Latitude/degreeUnit))))
+	binary.BigEndian.PutUint32(b[33:37], uint32(int32(math.Round(s.Location.Longitude/degreeUnit))))
+	binary.BigEndian.PutUint16(b[37:39], uint16(math.Round(s.Speed/speedUnit)))
+	b[39] = byte(s.Battery)
+	copy(b[payloadSize:], sign(b[:payloadSize], key))
+	return b, nil
+}
+
+// Decode checks the frame's signature with the key and returns the frame
+func Decode(b []byte, key []byte) (Frame, error) {
+	var f Frame
+	if len(b) != FrameSize || b[0] != Version {
+		return f, ErrMalformed
+	}
+	if !hmac.Equal(b[payloadSize:], sign(b[:payloadSize], key)) {
+		return f, ErrSignature
+	}
+
+	id, _ := uuid.FromBytes(b[1:17])
+	f.ScooterID = id.String()
+	f.Seq = binary.BigEndian.Uint32(b[17:21])
+	millis := int64(binary.BigEndian.Uint64(b[21:29]))
+	f.Sample = models.TelemetrySample{
+		Time: time.Unix(millis/1000, millis%1000*int64(time.Millisecond)).UTC(),
+		Location: models.Location{
+			Latitude:  float64(int32(binary.BigEndian.Uint32(b[29:33]))) * degreeUnit,
+			Longitude: float64(int32(binary.BigEndian.Uint32(b[33:37]))) * degreeUnit,
+		},
+		Speed:   float64(binary.BigEndian.Uint16(b[37:39])) * speedUnit,
+		Battery: int(b[39]),
+	}
+	return f, nil
+}
+
+// sign returns the payload's truncated HMAC-SHA256
+func sign(payload, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)[:signatureSize]
+}
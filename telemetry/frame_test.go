@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"scootin/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrame(t *testing.T) {
+	key := []byte("fleet-key")
+	f := Frame{ScooterID: uuid.New().String(), Seq: 42, Sample: models.TelemetrySample{
+		Time:     time.Date(2024, 5, 1, 12, 30, 15, 123456789, time.UTC),
+		Location: models.Location{Latitude: 52.520008, Longitude: -13.404954},
+		Speed:    17.46,
+		Battery:  83,
+	}}
+	b, err := Encode(f, key)
+	assert.NoError(t, err)
+	assert.Len(t, b, FrameSize)
+
+	// the time is kept to the millisecond, the position to 1e-7 degree and the speed to 0.1 km/h
+	decoded, err := Decode(b, key)
+	assert.NoError(t, err)
+	assert.Equal(t, f.ScooterID, decoded.ScooterID)
+	assert.Equal(t, f.Seq, decoded.Seq)
+	assert.Equal(t, f.Sample.Time.Truncate(time.Millisecond), decoded.Sample.Time)
+	assert.InDelta(t, f.Sample.Location.Latitude, decoded.Sample.Location.Latitude, 1e-7)
+	assert.InDelta(t, f.Sample.Location.Longitude, decoded.Sample.Location.Longitude, 1e-7)
+	assert.InDelta(t, 17.5, decoded.Sample.Speed, 1e-9)
+	assert.Equal(t, 83, decoded.Sample.Battery)
+
+	// a frame signed with another key or changed on the way is refused
+	_, err = Decode(b, []byte("another-key"))
+	assert.ErrorIs(t, err, ErrSignature)
+	b[39] = 100
+	_, err = Decode(b, key)
+	assert.ErrorIs(t, err, ErrSignature)
+	_, err = Decode(b[:FrameSize-1], key)
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	_, err = Encode(Frame{ScooterID: "sc1", Sample: f.Sample}, key)
+	assert.Error(t, err)
+	f.Sample.Battery = 300
+	_, err = Encode(f, key)
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package zone
+
+import (
+	"math"
+	"scootin/models"
+	"sort"
+)
+
+// cell is a square of the index grid
+type cell struct{ x, y int }
+
+func cellOf(pt point) cell {
+	return cell{int(math.Floor(pt[0] / cellDegrees)), int(math.Floor(pt[1] / cellDegrees))}
+}
+
+// entry is an indexed polygon of a zone
+type entry struct {
+	zone    int
+	polygon polygon
+}
+
+// Index finds the zones containing a location: the polygons are kept under the grid cells their bounding
+// box overlaps, so a lookup only tests the polygons of the location's cell.
+type Index struct {
+	zones   []models.Zone
+	entries []entry
+	cells   map[cell][]int
+	large   []int // the entries overlapping too many cells to be kept under each
+	// operating reports whether a zone is tagged as an operating area
+	operating bool
+}
+
+// NewIndex indexes the zones, the ones without a valid geometry are left out
+func NewIndex(zones []models.Zone) *Index {
+	ix := &Index{zones: zones, cells: make(map[cell][]int)}
+	for i := range zones {
+		polygons, err := parse(zones[i].Geometry)
+		if err != nil {
+			continue
+		}
+		ix.operating = ix.operating || zones[i].Has(models.ZoneOperatingArea)
+		for _, p := range polygons {
+			e := len(ix.entries)
+			ix.entries = append(ix.entries, entry{zone: i, polygon: p})
+
+			from, to := cellOf(p.min), cellOf(p.max)
+			if (to.x-from.x+1)*(to.y-from.y+1) > maxCells {
+				ix.large = append(ix.large, e)
+				continue
+			}
+			for x := from.x; x <= to.x; x++ {
+				for y := from.y; y <= to.y; y++ {
+					ix.cells[cell{x, y}] = append(ix.cells[cell{x, y}], e)
+				}
+			}
+		}
+	}
+	return ix
+}
+
+// At returns the zones containing the location, in their indexing order
+func (ix *Index) At(l models.Location) []models.Zone {
+	pt := point{l.Longitude, l.Latitude}
+	found := make([]int, 0)
+	for _, candidates := range [][]int{ix.cells[cellOf(pt)], ix.large} {
+		for _, e := range candidates {
+			if z := ix.entries[e].zone; !has(found, z) && ix.entries[e].polygon.contains(pt) {
+				found = append(found, z)
+			}
+		}
+	}
+	sort.Ints(found)
+
+	zones := make([]models.Zone, 0, len(found))
+	for _, z := range found {
+		zones = append(zones, ix.zones[z])
+	}
+	return zones
+}
+
+func has(zones []int, zone int) bool {
+	for _, z := range zones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
+// CanPark checks a trip may end at the location: within an operating area when there's any, and out of
+// the no-parking zones.
+func (ix *Index) CanPark(l models.Location) error {
+	inOperatingArea := !ix.operating
+	for _, z := range ix.At(l) {
+		if z.Has(models.ZoneNoParking) {
+			return models.Errorf(models.ErrNoParkingZone, "zone %s", z.ID)
+		}
+		inOperatingArea = inOperatingArea || z.Has(models.ZoneOperatingArea)
+	}
+	if !inOperatingArea {
+		return models.Errorf(models.ErrOutsideOperatingArea, "location %v", l)
+	}
+	return nil
+}
+
+// Entered returns the zones with the tag containing the location to but not the location from
+func (ix *Index) Entered(from, to models.Location, tag models.ZoneTag) []models.Zone {
+	was := make(map[string]bool)
+	for _, z := range ix.At(from) {
+		was[z.ID] = true
+	}
+	entered := make([]models.Zone, 0)
+	for _, z := range ix.At(to) {
+		if z.Has(tag) && !was[z.ID] {
+			entered = append(entered, z)
+		}
+	}
+	return entered
+}
@@ -0,0 +1,122 @@
+// Package zone parses the zones' GeoJSON polygons and finds the zones containing a location.
+package zone
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"scootin/models"
+)
+
+const (
+	// cellDegrees is the side of the index grid cells, about 1.1km of latitude
+	cellDegrees = 0.01
+	// maxCells is the most cells a polygon is indexed under, the larger ones are checked on every lookup
+	maxCells = 4096
+)
+
+// point is a [longitude, latitude] position
+type point [2]float64
+
+// polygon is an outer ring followed by its holes, with its bounding box
+type polygon struct {
+	rings    [][]point
+	min, max point
+}
+
+// Valid checks the zone can be indexed: a known tag at least, a speed limit for the slow zones and a valid geometry
+func Valid(z *models.Zone) error {
+	if z == nil || len(z.Tags) == 0 {
+		return fmt.Errorf("the zone has no tag")
+	}
+	for _, tag := range z.Tags {
+		switch tag {
+		case models.ZoneOperatingArea, models.ZoneNoParking, models.ZoneNoRiding, models.ZoneSlow:
+		default:
+			return fmt.Errorf("unknown zone tag %q", tag)
+		}
+	}
+	if z.Has(models.ZoneSlow) && z.SpeedLimit <= 0 {
+		return fmt.Errorf("the slow zone has no speed limit")
+	}
+	_, err := parse(z.Geometry)
+	return err
+}
+
+// parse returns the polygons of the GeoJSON Polygon or MultiPolygon geometry
+func parse(g models.Geometry) ([]polygon, error) {
+	var coordinates [][][]point
+	switch g.Type {
+	case "Polygon":
+		var rings [][]point
+		if err := json.Unmarshal(g.Coordinates, &rings); err != nil {
+			return nil, fmt.Errorf("invalid polygon: %s", err)
+		}
+		coordinates = [][][]point{rings}
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("invalid multipolygon: %s", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported geometry %q", g.Type)
+	}
+	if len(coordinates) == 0 {
+		return nil, fmt.Errorf("empty geometry")
+	}
+
+	polygons := make([]polygon, 0, len(coordinates))
+	for _, rings := range coordinates {
+		if len(rings) == 0 {
+			return nil, fmt.Errorf("polygon without rings")
+		}
+		p := polygon{rings: rings, min: point{math.Inf(1), math.Inf(1)}, max: point{math.Inf(-1), math.Inf(-1)}}
+		for _, ring := range rings {
+			if len(ring) < 4 {
+				return nil, fmt.Errorf("a ring needs 4 positions at least")
+			}
+			for _, pt := range ring {
+				if !geoValid(pt) {
+					return nil, fmt.Errorf("invalid position %v", pt)
+				}
+			}
+		}
+		for _, pt := range rings[0] {
+			p.min = point{math.Min(p.min[0], pt[0]), math.Min(p.min[1], pt[1])}
+			p.max = point{math.Max(p.max[0], pt[0]), math.Max(p.max[1], pt[1])}
+		}
+		polygons = append(polygons, p)
+	}
+	return polygons, nil
+}
+
+func geoValid(pt point) bool {
+	return pt[0] >= -180 && pt[0] <= 180 && pt[1] >= -90 && pt[1] <= 90
+}
+
+// contains reports whether the position is inside the outer ring and outside the holes
+func (p *polygon) contains(pt point) bool {
+	if pt[0] < p.min[0] || pt[0] > p.max[0] || pt[1] < p.min[1] || pt[1] > p.max[1] {
+		return false
+	}
+	if !inRing(p.rings[0], pt) {
+		return false
+	}
+	for _, hole := range p.rings[1:] {
+		if inRing(hole, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing casts a ray from the position eastwards and counts the ring's edges it crosses
+func inRing(ring []point, pt point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) && pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,88 @@
+package zone
+
+import (
+	"encoding/json"
+	"fmt"
+	"scootin/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// square returns the polygon ring of the square of the half side around the [longitude, latitude] center
+func square(lon, lat, half float64) [][2]float64 {
+	return [][2]float64{{lon - half, lat - half}, {lon + half, lat - half}, {lon + half, lat + half}, {lon - half, lat + half}, {lon - half, lat - half}}
+}
+
+func geometry(t *testing.T, kind string, coordinates interface{}) models.Geometry {
+	j, err := json.Marshal(coordinates)
+	assert.NoError(t, err)
+	return models.Geometry{Type: kind, Coordinates: j}
+}
+
+func TestValid(t *testing.T) {
+	polygon := geometry(t, "Polygon", [][][2]float64{square(13.4, 52.5, 0.1)})
+	assert.NoError(t, Valid(&models.Zone{Tags: []models.ZoneTag{models.ZoneNoParking}, Geometry: polygon}))
+	assert.NoError(t, Valid(&models.Zone{Tags: []models.ZoneTag{models.ZoneSlow}, SpeedLimit: 10, Geometry: polygon}))
+	assert.Error(t, Valid(&models.Zone{Tags: []models.ZoneTag{models.ZoneSlow}, Geometry: polygon}))
+	assert.Error(t, Valid(&models.Zone{Tags: []models.ZoneTag{"parking"}, Geometry: polygon}))
+	assert.Error(t, Valid(&models.Zone{Geometry: polygon}))
+	assert.Error(t, Valid(nil))
+
+	tags := []models.ZoneTag{models.ZoneNoRiding}
+	assert.Error(t, Valid(&models.Zone{Tags: tags, Geometry: geometry(t, "Point", [2]float64{13.4, 52.5})}))
+	assert.Error(t, Valid(&models.Zone{Tags: tags, Geometry: geometry(t, "Polygon", [][][2]float64{square(13.4, 52.5, 0.1)[:3]})}))
+	assert.Error(t, Valid(&models.Zone{Tags: tags, Geometry: geometry(t, "Polygon", [][][2]float64{square(13.4, 95, 0.1)})}))
+	assert.Error(t, Valid(&models.Zone{Tags: tags, Geometry: geometry(t, "MultiPolygon", [][][][2]float64{})}))
+}
+
+func TestIndex(t *testing.T) {
+	// a city wide operating area with a hole in it, a no-parking square and a country wide slow zone
+	index := NewIndex([]models.Zone{
+		{ID: "city", Tags: []models.ZoneTag{models.ZoneOperatingArea},
+			Geometry: geometry(t, "Polygon", [][][2]float64{square(13.4, 52.5, 0.2), square(13.5, 52.6, 0.01)})},
+		{ID: "station", Tags: []models.ZoneTag{models.ZoneNoParking, models.ZoneNoRiding},
+			Geometry: geometry(t, "MultiPolygon", [][][][2]float64{{square(13.3, 52.4, 0.001)}, {square(13.35, 52.4, 0.001)}})},
+		{ID: "country", Tags: []models.ZoneTag{models.ZoneSlow}, SpeedLimit: 15,
+			Geometry: geometry(t, "Polygon", [][][2]float64{square(10, 51, 5)})},
+		{ID: "invalid", Tags: []models.ZoneTag{models.ZoneNoParking}, Geometry: models.Geometry{Type: "Polygon"}},
+	})
+	ids := func(zones []models.Zone) []string {
+		ids := make([]string, 0)
+		for _, z := range zones {
+			ids = append(ids, z.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"city", "country"}, ids(index.At(models.Location{Latitude: 52.5, Longitude: 13.4})))
+	assert.Equal(t, []string{"country"}, ids(index.At(models.Location{Latitude: 52.6, Longitude: 13.5})))
+	assert.Equal(t, []string{"city", "station", "country"}, ids(index.At(models.Location{Latitude: 52.4, Longitude: 13.35})))
+	assert.Empty(t, index.At(models.Location{Latitude: 40, Longitude: -3}))
+
+	assert.NoError(t, index.CanPark(models.Location{Latitude: 52.5, Longitude: 13.4}))
+	assert.ErrorIs(t, index.CanPark(models.Location{Latitude: 52.4, Longitude: 13.3}), models.ErrNoParkingZone)
+	assert.ErrorIs(t, index.CanPark(models.Location{Latitude: 52.6, Longitude: 13.5}), models.ErrOutsideOperatingArea)
+	assert.NoError(t, NewIndex(nil).CanPark(models.Location{Latitude: 52.6, Longitude: 13.5}))
+
+	// only the entered no-riding zones count
+	outside, inside := models.Location{Latitude: 52.41, Longitude: 13.3}, models.Location{Latitude: 52.4, Longitude: 13.3}
+	assert.Equal(t, []string{"station"}, ids(index.Entered(outside, inside, models.ZoneNoRiding)))
+	assert.Empty(t, index.Entered(inside, inside, models.ZoneNoRiding))
+	assert.Empty(t, index.Entered(inside, outside, models.ZoneNoRiding))
+}
+
+func BenchmarkIndexAt(b *testing.B) {
+	zones := make([]models.Zone, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		j, _ := json.Marshal([][][2]float64{square(13+float64(i%40)*0.02, 52+float64(i/40)*0.02, 0.005)})
+		zones = append(zones, models.Zone{ID: fmt.Sprint(i), Tags: []models.ZoneTag{models.ZoneNoRiding},
+			Geometry: models.Geometry{Type: "Polygon", Coordinates: j}})
+	}
+	index := NewIndex(zones)
+	l := models.Location{Latitude: 52.2, Longitude: 13.4}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.At(l)
+	}
+}